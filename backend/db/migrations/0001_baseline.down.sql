@@ -0,0 +1,30 @@
+-- Drops every table of the baseline, and with them all data
+
+SET FOREIGN_KEY_CHECKS=0;
+
+DROP TABLE IF EXISTS `fx_rates`;
+DROP TABLE IF EXISTS `jobs`;
+DROP TABLE IF EXISTS `notification_deliveries`;
+DROP TABLE IF EXISTS `notification_channels`;
+DROP TABLE IF EXISTS `notifications`;
+DROP TABLE IF EXISTS `categorization_rules`;
+DROP TABLE IF EXISTS `envelope_transfers`;
+DROP TABLE IF EXISTS `plan_template_items`;
+DROP TABLE IF EXISTS `plan_templates`;
+DROP TABLE IF EXISTS `recurring_occurrences`;
+DROP TABLE IF EXISTS `recurring_rules`;
+DROP TABLE IF EXISTS `goal_installments`;
+DROP TABLE IF EXISTS `goal_contributions`;
+DROP TABLE IF EXISTS `goals`;
+DROP TABLE IF EXISTS `borrow_repayments`;
+DROP TABLE IF EXISTS `borrow_entries`;
+DROP TABLE IF EXISTS `counterparties`;
+DROP TABLE IF EXISTS `earning_entries`;
+DROP TABLE IF EXISTS `spending_entries`;
+DROP TABLE IF EXISTS `plans`;
+DROP TABLE IF EXISTS `categories`;
+DROP TABLE IF EXISTS `months`;
+DROP TABLE IF EXISTS `sessions`;
+DROP TABLE IF EXISTS `users`;
+
+SET FOREIGN_KEY_CHECKS=1;
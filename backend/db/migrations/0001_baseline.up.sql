@@ -0,0 +1,458 @@
+-- Baseline: the schema as of the switch to versioned migrations. Statements are idempotent so that
+-- databases created by the former startup migrations can be baselined in place.
+
+SET NAMES utf8mb4;
+SET FOREIGN_KEY_CHECKS=0;
+
+-- Users
+CREATE TABLE IF NOT EXISTS `users` (
+  `id` VARCHAR(36) NOT NULL,
+  `email` VARCHAR(255) NOT NULL,
+  `name` VARCHAR(255) NULL,
+  `password_hash` VARCHAR(255) NULL,
+  `base_currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `budget_alert_thresholds` VARCHAR(64) NOT NULL DEFAULT '80,100',
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_users_email` (`email`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Sessions (refresh tokens; id is the access token `jti` claim)
+CREATE TABLE IF NOT EXISTS `sessions` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `refresh_token_hash` VARCHAR(64) NOT NULL,
+  `expires_at` DATETIME(3) NOT NULL,
+  `revoked_at` DATETIME(3) NULL,
+  `last_used_at` DATETIME(3) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_sessions_refresh_token_hash` (`refresh_token_hash`),
+  KEY `idx_sessions_user_id` (`user_id`),
+  CONSTRAINT `fk_sessions_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Months (composite PK)
+CREATE TABLE IF NOT EXISTS `months` (
+  `user_id` VARCHAR(36) NOT NULL,
+  `month_key` VARCHAR(7) NOT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`user_id`, `month_key`),
+  CONSTRAINT `fk_months_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Categories (composite PK)
+CREATE TABLE IF NOT EXISTS `categories` (
+  `user_id` VARCHAR(36) NOT NULL,
+  `name` VARCHAR(64) NOT NULL,
+  `parent` VARCHAR(64) NOT NULL DEFAULT '',
+  `color` VARCHAR(7) NOT NULL DEFAULT '',
+  `icon` VARCHAR(64) NOT NULL DEFAULT '',
+  `type` VARCHAR(16) NOT NULL DEFAULT 'discretionary',
+  `sort_order` BIGINT NOT NULL DEFAULT 0,
+  `archived` BOOLEAN NOT NULL DEFAULT FALSE,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`user_id`, `name`),
+  CONSTRAINT `fk_categories_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Plans (unique across user+month+category)
+CREATE TABLE IF NOT EXISTS `plans` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `month_key` VARCHAR(7) NOT NULL,
+  `category` VARCHAR(64) NOT NULL,
+  `planned_amount` DECIMAL(19,4) NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_user_month_category` (`user_id`, `month_key`, `category`),
+  CONSTRAINT `fk_plans_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_plans_month`
+    FOREIGN KEY (`user_id`, `month_key`) REFERENCES `months`(`user_id`, `month_key`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Spending entries
+CREATE TABLE IF NOT EXISTS `spending_entries` (
+  `id` VARCHAR(36) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `category` VARCHAR(64) NOT NULL,
+  `date` DATETIME NOT NULL,
+  `month_key` VARCHAR(7) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `note` TEXT NULL,
+  `merchant` VARCHAR(255) NOT NULL DEFAULT '',
+  `version` BIGINT NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_spending_user` (`user_id`),
+  KEY `idx_spending_month` (`month_key`),
+  KEY `idx_spending_user_month` (`user_id`, `month_key`),
+  KEY `idx_spending_category` (`category`),
+  CONSTRAINT `fk_spending_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_spending_month`
+    FOREIGN KEY (`user_id`, `month_key`) REFERENCES `months`(`user_id`, `month_key`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Earning entries
+CREATE TABLE IF NOT EXISTS `earning_entries` (
+  `id` VARCHAR(36) NOT NULL,
+  `source` VARCHAR(255) NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `date` DATETIME NOT NULL,
+  `month_key` VARCHAR(7) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `version` BIGINT NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_earning_user` (`user_id`),
+  KEY `idx_earning_month` (`month_key`),
+  KEY `idx_earning_user_month` (`user_id`, `month_key`),
+  CONSTRAINT `fk_earning_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_earning_month`
+    FOREIGN KEY (`user_id`, `month_key`) REFERENCES `months`(`user_id`, `month_key`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Counterparties (people and organisations debts are held with)
+CREATE TABLE IF NOT EXISTS `counterparties` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `name` VARCHAR(255) NOT NULL,
+  `note` TEXT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_counterparty_user_name` (`user_id`, `name`),
+  CONSTRAINT `fk_counterparty_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Borrow entries (debts in either direction: borrowed from or lent to a counterparty)
+CREATE TABLE IF NOT EXISTS `borrow_entries` (
+  `id` VARCHAR(36) NOT NULL,
+  `direction` VARCHAR(8) NOT NULL DEFAULT 'borrowed',
+  `counterparty_id` VARCHAR(36) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `date` DATETIME NOT NULL,
+  `due_date` DATE NULL,
+  `interest_rate` DECIMAL(9,4) NOT NULL DEFAULT 0,
+  `compounding` VARCHAR(8) NOT NULL DEFAULT 'simple',
+  `month_key` VARCHAR(7) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `version` BIGINT NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_borrow_user` (`user_id`),
+  KEY `idx_borrow_month` (`month_key`),
+  KEY `idx_borrow_user_month` (`user_id`, `month_key`),
+  KEY `idx_borrow_counterparty` (`counterparty_id`),
+  KEY `idx_borrow_due_date` (`due_date`),
+  CONSTRAINT `fk_borrow_counterparty`
+    FOREIGN KEY (`counterparty_id`) REFERENCES `counterparties`(`id`)
+    ON DELETE RESTRICT ON UPDATE CASCADE,
+  CONSTRAINT `fk_borrow_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_borrow_month`
+    FOREIGN KEY (`user_id`, `month_key`) REFERENCES `months`(`user_id`, `month_key`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Borrow repayments (ledger; source of truth for repaid and outstanding amounts)
+CREATE TABLE IF NOT EXISTS `borrow_repayments` (
+  `id` VARCHAR(36) NOT NULL,
+  `borrow_id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `date` DATETIME NOT NULL,
+  `note` TEXT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_borrow_repayments_borrow_id` (`borrow_id`),
+  KEY `idx_borrow_repayments_user_id` (`user_id`),
+  CONSTRAINT `fk_borrow_repayments_borrow`
+    FOREIGN KEY (`borrow_id`) REFERENCES `borrow_entries`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Goals
+CREATE TABLE IF NOT EXISTS `goals` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NULL,
+  `title` VARCHAR(255) NOT NULL,
+  `description` TEXT NULL,
+  `category` VARCHAR(255) NULL,
+  `save_frequency` VARCHAR(255) NULL,
+  `duration` INT NULL,
+  `start_date` DATETIME NULL,
+  `end_date` DATETIME NULL,
+  `target_date` DATETIME NULL,
+  `status` VARCHAR(20) NOT NULL DEFAULT 'not_started',
+  `created_at` DATETIME(3) NULL,
+  `target_amount` DECIMAL(19,4) NULL,
+  `current_amount` DECIMAL(19,4) NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  PRIMARY KEY (`id`),
+  KEY `idx_goals_user` (`user_id`),
+  CONSTRAINT `fk_goals_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Goal contributions (ledger; goals.current_amount caches SUM(amount))
+CREATE TABLE IF NOT EXISTS `goal_contributions` (
+  `id` VARCHAR(36) NOT NULL,
+  `goal_id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `date` DATETIME NOT NULL,
+  `note` TEXT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_goal_contributions_goal_id` (`goal_id`),
+  KEY `idx_goal_contributions_user_id` (`user_id`),
+  CONSTRAINT `fk_goal_contributions_goal`
+    FOREIGN KEY (`goal_id`) REFERENCES `goals`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Goal installments (savings schedule; regenerated when schedule fields change)
+CREATE TABLE IF NOT EXISTS `goal_installments` (
+  `id` VARCHAR(36) NOT NULL,
+  `goal_id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `sequence` BIGINT NOT NULL,
+  `due_date` DATETIME(3) NOT NULL,
+  `planned_amount` DECIMAL(19,4) NOT NULL,
+  `progress_amount` DECIMAL(19,4) NOT NULL DEFAULT 0,
+  `completed` TINYINT(1) NOT NULL DEFAULT 0,
+  `completed_at` DATETIME(3) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_goal_installment_seq` (`goal_id`, `sequence`),
+  KEY `idx_goal_installments_user_id` (`user_id`),
+  CONSTRAINT `fk_goal_installments_goal`
+    FOREIGN KEY (`goal_id`) REFERENCES `goals`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Recurring rules (materialized into spending/earning entries by the scheduler)
+CREATE TABLE IF NOT EXISTS `recurring_rules` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `kind` VARCHAR(16) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `category` VARCHAR(64) NULL,
+  `source` VARCHAR(255) NULL,
+  `note` TEXT NULL,
+  `rrule` VARCHAR(255) NOT NULL,
+  `start_date` DATETIME(3) NOT NULL,
+  `end_date` DATETIME(3) NULL,
+  `next_run_date` DATETIME(3) NULL,
+  `last_run_date` DATETIME(3) NULL,
+  `active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_recurring_rules_user_id` (`user_id`),
+  KEY `idx_recurring_rules_next_run_date` (`next_run_date`),
+  CONSTRAINT `fk_recurring_rules_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Recurring occurrences (one row per materialized occurrence; unique per rule and date)
+CREATE TABLE IF NOT EXISTS `recurring_occurrences` (
+  `id` VARCHAR(36) NOT NULL,
+  `rule_id` VARCHAR(36) NOT NULL,
+  `occurrence_date` DATETIME(3) NOT NULL,
+  `entry_id` VARCHAR(36) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_rule_occurrence` (`rule_id`, `occurrence_date`),
+  CONSTRAINT `fk_recurring_occurrences_rule`
+    FOREIGN KEY (`rule_id`) REFERENCES `recurring_rules`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Plan templates (named budgets a new month's plans can be cloned from)
+CREATE TABLE IF NOT EXISTS `plan_templates` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `name` VARCHAR(64) NOT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_template_user_name` (`user_id`, `name`),
+  CONSTRAINT `fk_plan_templates_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Plan template items (one planned amount per category and template)
+CREATE TABLE IF NOT EXISTS `plan_template_items` (
+  `id` VARCHAR(36) NOT NULL,
+  `template_id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `category` VARCHAR(64) NOT NULL,
+  `planned_amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_template_category` (`template_id`, `category`),
+  KEY `idx_plan_template_items_user_id` (`user_id`),
+  CONSTRAINT `fk_plan_template_items_template`
+    FOREIGN KEY (`template_id`) REFERENCES `plan_templates`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Envelope transfers (budget moved between category envelopes within a month)
+CREATE TABLE IF NOT EXISTS `envelope_transfers` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `month_key` VARCHAR(7) NOT NULL,
+  `from_category` VARCHAR(64) NOT NULL,
+  `to_category` VARCHAR(64) NOT NULL,
+  `amount` DECIMAL(19,4) NOT NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT 'USD',
+  `note` TEXT NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_envelope_transfer_user_month` (`user_id`, `month_key`),
+  CONSTRAINT `fk_envelope_transfers_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE,
+  CONSTRAINT `fk_envelope_transfers_month`
+    FOREIGN KEY (`user_id`, `month_key`) REFERENCES `months`(`user_id`, `month_key`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Categorization rules (assign a category to spending posted without one; lowest priority first)
+CREATE TABLE IF NOT EXISTS `categorization_rules` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `priority` BIGINT NOT NULL DEFAULT 0,
+  `category` VARCHAR(64) NOT NULL,
+  `note_contains` VARCHAR(255) NOT NULL DEFAULT '',
+  `merchant` VARCHAR(255) NOT NULL DEFAULT '',
+  `min_amount` DECIMAL(19,4) NULL,
+  `max_amount` DECIMAL(19,4) NULL,
+  `currency` VARCHAR(3) NOT NULL DEFAULT '',
+  `active` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_categorization_user_priority` (`user_id`, `priority`),
+  CONSTRAINT `fk_categorization_rules_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Notifications (budget, debt and goal alerts; `dedupe_key` makes each event notify once per user)
+CREATE TABLE IF NOT EXISTS `notifications` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `kind` VARCHAR(32) NOT NULL,
+  `dedupe_key` VARCHAR(191) NOT NULL,
+  `title` VARCHAR(255) NOT NULL,
+  `body` TEXT NULL,
+  `month_key` VARCHAR(7) NOT NULL DEFAULT '',
+  `category` VARCHAR(64) NOT NULL DEFAULT '',
+  `read_at` DATETIME(3) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_notification_user_key` (`user_id`, `dedupe_key`),
+  KEY `idx_notification_user_created` (`user_id`, `created_at`),
+  CONSTRAINT `fk_notifications_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Notification channels (per-user Signal number / Telegram chat ID)
+CREATE TABLE IF NOT EXISTS `notification_channels` (
+  `id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `channel` VARCHAR(16) NOT NULL,
+  `address` VARCHAR(128) NOT NULL,
+  `enabled` TINYINT(1) NOT NULL DEFAULT 1,
+  `created_at` DATETIME(3) NULL,
+  `updated_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_channel_user_channel` (`user_id`, `channel`),
+  CONSTRAINT `fk_notification_channels_user`
+    FOREIGN KEY (`user_id`) REFERENCES `users`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Notification outbox (one row per notification and channel; written with the notification)
+CREATE TABLE IF NOT EXISTS `notification_deliveries` (
+  `id` VARCHAR(36) NOT NULL,
+  `notification_id` VARCHAR(36) NOT NULL,
+  `user_id` VARCHAR(36) NOT NULL,
+  `channel` VARCHAR(16) NOT NULL,
+  `address` VARCHAR(128) NOT NULL,
+  `status` VARCHAR(16) NOT NULL DEFAULT 'pending',
+  `attempts` BIGINT NOT NULL DEFAULT 0,
+  `next_attempt_at` DATETIME(3) NULL,
+  `last_error` VARCHAR(512) NOT NULL DEFAULT '',
+  `sent_at` DATETIME(3) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_delivery_notification_channel` (`notification_id`, `channel`),
+  KEY `idx_notification_deliveries_user_id` (`user_id`),
+  KEY `idx_delivery_status_next` (`status`, `next_attempt_at`),
+  CONSTRAINT `fk_notification_deliveries_notification`
+    FOREIGN KEY (`notification_id`) REFERENCES `notifications`(`id`)
+    ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- Background jobs queue (`dead` rows are dead-lettered and kept for inspection)
+CREATE TABLE IF NOT EXISTS `jobs` (
+  `id` VARCHAR(36) NOT NULL,
+  `kind` VARCHAR(64) NOT NULL,
+  `payload` TEXT NULL,
+  `status` VARCHAR(16) NOT NULL DEFAULT 'queued',
+  `attempts` BIGINT NOT NULL DEFAULT 0,
+  `max_attempts` BIGINT NOT NULL DEFAULT 5,
+  `run_at` DATETIME(3) NULL,
+  `locked_by` VARCHAR(64) NOT NULL DEFAULT '',
+  `locked_until` DATETIME(3) NULL,
+  `last_error` VARCHAR(1024) NOT NULL DEFAULT '',
+  `finished_at` DATETIME(3) NULL,
+  `created_at` DATETIME(3) NULL,
+  `updated_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  KEY `idx_jobs_kind` (`kind`),
+  KEY `idx_job_status_run` (`status`, `run_at`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+-- FX rates (1 base = rate quote; shared, maintained via the admin endpoints)
+CREATE TABLE IF NOT EXISTS `fx_rates` (
+  `id` VARCHAR(36) NOT NULL,
+  `base` VARCHAR(3) NOT NULL,
+  `quote` VARCHAR(3) NOT NULL,
+  `date` DATE NOT NULL,
+  `rate` DOUBLE NOT NULL,
+  `source` VARCHAR(64) NULL,
+  `created_at` DATETIME(3) NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `idx_fx_pair_date` (`base`, `quote`, `date`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
+SET FOREIGN_KEY_CHECKS=1;
@@ -0,0 +1 @@
+ALTER TABLE `sessions` DROP KEY `idx_sessions_previous_refresh_token_hash`, DROP COLUMN `previous_refresh_token_hash`;
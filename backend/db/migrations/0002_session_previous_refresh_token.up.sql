@@ -0,0 +1,11 @@
+-- Sessions remember the refresh token they last rotated away from, so that replaying it revokes the
+-- session. Legacy databases already have the column from the startup migrations run before their
+-- baseline, so it is only added when missing.
+
+SET @stmt = IF(
+  (SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'sessions' AND COLUMN_NAME = 'previous_refresh_token_hash') = 0,
+  'ALTER TABLE `sessions` ADD COLUMN `previous_refresh_token_hash` VARCHAR(64) NOT NULL DEFAULT '''' AFTER `refresh_token_hash`, ADD KEY `idx_sessions_previous_refresh_token_hash` (`previous_refresh_token_hash`)',
+  'DO 0');
+PREPARE add_previous_hash FROM @stmt;
+EXECUTE add_previous_hash;
+DEALLOCATE PREPARE add_previous_hash;
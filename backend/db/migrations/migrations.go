@@ -0,0 +1,10 @@
+// Package migrations embeds the numbered SQL migrations: NNNN_name.up.sql applies a change and
+// NNNN_name.down.sql reverts it. Applied files must never be edited; add a new version instead.
+package migrations
+
+import "embed"
+
+//go:generate go run ../.. migrate schema ../schema.sql
+
+//go:embed *.sql
+var FS embed.FS
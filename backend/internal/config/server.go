@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"time"
+)
+
+// ServerConfig holds the HTTP server timeouts, the shutdown schedule and startup behavior
+type ServerConfig struct {
+	Port               string
+	ReadTimeout        time.Duration // HTTP_READ_TIMEOUT, whole request including body (default 30s)
+	ReadHeaderTimeout  time.Duration // HTTP_READ_HEADER_TIMEOUT (default 5s)
+	WriteTimeout       time.Duration // HTTP_WRITE_TIMEOUT, up to the end of the response (default 120s, covers exports)
+	IdleTimeout        time.Duration // HTTP_IDLE_TIMEOUT, keep-alive connections (default 120s)
+	// DrainDelay is how long readiness fails before the listener closes, so load balancers stop
+	// routing here first (SHUTDOWN_DRAIN_DELAY, default 5s)
+	DrainDelay         time.Duration
+	// ShutdownTimeout bounds waiting for in-flight requests and background work (SHUTDOWN_TIMEOUT, default 30s)
+	ShutdownTimeout    time.Duration
+	// HealthCheckTimeout bounds each readiness check (HEALTH_CHECK_TIMEOUT, default 2s)
+	HealthCheckTimeout time.Duration
+	// MigrateOnStart applies pending migrations before serving (MIGRATE_ON_START, default true); when
+	// false, run `migrate up` separately and readiness fails until no migration is pending
+	MigrateOnStart     bool
+}
+
+// LoadServerConfig reads the server settings from the environment
+func LoadServerConfig() ServerConfig {
+	return ServerConfig{
+		Port:               MustGetEnv("PORT", "8081"),
+		ReadTimeout:        durationEnv("HTTP_READ_TIMEOUT", 30*time.Second),
+		ReadHeaderTimeout:  durationEnv("HTTP_READ_HEADER_TIMEOUT", 5*time.Second),
+		WriteTimeout:       durationEnv("HTTP_WRITE_TIMEOUT", 120*time.Second),
+		IdleTimeout:        durationEnv("HTTP_IDLE_TIMEOUT", 120*time.Second),
+		DrainDelay:         durationEnv("SHUTDOWN_DRAIN_DELAY", 5*time.Second),
+		ShutdownTimeout:    durationEnv("SHUTDOWN_TIMEOUT", 30*time.Second),
+		HealthCheckTimeout: durationEnv("HEALTH_CHECK_TIMEOUT", 2*time.Second),
+		MigrateOnStart:     os.Getenv("MIGRATE_ON_START") != "false",
+	}
+}
+
+// durationEnv parses a Go duration; "0" is allowed (no timeout / no delay)
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d >= 0 {
+		return d
+	}
+	return fallback
+}
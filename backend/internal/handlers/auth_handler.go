@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
@@ -10,17 +11,21 @@ import (
 	"gorm.io/gorm"
 
 	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
 	"achieving-backend/internal/services"
 	"achieving-backend/internal/middleware"
 )
 
 // RegisterAuthRoutes wires /auth endpoints using layered services
 func RegisterAuthRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	sessions := services.NewSessionService(repository.NewSessionRepository(db))
 	// Registration
 	type RegisterInput struct {
 		Email    string `json:"email" binding:"required"`
 		Password string `json:"password" binding:"required"`
 		Name     string `json:"name"`
+		// BaseCurrency is the ISO 4217 code summaries are reported in (default USD)
+		BaseCurrency string `json:"baseCurrency" binding:"omitempty,iso4217"`
 	}
 	api.POST("/auth/register", func(c *gin.Context) {
 		var input RegisterInput
@@ -46,12 +51,13 @@ func RegisterAuthRoutes(api *gin.RouterGroup, db *gorm.DB) {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
 			return
 		}
-		u := models.User{ID: uuid.NewString(), Email: email, Name: input.Name, PasswordHash: string(ph)}
+		u := models.User{ID: uuid.NewString(), Email: email, Name: input.Name, PasswordHash: string(ph), BaseCurrency: input.BaseCurrency}
 		if err := db.Create(&u).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create user"})
 			return
 		}
-		c.JSON(http.StatusCreated, gin.H{"id": u.ID, "email": u.Email, "name": u.Name})
+		if u.BaseCurrency == "" { u.BaseCurrency = "USD" }
+		c.JSON(http.StatusCreated, gin.H{"id": u.ID, "email": u.Email, "name": u.Name, "baseCurrency": u.BaseCurrency})
 	})
 
 	// Login
@@ -75,30 +81,70 @@ func RegisterAuthRoutes(api *gin.RouterGroup, db *gorm.DB) {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 			return
 		}
-		tok, claims, err := services.GenerateToken(u.ID, u.Name, u.Email)
+		pair, err := sessions.StartSession(u.ID, u.Name, u.Email)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to generate token"})
 			return
 		}
-		c.JSON(http.StatusOK, gin.H{"token": tok, "user": claims})
+		c.JSON(http.StatusOK, gin.H{"token": pair.Token, "refreshToken": pair.RefreshToken, "user": pair.Claims})
+	})
+
+	// Refresh: exchange a refresh token for a new access token (refresh token is rotated)
+	type RefreshInput struct { RefreshToken string `json:"refreshToken" binding:"required"` }
+	api.POST("/auth/refresh", func(c *gin.Context) {
+		var input RefreshInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		pair, err := sessions.Refresh(input.RefreshToken)
+		if errors.Is(err, services.ErrInvalidSession) { c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refresh token"}); return }
+		c.JSON(http.StatusOK, gin.H{"token": pair.Token, "refreshToken": pair.RefreshToken, "user": pair.Claims})
+	})
+
+	// Logout: revoke the session behind the presented access token
+	api.POST("/auth/logout", middleware.AuthRequired(db), func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		m := claims.(map[string]interface{})
+		userID, _ := m["sub"].(string)
+		jti, _ := m["jti"].(string)
+		if _, err := sessions.Revoke(userID, jti); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to logout"}); return
+		}
+		c.Status(http.StatusNoContent)
 	})
 
 	// Me endpoint protected by middleware
-	api.GET("/auth/me", middleware.AuthRequired(), func(c *gin.Context) {
+	api.GET("/auth/me", middleware.AuthRequired(db), func(c *gin.Context) {
 		claims, _ := c.Get("claims")
-		c.JSON(http.StatusOK, gin.H{"user": claims})
+		userID, _ := claims.(map[string]interface{})["sub"].(string)
+		setting, _ := repository.NewSpendingRepository(db).BudgetAlertThresholds(userID)
+		thresholds, _ := services.ParseThresholds(setting)
+		if thresholds == nil { thresholds = []int{} }
+		c.JSON(http.StatusOK, gin.H{"user": claims, "baseCurrency": repository.NewFxRepository(db).BaseCurrency(userID), "budgetAlertThresholds": thresholds})
 	})
 
-	// Update profile (name)
-	type UpdateProfileInput struct { Name string `json:"name" binding:"required"` }
-	api.PATCH("/auth/profile", middleware.AuthRequired(), func(c *gin.Context) {
+	// Update profile (name, base currency and/or budget alert thresholds; [] disables budget alerts)
+	type UpdateProfileInput struct {
+		Name                  *string `json:"name"`
+		BaseCurrency          *string `json:"baseCurrency" binding:"omitempty,iso4217"`
+		BudgetAlertThresholds *[]int  `json:"budgetAlertThresholds" binding:"omitempty,max=10,dive,min=1,max=1000"`
+	}
+	api.PATCH("/auth/profile", middleware.AuthRequired(db), func(c *gin.Context) {
 		var input UpdateProfileInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		updates := map[string]interface{}{}
+		if input.Name != nil { updates["name"] = *input.Name }
+		if input.BaseCurrency != nil && *input.BaseCurrency != "" { updates["base_currency"] = *input.BaseCurrency }
+		if input.BudgetAlertThresholds != nil {
+			ts, err := services.ParseThresholds(services.FormatThresholds(*input.BudgetAlertThresholds))
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+			updates["budget_alert_thresholds"] = services.FormatThresholds(ts)
+		}
+		if len(updates) == 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		claims, _ := c.Get("claims")
 		m := claims.(map[string]interface{})
 		userID, _ := m["sub"].(string)
 		if userID == "" { c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token"}); return }
-		if err := db.Model(&models.User{}).Where("id = ?", userID).Update("name", input.Name).Error; err != nil {
+		if err := db.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update profile"}); return
 		}
 		c.Status(http.StatusNoContent)
@@ -106,7 +152,7 @@ func RegisterAuthRoutes(api *gin.RouterGroup, db *gorm.DB) {
 
 	// Change password
 	type ChangePasswordInput struct { Current string `json:"current" binding:"required"`; New string `json:"new" binding:"required"` }
-	api.PATCH("/auth/password", middleware.AuthRequired(), func(c *gin.Context) {
+	api.PATCH("/auth/password", middleware.AuthRequired(db), func(c *gin.Context) {
 		var input ChangePasswordInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		if len(input.New) < 6 { c.JSON(http.StatusBadRequest, gin.H{"error": "password too short"}); return }
@@ -124,6 +170,11 @@ func RegisterAuthRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err := db.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", string(ph)).Error; err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to change password"}); return
 		}
+		// Keep the caller signed in but sign out every other device
+		jti, _ := m["jti"].(string)
+		if _, err := sessions.RevokeOthers(userID, jti); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to revoke sessions"}); return
+		}
 		c.Status(http.StatusNoContent)
 	})
 }
\ No newline at end of file
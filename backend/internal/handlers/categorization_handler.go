@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterCategorizationRoutes wires the rules that categorize spending entries posted without a category
+func RegisterCategorizationRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewCategorizationService(repository.NewCategorizationRepository(db), repository.NewSpendingRepository(db))
+
+	ruleError := func(c *gin.Context, err error, msg string) {
+		switch {
+		case errors.Is(err, services.ErrInvalidCategorizationRule):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrUnknownCategory):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		}
+	}
+
+	api.GET("/categorization-rules", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rules, err := svc.List(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list categorization rules"}); return }
+		c.JSON(http.StatusOK, rules)
+	})
+
+	// minAmount/maxAmount are raw so that an explicit null can clear a bound on PATCH
+	type CategorizationRuleInput struct {
+		Priority     *int            `json:"priority"`
+		Category     *string         `json:"category"`
+		NoteContains *string         `json:"noteContains" binding:"omitempty,max=255"`
+		Merchant     *string         `json:"merchant" binding:"omitempty,max=255"`
+		MinAmount    json.RawMessage `json:"minAmount"`
+		MaxAmount    json.RawMessage `json:"maxAmount"`
+		Currency     *string         `json:"currency" binding:"omitempty,iso4217"`
+		Active       *bool           `json:"active"`
+	}
+	bound := func(raw json.RawMessage, dst **models.Money) error {
+		if len(raw) == 0 { return nil }
+		if string(raw) == "null" { *dst = nil; return nil }
+		var m models.Money
+		if err := json.Unmarshal(raw, &m); err != nil { return err }
+		*dst = &m
+		return nil
+	}
+	// apply copies the provided fields onto rule; an empty currency removes the currency restriction
+	apply := func(rule *models.CategorizationRule, input CategorizationRuleInput) error {
+		if input.Priority != nil { rule.Priority = *input.Priority }
+		if input.Category != nil { rule.Category = *input.Category }
+		if input.NoteContains != nil { rule.NoteContains = *input.NoteContains }
+		if input.Merchant != nil { rule.Merchant = *input.Merchant }
+		if input.Currency != nil { rule.Currency = *input.Currency }
+		if input.Active != nil { rule.Active = *input.Active }
+		if err := bound(input.MinAmount, &rule.MinAmount); err != nil { return err }
+		return bound(input.MaxAmount, &rule.MaxAmount)
+	}
+
+	api.POST("/categorization-rules", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input CategorizationRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rule := models.CategorizationRule{UserID: userID, Active: true}
+		if err := apply(&rule, input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"}); return }
+		if err := svc.Create(&rule, input.Priority); err != nil { ruleError(c, err, "failed to create categorization rule"); return }
+		c.JSON(http.StatusCreated, rule)
+	})
+
+	type ReorderInput struct {
+		IDs []string `json:"ids" binding:"required"`
+	}
+
+	// Assigns priorities in the given order; unlisted rules follow in their current order
+	api.PUT("/categorization-rules/order", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input ReorderInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rules, err := svc.Reorder(userID, input.IDs)
+		if errors.Is(err, repository.ErrUnknownRule) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reorder categorization rules"}); return }
+		c.JSON(http.StatusOK, rules)
+	})
+
+	api.PATCH("/categorization-rules/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input CategorizationRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rule, err := svc.Find(userID, c.Param("id"))
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch categorization rule"}); return }
+		if err := apply(rule, input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid amount"}); return }
+		if err := svc.Update(rule); err != nil { ruleError(c, err, "failed to update categorization rule"); return }
+		c.JSON(http.StatusOK, rule)
+	})
+
+	api.DELETE("/categorization-rules/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.Delete(userID, c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete categorization rule"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+
+	type PreviewInput struct {
+		Note     string       `json:"note"`
+		Merchant string       `json:"merchant"`
+		Amount   models.Money `json:"amount"`
+		Currency string       `json:"currency" binding:"omitempty,iso4217"`
+	}
+
+	// Shows which category the active rules would give an entry, without storing anything
+	api.POST("/categorization-rules/preview", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input PreviewInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		match, err := svc.Preview(userID, input.Note, input.Merchant, input.Amount, input.Currency)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to preview categorization"}); return }
+		c.JSON(http.StatusOK, match)
+	})
+
+	type ApplyInput struct {
+		Month     string `json:"month" binding:"required"`
+		Overwrite bool   `json:"overwrite"`
+		DryRun    bool   `json:"dryRun"`
+	}
+
+	// Re-runs the rules over a month's spending entries; only uncategorized ones unless overwrite is set
+	api.POST("/categorization-rules/apply", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input ApplyInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if len(input.Month) != 7 || input.Month[4] != '-' { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month key"}); return }
+		res, err := svc.Recategorize(userID, input.Month, input.Overwrite, input.DryRun)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to apply categorization rules"}); return }
+		c.JSON(http.StatusOK, res)
+	})
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterCounterpartyRoutes wires the people and organisations debts are held with
+func RegisterCounterpartyRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewCounterpartyService(repository.NewCounterpartyRepository(db))
+
+	api.GET("/counterparties", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		items, err := svc.List(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list counterparties"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	type CreateCounterpartyInput struct {
+		Name string `json:"name" binding:"required"`
+		Note string `json:"note"`
+	}
+
+	api.POST("/counterparties", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input CreateCounterpartyInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if strings.TrimSpace(input.Name) == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "name is required"}); return }
+		item, err := svc.Create(userID, input.Name, input.Note)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create counterparty"}); return }
+		c.JSON(http.StatusCreated, item)
+	})
+
+	type UpdateCounterpartyInput struct {
+		Name *string `json:"name"`
+		Note *string `json:"note"`
+	}
+
+	api.PATCH("/counterparties/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input UpdateCounterpartyInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		updates := map[string]interface{}{}
+		if input.Name != nil {
+			name := strings.TrimSpace(*input.Name)
+			if name == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"}); return }
+			updates["name"] = name
+		}
+		if input.Note != nil { updates["note"] = *input.Note }
+		if len(updates) == 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"}); return }
+		item, err := svc.Update(userID, c.Param("id"), updates)
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "counterparty not found"}); return }
+		if err == repository.ErrCounterpartyNameTaken { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update counterparty"}); return }
+		c.JSON(http.StatusOK, item)
+	})
+
+	api.DELETE("/counterparties/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.Delete(userID, c.Param("id"))
+		if err == repository.ErrCounterpartyInUse { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete counterparty"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "counterparty not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+
+	// Net position per currency plus every debt and repayment with the counterparty, across months
+	api.GET("/counterparties/:id/balance", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		st, err := svc.Statement(userID, c.Param("id"))
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "counterparty not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load balance"}); return }
+		c.JSON(http.StatusOK, st)
+	})
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterEnvelopeRoutes wires envelope balances carried across months and transfers between envelopes
+func RegisterEnvelopeRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewEnvelopeService(repository.NewEnvelopeRepository(db))
+	fx := services.NewFxService(repository.NewFxRepository(db))
+
+	api.GET("/envelopes", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		mk := c.Query("month")
+		if len(mk) != 7 || mk[4] != '-' { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month key"}); return }
+		ok, err := svc.HasMonth(userID, mk)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load envelopes"}); return }
+		if !ok { c.JSON(http.StatusNotFound, gin.H{"error": "month not found"}); return }
+		report, err := svc.Envelopes(fx.Converter(userID), userID, mk)
+		var missing *services.MissingRatesError
+		if errors.As(err, &missing) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing exchange rate", "missing": missing.Missing}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load envelopes"}); return }
+		c.JSON(http.StatusOK, report)
+	})
+
+	api.GET("/envelopes/transfers", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		items, err := svc.ListTransfers(userID, c.Query("month"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list transfers"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	type CreateTransferInput struct {
+		MonthKey string       `json:"monthKey" binding:"required"`
+		From     string       `json:"from" binding:"required"`
+		To       string       `json:"to" binding:"required"`
+		Amount   models.Money `json:"amount" binding:"required"`
+		Currency string       `json:"currency" binding:"omitempty,iso4217"`
+		Note     string       `json:"note"`
+	}
+
+	api.POST("/envelopes/transfers", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input CreateTransferInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if len(input.MonthKey) != 7 || input.MonthKey[4] != '-' { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month key"}); return }
+		t := models.EnvelopeTransfer{UserID: userID, MonthKey: input.MonthKey, FromCategory: input.From, ToCategory: input.To, Amount: input.Amount, Currency: input.Currency, Note: input.Note}
+		err := svc.CreateTransfer(&t)
+		if errors.Is(err, services.ErrInvalidTransfer) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		if errors.Is(err, repository.ErrUnknownCategory) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create transfer"}); return }
+		c.JSON(http.StatusCreated, t)
+	})
+
+	api.DELETE("/envelopes/transfers/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.DeleteTransfer(userID, c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete transfer"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+}
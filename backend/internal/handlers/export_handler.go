@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// maxRestoreBytes caps uploaded export bundles
+const maxRestoreBytes = 50 << 20
+
+// RegisterExportRoutes wires account data export (zip of JSON + CSV) and restore
+func RegisterExportRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewExportService(repository.NewExportRepository(db), repository.NewGoalRepository(db))
+
+	api.GET("/export", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		filename := fmt.Sprintf("achieving-export-%s.zip", time.Now().Format("20060102"))
+		c.Header("Content-Type", "application/zip")
+		c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+		c.Status(http.StatusOK)
+		// Headers are already sent while streaming; a failure can only be logged
+		if err := svc.WriteZip(userID, c.Writer); err != nil {
+			log.Printf("export for user %s failed: %v", userID, err)
+		}
+	})
+
+	// Multipart form: file (zip produced by GET /export)
+	api.POST("/export/restore", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRestoreBytes)
+		fh, err := c.FormFile("file")
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"}); return }
+		f, err := fh.Open()
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"}); return }
+		defer f.Close()
+		ef, err := services.ReadZip(f, fh.Size)
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		if err := svc.Restore(userID, ef); err != nil {
+			if errors.Is(err, services.ErrAccountNotEmpty) { c.JSON(http.StatusConflict, gin.H{"error": "account already contains other data"}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore bundle"}); return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"months": len(ef.Months), "categories": len(ef.Categories), "plans": len(ef.Plans),
+			"spending": len(ef.Spending), "earnings": len(ef.Earnings), "borrows": len(ef.Borrows),
+			"goals": len(ef.Goals), "goalContributions": len(ef.GoalContributions),
+			"recurringRules": len(ef.RecurringRules), "categorizationRules": len(ef.CategorizationRules),
+			"planTemplates": len(ef.PlanTemplates), "notificationChannels": len(ef.NotificationChannels),
+		})
+	})
+}
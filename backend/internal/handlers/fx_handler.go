@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/middleware"
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// maxFxImportBytes caps uploaded rate files
+const maxFxImportBytes = 10 << 20
+
+// RegisterFxRoutes wires exchange rate listing and the admin endpoints that maintain rates
+func RegisterFxRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewFxService(repository.NewFxRepository(db))
+
+	// GET /fx-rates?base=USD&quote=KHR&from=YYYY-MM-DD&to=YYYY-MM-DD (latest 1000)
+	api.GET("/fx-rates", func(c *gin.Context) {
+		var from, to *time.Time
+		if v := c.Query("from"); v != "" {
+			d, err := parseISODate(v)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid from"}); return }
+			from = &d
+		}
+		if v := c.Query("to"); v != "" {
+			d, err := parseISODate(v)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid to"}); return }
+			to = &d
+		}
+		rates, err := svc.ListRates(c.Query("base"), c.Query("quote"), from, to)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list rates"}); return }
+		c.JSON(http.StatusOK, rates)
+	})
+
+	admin := api.Group("/admin", middleware.AdminRequired())
+
+	type FxRateInput struct {
+		Date   string  `json:"date" binding:"required"`
+		Base   string  `json:"base" binding:"required,iso4217"`
+		Quote  string  `json:"quote" binding:"required,iso4217"`
+		Rate   float64 `json:"rate" binding:"required,gt=0"`
+		Source string  `json:"source"`
+	}
+	// Body: a JSON array of rates; existing (base, quote, date) rows are overwritten
+	admin.POST("/fx-rates", func(c *gin.Context) {
+		var input []FxRateInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rates := make([]models.FxRate, 0, len(input))
+		for _, in := range input {
+			d, err := parseISODate(in.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			rates = append(rates, models.FxRate{Date: d, Base: in.Base, Quote: in.Quote, Rate: in.Rate, Source: in.Source})
+		}
+		if err := svc.SaveRates(rates); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		c.JSON(http.StatusOK, gin.H{"saved": len(rates)})
+	})
+
+	// Multipart form: file (CSV date,base,quote,rate[,source]), source (default for rows without one)
+	admin.POST("/fx-rates/import", func(c *gin.Context) {
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFxImportBytes)
+		fh, err := c.FormFile("file")
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"}); return }
+		f, err := fh.Open()
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"}); return }
+		defer f.Close()
+		rates, err := services.ParseFxCSV(f, c.DefaultPostForm("source", "import"))
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		if err := svc.SaveRates(rates); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		c.JSON(http.StatusOK, gin.H{"saved": len(rates)})
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"time"
 
@@ -11,20 +12,20 @@ import (
 	"achieving-backend/internal/models"
 	"achieving-backend/internal/repository"
 	"achieving-backend/internal/services"
-	"achieving-backend/internal/middleware"
 )
 
 // RegisterGoalRoutes wires goal endpoints into the provided router group
 func RegisterGoalRoutes(api *gin.RouterGroup, db *gorm.DB) {
 	repo := repository.NewGoalRepository(db)
 	svc := services.NewGoalService(repo)
-	api.Use(middleware.AuthRequired())
+	fx := services.NewFxService(repository.NewFxRepository(db))
 
 	api.GET("/goals", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		goals, err := svc.ListGoals(userID)
+		if err == nil { err = svc.WithBase(fx.Converter(userID), userID, goals) }
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list goals"})
 			return
@@ -33,16 +34,17 @@ func RegisterGoalRoutes(api *gin.RouterGroup, db *gorm.DB) {
 	})
 
 	type CreateGoalInput struct {
-		Title         string   `json:"title" binding:"required"`
-		Description   *string  `json:"description"`
-		Category      *string  `json:"category"`
-		SaveFrequency *string  `json:"saveFrequency"`
-		Duration      *int     `json:"duration"`
-		StartDate     *string  `json:"startDate"`
-		EndDate       *string  `json:"endDate"`
-		TargetDate    *string  `json:"targetDate"`
-		TargetAmount  *float64 `json:"targetAmount"`
-		CurrentAmount *float64 `json:"currentAmount"`
+		Title         string        `json:"title" binding:"required"`
+		Description   *string       `json:"description"`
+		Category      *string       `json:"category"`
+		SaveFrequency *string       `json:"saveFrequency"`
+		Duration      *int          `json:"duration"`
+		StartDate     *string       `json:"startDate"`
+		EndDate       *string       `json:"endDate"`
+		TargetDate    *string       `json:"targetDate"`
+		TargetAmount  *models.Money `json:"targetAmount"`
+		CurrentAmount *models.Money `json:"currentAmount"`
+		Currency      string        `json:"currency" binding:"omitempty,iso4217"`
 	}
 
 	api.POST("/goals", func(c *gin.Context) {
@@ -100,8 +102,11 @@ func RegisterGoalRoutes(api *gin.RouterGroup, db *gorm.DB) {
 			TargetAmount:  input.TargetAmount,
 			CurrentAmount: input.CurrentAmount,
 		}
-		// Create via service using per-user scoping
-		created, err := svc.CreateGoal(userID, g.Title, g.Description, g.Category, g.SaveFrequency, g.Duration, g.StartDate, g.EndDate, g.TargetDate, g.TargetAmount)
+		// Create via service using per-user scoping; an initial saved amount becomes the opening ledger entry
+		var opening models.Money
+		if input.CurrentAmount != nil { opening = *input.CurrentAmount }
+		created, err := svc.CreateGoal(userID, g.Title, g.Description, g.Category, g.SaveFrequency, g.Duration, g.StartDate, g.EndDate, g.TargetDate, g.TargetAmount, input.Currency, opening)
+		if errors.Is(err, services.ErrScheduleTooFine) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create goal"})
 			return
@@ -127,27 +132,28 @@ func RegisterGoalRoutes(api *gin.RouterGroup, db *gorm.DB) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"})
 			return
 		}
-		updates := map[string]interface{}{"status": input.Status}
-		rows, err := svc.UpdateGoal(userID, id, updates)
+		// Status is derived from contributions; only the derived value is accepted
+		err := svc.SetStatus(userID, id, input.Status)
+		if errors.Is(err, gorm.ErrRecordNotFound) { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+		if errors.Is(err, services.ErrDerivedStatus) { c.JSON(http.StatusConflict, gin.H{"error": err.Error()}); return }
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update status"})
 			return
 		}
-		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
 		c.Status(http.StatusNoContent)
 	})
 
 	type UpdateGoalInput struct {
-		Title         *string  `json:"title"`
-		Description   *string  `json:"description"`
-		Category      *string  `json:"category"`
-		SaveFrequency *string  `json:"saveFrequency"`
-		Duration      *int     `json:"duration"`
-		StartDate     *string  `json:"startDate"`
-		EndDate       *string  `json:"endDate"`
-		TargetDate    *string  `json:"targetDate"`
-		TargetAmount  *float64 `json:"targetAmount"`
-		CurrentAmount *float64 `json:"currentAmount"`
+		Title         *string       `json:"title"`
+		Description   *string       `json:"description"`
+		Category      *string       `json:"category"`
+		SaveFrequency *string       `json:"saveFrequency"`
+		Duration      *int          `json:"duration"`
+		StartDate     *string       `json:"startDate"`
+		EndDate       *string       `json:"endDate"`
+		TargetDate    *string       `json:"targetDate"`
+		TargetAmount  *models.Money `json:"targetAmount"`
+		CurrentAmount *models.Money `json:"currentAmount"`
 	}
 
 	api.PUT("/goals/:id", func(c *gin.Context) {
@@ -182,16 +188,97 @@ func RegisterGoalRoutes(api *gin.RouterGroup, db *gorm.DB) {
 			}
 		}
 		if input.TargetAmount != nil { updates["target_amount"] = *input.TargetAmount }
-		if input.CurrentAmount != nil { updates["current_amount"] = *input.CurrentAmount }
 
-		rows, err := svc.UpdateGoal(userID, id, updates)
-		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update goal"}); return }
-		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+		if _, err := svc.FindGoal(userID, id); err != nil {
+			if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch goal"}); return
+		}
+		if len(updates) > 0 {
+			if _, err := svc.UpdateGoal(userID, id, updates); err != nil {
+				if errors.Is(err, services.ErrScheduleTooFine) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update goal"}); return
+			}
+		}
+		// currentAmount is derived from the ledger; a direct value is recorded as an adjustment
+		if input.CurrentAmount != nil {
+			if err := svc.SetCurrentAmount(userID, id, *input.CurrentAmount); err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update goal"}); return }
+		}
 		g, err := svc.FindGoal(userID, id)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch updated goal"}); return }
 		c.JSON(http.StatusOK, g)
 	})
 
+	// Contributions ledger
+	api.GET("/goals/:id/contributions", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		if _, err := svc.FindGoal(userID, id); err != nil {
+			if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch goal"}); return
+		}
+		items, err := svc.ListContributions(userID, id)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list contributions"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	type CreateContributionInput struct {
+		Amount   models.Money `json:"amount" binding:"required"`
+		Currency string       `json:"currency" binding:"omitempty,iso4217"`
+		Date     string       `json:"date"`
+		Note     string       `json:"note"`
+	}
+
+	api.POST("/goals/:id/contributions", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		var input CreateContributionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+			return
+		}
+		d := time.Now()
+		if input.Date != "" {
+			parsed, err := parseISODate(input.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			d = parsed
+		}
+		// Amounts in another currency are converted into the goal's currency
+		item, err := svc.AddContributionIn(fx.Converter(userID), userID, id, input.Amount, input.Currency, d, input.Note)
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+		var missing *services.MissingRatesError
+		if errors.As(err, &missing) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing exchange rate", "missing": missing.Missing}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create contribution"}); return }
+		c.JSON(http.StatusCreated, item)
+	})
+
+	api.DELETE("/goals/:id/contributions/:contributionId", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		rows, err := svc.DeleteContribution(userID, id, c.Param("contributionId"))
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete contribution"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "contribution not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+
+	// Savings schedule (installments per SaveFrequency period)
+	api.GET("/goals/:id/schedule", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		items, err := svc.Schedule(userID, id)
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "goal not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load schedule"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
 	api.DELETE("/goals/:id", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// maxImportBytes caps uploaded statement size
+const maxImportBytes = 5 << 20
+
+// RegisterImportRoutes wires bank statement import (CSV with column mapping, OFX/QFX)
+func RegisterImportRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewImportService(repository.NewSpendingRepository(db))
+	fx := services.NewFxService(repository.NewFxRepository(db))
+
+	// Multipart form: file, format (csv|ofx|qfx, inferred from the file name when empty),
+	// mapping (JSON CSVMapping, CSV only), kind (spending|earning, optional), dryRun (true|false),
+	// defaultCategory (for spending rows no categorization rule matches; the mapping's takes precedence)
+	api.POST("/import", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBytes)
+		fh, err := c.FormFile("file")
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"}); return }
+		format := strings.ToLower(c.PostForm("format"))
+		if format == "" { format = strings.TrimPrefix(strings.ToLower(filepath.Ext(fh.Filename)), ".") }
+		kind := c.PostForm("kind")
+		if kind != "" && kind != "spending" && kind != "earning" { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid kind"}); return }
+		dryRun := c.PostForm("dryRun") == "true" || c.Query("dryRun") == "true"
+
+		f, err := fh.Open()
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "failed to read file"}); return }
+		defer f.Close()
+
+		var rows []services.ImportRow
+		var rowErrs []services.ImportRowError
+		defaultCategory := c.PostForm("defaultCategory")
+		switch format {
+		case "csv":
+			var mapping services.CSVMapping
+			if err := json.Unmarshal([]byte(c.PostForm("mapping")), &mapping); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid mapping"}); return }
+			if mapping.DefaultCategory != "" { defaultCategory = mapping.DefaultCategory }
+			rows, rowErrs, err = services.ParseCSVStatement(f, mapping)
+		case "ofx", "qfx":
+			rows, rowErrs, err = services.ParseOFXStatement(f)
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported format"}); return
+		}
+		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+
+		res, err := svc.Import(fx.Converter(userID), userID, rows, rowErrs, services.ImportOptions{Kind: kind, DryRun: dryRun, DefaultCategory: defaultCategory})
+		if errors.Is(err, services.ErrImportRows) { c.JSON(http.StatusUnprocessableEntity, res); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import statement"}); return }
+		if dryRun { c.JSON(http.StatusOK, res); return }
+		c.JSON(http.StatusCreated, res)
+	})
+}
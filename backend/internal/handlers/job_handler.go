@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/middleware"
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterJobRoutes wires the admin endpoints that expose the background jobs queue
+func RegisterJobRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewJobService(repository.NewJobRepository(db))
+	admin := api.Group("/admin", middleware.AdminRequired())
+
+	admin.GET("/jobs/status", func(c *gin.Context) {
+		st, err := svc.Status(time.Now())
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load queue status"}); return }
+		c.JSON(http.StatusOK, st)
+	})
+
+	// GET /admin/jobs?status=dead&kind=month_summary&limit=50 (newest first, max 500)
+	admin.GET("/jobs", func(c *gin.Context) {
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 || limit > 500 { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"}); return }
+		status := c.Query("status")
+		switch status {
+		case "", models.JobQueued, models.JobRunning, models.JobDone, models.JobDead:
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid status"}); return
+		}
+		items, err := svc.List(status, c.Query("kind"), limit)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jobs"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	// Re-queues a dead-lettered job with a fresh set of attempts
+	admin.POST("/jobs/:id/retry", func(c *gin.Context) {
+		rows, err := svc.Requeue(c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retry job"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "no dead job with this id"}); return }
+		c.Status(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/notifications"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterNotificationRoutes wires the user's notifications and their outbound channel settings
+func RegisterNotificationRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	repo := repository.NewNotificationRepository(db)
+	svc := services.NewNotificationService(repo)
+	sender := notifications.NewDispatcher(repo, notifications.FromEnv())
+
+	// ?unread=true lists unread notifications only; limit defaults to 50 (max 200)
+	api.GET("/notifications", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+		if err != nil || limit < 1 || limit > 200 { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid limit"}); return }
+		items, err := svc.List(userID, c.Query("unread") == "true", limit)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list notifications"}); return }
+		unread, err := svc.UnreadCount(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list notifications"}); return }
+		c.JSON(http.StatusOK, gin.H{"items": items, "unread": unread})
+	})
+
+	type MarkReadInput struct {
+		Read *bool `json:"read" binding:"required"`
+	}
+
+	api.PATCH("/notifications/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input MarkReadInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		n, err := svc.SetRead(userID, c.Param("id"), *input.Read)
+		if errors.Is(err, gorm.ErrRecordNotFound) { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notification"}); return }
+		c.JSON(http.StatusOK, n)
+	})
+
+	// Marks every unread notification read
+	api.PATCH("/notifications", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		n, err := svc.MarkAllRead(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update notifications"}); return }
+		c.JSON(http.StatusOK, gin.H{"updated": n})
+	})
+
+	// Outbox status of a notification per channel
+	api.GET("/notifications/:id/deliveries", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		items, err := svc.Deliveries(userID, c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list deliveries"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	// available lists the channels this server can send through
+	api.GET("/notification-channels", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		items, err := svc.ListChannels(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list channels"}); return }
+		c.JSON(http.StatusOK, gin.H{"channels": items, "available": sender.Channels()})
+	})
+
+	type ChannelInput struct {
+		Address string `json:"address" binding:"required"`
+		Enabled *bool  `json:"enabled"`
+	}
+
+	// Only notifications created afterwards are delivered to a new or changed address
+	api.PUT("/notification-channels/:channel", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input ChannelInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		enabled := input.Enabled == nil || *input.Enabled
+		ch, err := svc.SaveChannel(userID, c.Param("channel"), input.Address, enabled)
+		if errors.Is(err, services.ErrInvalidChannel) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save channel"}); return }
+		c.JSON(http.StatusOK, ch)
+	})
+
+	api.DELETE("/notification-channels/:channel", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.DeleteChannel(userID, c.Param("channel"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete channel"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+
+	// Sends a test message right away, bypassing the outbox
+	api.POST("/notification-channels/:channel/test", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		ch, err := svc.FindChannel(userID, c.Param("channel"))
+		if errors.Is(err, gorm.ErrRecordNotFound) { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load channel"}); return }
+		msg := notifications.Message{Title: "Achieving test message", Body: "Notifications will be delivered here."}
+		if err := sender.Send(c.Request.Context(), ch.Channel, ch.Address, msg); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{"error": "delivery failed", "detail": err.Error()}); return
+		}
+		c.Status(http.StatusNoContent)
+	})
+}
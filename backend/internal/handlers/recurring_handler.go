@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterRecurringRoutes wires CRUD for recurring spending/earning rules
+func RegisterRecurringRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewRecurringService(repository.NewRecurringRepository(db), services.NewSpendingService(repository.NewSpendingRepository(db)), services.NewFxService(repository.NewFxRepository(db)))
+
+	api.GET("/recurring", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rules, err := svc.ListRules(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list recurring rules"}); return }
+		c.JSON(http.StatusOK, rules)
+	})
+
+	type RecurringRuleInput struct {
+		Kind      *string       `json:"kind"`
+		Amount    *models.Money `json:"amount"`
+		Currency  *string       `json:"currency" binding:"omitempty,iso4217"`
+		Category  *string       `json:"category"`
+		Source    *string       `json:"source"`
+		Note      *string       `json:"note"`
+		RRule     *string       `json:"rrule"`
+		StartDate *string       `json:"startDate"`
+		EndDate   *string       `json:"endDate"`
+		Active    *bool         `json:"active"`
+	}
+	// apply copies the provided fields onto rule; an empty endDate clears it
+	apply := func(rule *models.RecurringRule, input RecurringRuleInput) error {
+		if input.Kind != nil { rule.Kind = *input.Kind }
+		if input.Amount != nil { rule.Amount = *input.Amount }
+		if input.Currency != nil && *input.Currency != "" { rule.Currency = *input.Currency }
+		if input.Category != nil { rule.Category = *input.Category }
+		if input.Source != nil { rule.Source = *input.Source }
+		if input.Note != nil { rule.Note = *input.Note }
+		if input.RRule != nil { rule.RRule = *input.RRule }
+		if input.StartDate != nil {
+			d, err := parseISODate(*input.StartDate)
+			if err != nil { return err }
+			rule.StartDate = d
+		}
+		if input.EndDate != nil {
+			if *input.EndDate == "" { rule.EndDate = nil } else {
+				d, err := parseISODate(*input.EndDate)
+				if err != nil { return err }
+				rule.EndDate = &d
+			}
+		}
+		return nil
+	}
+
+	api.POST("/recurring", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input RecurringRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rule := models.RecurringRule{UserID: userID, StartDate: time.Now()}
+		if err := apply(&rule, input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+		if err := svc.CreateRule(&rule); err != nil {
+			if errors.Is(err, services.ErrInvalidRule) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create recurring rule"}); return
+		}
+		c.JSON(http.StatusCreated, rule)
+	})
+
+	api.PATCH("/recurring/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input RecurringRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		rule, err := svc.FindRule(userID, c.Param("id"))
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch recurring rule"}); return }
+		if err := apply(rule, input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+		if input.Active != nil { rule.Active = *input.Active }
+		if err := svc.UpdateRule(rule); err != nil {
+			if errors.Is(err, services.ErrInvalidRule) { c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update recurring rule"}); return
+		}
+		c.JSON(http.StatusOK, rule)
+	})
+
+	api.DELETE("/recurring/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.DeleteRule(userID, c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete recurring rule"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+}
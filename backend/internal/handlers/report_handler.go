@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterReportRoutes wires multi-month reporting endpoints
+func RegisterReportRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewReportService(repository.NewReportRepository(db))
+	fx := services.NewFxService(repository.NewFxRepository(db))
+
+	// GET /reports/trends?from=YYYY-MM&to=YYYY-MM&groupBy=category|source&window=3
+	// Defaults to the last 12 months ending with the current month.
+	api.GET("/reports/trends", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		to := c.DefaultQuery("to", time.Now().Format("2006-01"))
+		from := c.Query("from")
+		if from == "" {
+			t, err := time.Parse("2006-01", to)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month range"}); return }
+			from = t.AddDate(0, -11, 0).Format("2006-01")
+		}
+		groupBy := c.Query("groupBy")
+		if groupBy != "" && groupBy != "category" && groupBy != "source" { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid groupBy"}); return }
+		window, _ := strconv.Atoi(c.DefaultQuery("window", "3"))
+		report, err := svc.Trends(fx.Converter(userID), userID, from, to, groupBy, window)
+		if errors.Is(err, services.ErrInvalidRange) { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month range"}); return }
+		var missing *services.MissingRatesError
+		if errors.As(err, &missing) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing exchange rate", "missing": missing.Missing}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build report"}); return }
+		c.JSON(http.StatusOK, report)
+	})
+}
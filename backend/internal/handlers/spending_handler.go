@@ -1,17 +1,49 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"achieving-backend/internal/models"
 	"achieving-backend/internal/services"
 	"achieving-backend/internal/repository"
- 	"achieving-backend/internal/middleware"
 )
 
+// entryUpdateError maps errors from the PATCH entry endpoints to responses
+func entryUpdateError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, repository.ErrVersionConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": "entry was modified, reload and retry"})
+	case errors.Is(err, repository.ErrUnknownCounterparty):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "unknown counterparty"})
+	case errors.Is(err, repository.ErrRepaymentExceedsBalance):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "amount is below what has already been repaid"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
+// categoryError maps errors from the category endpoints to responses
+func categoryError(c *gin.Context, err error, msg string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+	case errors.Is(err, repository.ErrInvalidParent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, repository.ErrCategoryExists), errors.Is(err, repository.ErrPlanCurrencyConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+	}
+}
+
 func parseISODate(s string) (time.Time, error) {
 	if t, err := time.Parse(time.RFC3339, s); err == nil { return t, nil }
 	return time.Parse("2006-01-02", s)
@@ -20,7 +52,7 @@ func parseISODate(s string) (time.Time, error) {
 // RegisterSpendingRoutes wires spend-related endpoints into the router group
 func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 	svc := services.NewSpendingService(repository.NewSpendingRepository(db))
-	api.Use(middleware.AuthRequired())
+	fx := services.NewFxService(repository.NewFxRepository(db))
 	// Spending entries
 	api.GET("/spending", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
@@ -31,7 +63,8 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list spending"}); return }
 		c.JSON(http.StatusOK, entries)
 	})
-	type CreateSpendingInput struct { Amount float64 `json:"amount" binding:"required"`; Category string `json:"category" binding:"required"`; Date string `json:"date" binding:"required"`; Note string `json:"note"` }
+	type CreateSpendingInput struct { Amount models.Money `json:"amount" binding:"required"`; Category string `json:"category"`; Date string `json:"date" binding:"required"`; Note string `json:"note"`; Merchant string `json:"merchant" binding:"max=255"`; Currency string `json:"currency" binding:"omitempty,iso4217"` }
+	// Without a category, the user's categorization rules assign one
 	api.POST("/spending", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -40,10 +73,36 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		d, err := parseISODate(input.Date)
 		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
-		entry, err := svc.CreateSpending(userID, input.Amount, input.Category, d, input.Note)
+		entry, err := svc.CreateSpending(fx.Converter(userID), userID, input.Amount, input.Category, d, input.Note, input.Merchant, input.Currency)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create spending"}); return }
 		c.JSON(http.StatusCreated, entry)
 	})
+	type UpdateSpendingInput struct { Version int64 `json:"version" binding:"required"`; Amount *models.Money `json:"amount"`; Category *string `json:"category"`; Date *string `json:"date"`; Note *string `json:"note"`; Merchant *string `json:"merchant" binding:"omitempty,max=255"`; Currency *string `json:"currency" binding:"omitempty,iso4217"` }
+	api.PATCH("/spending/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		var input UpdateSpendingInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		updates := map[string]interface{}{}
+		if input.Amount != nil { updates["amount"] = *input.Amount }
+		if input.Category != nil {
+			if *input.Category == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "category cannot be empty"}); return }
+			updates["category"] = *input.Category
+		}
+		if input.Date != nil {
+			d, err := parseISODate(*input.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			updates["date"] = d
+		}
+		if input.Note != nil { updates["note"] = *input.Note }
+		if input.Merchant != nil { updates["merchant"] = *input.Merchant }
+		if input.Currency != nil { updates["currency"] = *input.Currency }
+		entry, err := svc.UpdateSpending(fx.Converter(userID), userID, id, input.Version, updates)
+		if err != nil { entryUpdateError(c, err, "failed to update spending"); return }
+		c.JSON(http.StatusOK, entry)
+	})
 	api.DELETE("/spending/:id", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -64,7 +123,7 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list earnings"}); return }
 		c.JSON(http.StatusOK, items)
 	})
-	type CreateEarningInput struct { Source string `json:"source" binding:"required"`; Amount float64 `json:"amount" binding:"required"`; Date string `json:"date" binding:"required"` }
+	type CreateEarningInput struct { Source string `json:"source" binding:"required"`; Amount models.Money `json:"amount" binding:"required"`; Date string `json:"date" binding:"required"`; Currency string `json:"currency" binding:"omitempty,iso4217"` }
 	api.POST("/earnings", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -73,10 +132,34 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		d, err := parseISODate(input.Date)
 		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
-		item, err := svc.CreateEarning(userID, input.Source, input.Amount, d)
+		item, err := svc.CreateEarning(userID, input.Source, input.Amount, d, input.Currency)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create earning"}); return }
 		c.JSON(http.StatusCreated, item)
 	})
+	type UpdateEarningInput struct { Version int64 `json:"version" binding:"required"`; Source *string `json:"source"`; Amount *models.Money `json:"amount"`; Date *string `json:"date"`; Currency *string `json:"currency" binding:"omitempty,iso4217"` }
+	api.PATCH("/earnings/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		var input UpdateEarningInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		updates := map[string]interface{}{}
+		if input.Source != nil {
+			if *input.Source == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "source cannot be empty"}); return }
+			updates["source"] = *input.Source
+		}
+		if input.Amount != nil { updates["amount"] = *input.Amount }
+		if input.Date != nil {
+			d, err := parseISODate(*input.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			updates["date"] = d
+		}
+		if input.Currency != nil { updates["currency"] = *input.Currency }
+		item, err := svc.UpdateEarning(userID, id, input.Version, updates)
+		if err != nil { entryUpdateError(c, err, "failed to update earning"); return }
+		c.JSON(http.StatusOK, item)
+	})
 	api.DELETE("/earnings/:id", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -93,11 +176,17 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		month := c.Query("month")
-		items, err := svc.ListBorrows(userID, month)
+		// status=outstanding lists open debts across all months unless month is also given
+		status := c.Query("status")
+		if status != "" && status != "outstanding" && status != "settled" { c.JSON(http.StatusBadRequest, gin.H{"error": "status must be outstanding or settled"}); return }
+		direction := c.Query("direction")
+		if direction != "" && direction != models.DirectionBorrowed && direction != models.DirectionLent { c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be borrowed or lent"}); return }
+		items, err := svc.ListBorrows(userID, month, direction, status)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list borrows"}); return }
 		c.JSON(http.StatusOK, items)
 	})
-	type CreateBorrowInput struct { From string `json:"from" binding:"required"`; Amount float64 `json:"amount" binding:"required"`; Date string `json:"date" binding:"required"` }
+	// A debt names its counterparty by counterpartyId, or by name in from (created when new)
+	type CreateBorrowInput struct { CounterpartyID string `json:"counterpartyId" binding:"required_without=From"`; From string `json:"from"`; Direction string `json:"direction" binding:"omitempty,oneof=borrowed lent"`; Amount models.Money `json:"amount" binding:"required"`; Date string `json:"date" binding:"required"`; Currency string `json:"currency" binding:"omitempty,iso4217"`; DueDate string `json:"dueDate"`; InterestRate float64 `json:"interestRate" binding:"gte=0,lte=1000"`; Compounding string `json:"compounding" binding:"omitempty,oneof=simple daily monthly yearly"` }
 	api.POST("/borrows", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -106,11 +195,57 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		d, err := parseISODate(input.Date)
 		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
-		item, err := svc.CreateBorrow(userID, input.From, input.Amount, d)
+		entry := models.BorrowEntry{Direction: input.Direction, Amount: input.Amount, Date: d, Currency: input.Currency, InterestRate: input.InterestRate, Compounding: input.Compounding}
+		if input.DueDate != "" {
+			due, err := parseISODate(input.DueDate)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dueDate"}); return }
+			entry.DueDate = &due
+		}
+		item, err := svc.CreateBorrow(userID, input.CounterpartyID, input.From, entry)
+		if err == repository.ErrUnknownCounterparty { c.JSON(http.StatusBadRequest, gin.H{"error": "unknown counterparty"}); return }
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create borrow"}); return }
 		c.JSON(http.StatusCreated, item)
 	})
-	type UpdateRepaymentInput struct { RepaidAmount float64 `json:"repaidAmount" binding:"required"`; RepaidDate string `json:"repaidDate" binding:"required"` }
+	type UpdateBorrowInput struct { Version int64 `json:"version" binding:"required"`; CounterpartyID *string `json:"counterpartyId"`; From *string `json:"from"`; Direction *string `json:"direction" binding:"omitempty,oneof=borrowed lent"`; Amount *models.Money `json:"amount"`; Date *string `json:"date"`; Currency *string `json:"currency" binding:"omitempty,iso4217"`; DueDate *string `json:"dueDate"`; InterestRate *float64 `json:"interestRate" binding:"omitempty,gte=0,lte=1000"`; Compounding *string `json:"compounding" binding:"omitempty,oneof=simple daily monthly yearly"` }
+	api.PATCH("/borrows/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		var input UpdateBorrowInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		updates := map[string]interface{}{}
+		if input.From != nil {
+			if *input.From == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "from cannot be empty"}); return }
+			updates["from"] = *input.From
+		}
+		if input.CounterpartyID != nil {
+			if *input.CounterpartyID == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "counterpartyId cannot be empty"}); return }
+			updates["counterparty_id"] = *input.CounterpartyID
+		}
+		if input.Direction != nil { updates["direction"] = *input.Direction }
+		// An empty dueDate clears it
+		if input.DueDate != nil {
+			if *input.DueDate == "" { updates["due_date"] = nil } else {
+				due, err := parseISODate(*input.DueDate)
+				if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dueDate"}); return }
+				updates["due_date"] = due
+			}
+		}
+		if input.InterestRate != nil { updates["interest_rate"] = *input.InterestRate }
+		if input.Compounding != nil { updates["compounding"] = *input.Compounding }
+		if input.Amount != nil { updates["amount"] = *input.Amount }
+		if input.Date != nil {
+			d, err := parseISODate(*input.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			updates["date"] = d
+		}
+		if input.Currency != nil { updates["currency"] = *input.Currency }
+		item, err := svc.UpdateBorrow(userID, id, input.Version, updates)
+		if err != nil { entryUpdateError(c, err, "failed to update borrow"); return }
+		c.JSON(http.StatusOK, item)
+	})
+	type UpdateRepaymentInput struct { RepaidAmount models.Money `json:"repaidAmount" binding:"required"`; RepaidDate string `json:"repaidDate" binding:"required"` }
 	api.PATCH("/borrows/:id/repayment", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -118,13 +253,90 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		id := c.Param("id")
 		var input UpdateRepaymentInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if input.RepaidAmount < 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "repaidAmount must not be negative"}); return }
 		d, err := parseISODate(input.RepaidDate)
 		if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
 		rows, err := svc.UpdateBorrowRepayment(userID, id, input.RepaidAmount, d)
+		if err == repository.ErrRepaymentExceedsBalance { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()}); return }
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update borrow"}); return }
 		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
 		c.Status(http.StatusNoContent)
 	})
+	// Debts past their due date with an amount still owed (?asOf=YYYY-MM-DD, default today)
+	api.GET("/borrows/overdue", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		asOf := time.Now()
+		if v := c.Query("asOf"); v != "" {
+			d, err := parseISODate(v)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid asOf"}); return }
+			asOf = d
+		}
+		items, err := svc.Overdue(userID, asOf)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list overdue borrows"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+	// Amount owed including accrued interest (?asOf=YYYY-MM-DD, default today)
+	api.GET("/borrows/:id/accrual", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		asOf := time.Now()
+		if v := c.Query("asOf"); v != "" {
+			d, err := parseISODate(v)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid asOf"}); return }
+			asOf = d
+		}
+		st, err := svc.DebtAccrual(userID, c.Param("id"), asOf)
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "borrow not found"}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to compute accrual"}); return }
+		c.JSON(http.StatusOK, st)
+	})
+	// Repayment ledger
+	api.GET("/borrows/:id/repayments", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		if _, err := svc.FindBorrow(userID, id); err != nil {
+			if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "borrow not found"}); return }
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch borrow"}); return
+		}
+		items, err := svc.ListRepayments(userID, id)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list repayments"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+	type CreateRepaymentInput struct { Amount models.Money `json:"amount" binding:"required"`; Date string `json:"date"`; Note string `json:"note"` }
+	api.POST("/borrows/:id/repayments", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		id := c.Param("id")
+		var input CreateRepaymentInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if input.Amount <= 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be positive"}); return }
+		d := time.Now()
+		if input.Date != "" {
+			parsed, err := parseISODate(input.Date)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid date"}); return }
+			d = parsed
+		}
+		item, err := svc.CreateRepayment(userID, id, input.Amount, d, input.Note)
+		if err == gorm.ErrRecordNotFound { c.JSON(http.StatusNotFound, gin.H{"error": "borrow not found"}); return }
+		if err == repository.ErrRepaymentExceedsBalance { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create repayment"}); return }
+		c.JSON(http.StatusCreated, item)
+	})
+	api.DELETE("/borrows/:id/repayments/:repaymentId", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.DeleteRepayment(userID, c.Param("id"), c.Param("repaymentId"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete repayment"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "repayment not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
 	api.DELETE("/borrows/:id", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -140,28 +352,72 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
-		cats, err := svc.ListCategories(userID)
+		// ?archived=true|false filters on the archive flag; all categories are listed by default
+		var archived *bool
+		if v := c.Query("archived"); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid archived"}); return }
+			archived = &b
+		}
+		cats, err := svc.ListCategories(userID, archived)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list categories"}); return }
 		c.JSON(http.StatusOK, cats)
 	})
-	type CreateCategoryInput struct { Name string `json:"name" binding:"required"` }
+	type CreateCategoryInput struct { Name string `json:"name" binding:"required"`; Parent string `json:"parent"`; Color string `json:"color" binding:"omitempty,hexcolor,len=7"`; Icon string `json:"icon" binding:"max=64"`; Type string `json:"type" binding:"omitempty,oneof=essential discretionary"`; SortOrder int `json:"sortOrder"`; Archived bool `json:"archived"` }
 	api.POST("/categories", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		var input CreateCategoryInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
-		cat, err := svc.CreateCategory(userID, input.Name)
-		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create category"}); return }
+		cat, err := svc.CreateCategory(userID, models.Category{Name: input.Name, Parent: input.Parent, Color: input.Color, Icon: input.Icon, Type: input.Type, SortOrder: input.SortOrder, Archived: input.Archived})
+		if err != nil { categoryError(c, err, "failed to create category"); return }
 		c.JSON(http.StatusCreated, cat)
 	})
+	// Rename and/or move under another parent ("" for top level); references are rewritten.
+	// Presentation fields and the archive flag are updated in place.
+	type UpdateCategoryInput struct { Name *string `json:"name"`; Parent *string `json:"parent"`; Color *string `json:"color" binding:"omitempty,hexcolor,len=7"`; Icon *string `json:"icon" binding:"omitempty,max=64"`; Type *string `json:"type" binding:"omitempty,oneof=essential discretionary"`; SortOrder *int `json:"sortOrder"`; Archived *bool `json:"archived"` }
+	api.PATCH("/categories/:name", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input UpdateCategoryInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if input.Name != nil && *input.Name == "" { c.JSON(http.StatusBadRequest, gin.H{"error": "name cannot be empty"}); return }
+		attrs := map[string]interface{}{}
+		if input.Color != nil { attrs["color"] = *input.Color }
+		if input.Icon != nil { attrs["icon"] = *input.Icon }
+		if input.Type != nil { attrs["type"] = *input.Type }
+		if input.SortOrder != nil { attrs["sort_order"] = *input.SortOrder }
+		if input.Archived != nil { attrs["archived"] = *input.Archived }
+		if input.Name == nil && input.Parent == nil && len(attrs) == 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"}); return }
+		cat, err := svc.UpdateCategory(userID, c.Param("name"), input.Name, input.Parent, attrs)
+		if err != nil { categoryError(c, err, "failed to update category"); return }
+		c.JSON(http.StatusOK, cat)
+	})
+	type MergeCategoriesInput struct { Sources []string `json:"sources" binding:"required,min=1"`; Target string `json:"target" binding:"required"` }
+	api.POST("/categories/merge", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input MergeCategoriesInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		cat, err := svc.MergeCategories(userID, input.Sources, input.Target)
+		if err != nil { categoryError(c, err, "failed to merge categories"); return }
+		c.JSON(http.StatusOK, cat)
+	})
+	// DELETE /categories/:name?reassignTo=Other moves its entries, plans, rules and children first;
+	// reassignTo is required while anything references the category
 	api.DELETE("/categories/:name", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		name := c.Param("name")
-		rows, err := svc.DeleteCategory(userID, name)
-		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete category"}); return }
+		target := c.Query("reassignTo")
+		if target == name { c.JSON(http.StatusBadRequest, gin.H{"error": "reassignTo must differ from the deleted category"}); return }
+		rows, err := svc.DeleteCategory(userID, name, target)
+		if err == repository.ErrCategoryInUse { c.JSON(http.StatusConflict, gin.H{"error": "category is in use; choose reassignTo"}); return }
+		if err != nil { categoryError(c, err, "failed to delete category"); return }
 		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
 		c.Status(http.StatusNoContent)
 	})
@@ -175,14 +431,14 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list plans"}); return }
 		c.JSON(http.StatusOK, plans)
 	})
-	type UpsertPlanInput struct { MonthKey string `json:"monthKey" binding:"required"`; Category string `json:"category" binding:"required"`; PlannedAmount float64 `json:"plannedAmount" binding:"required"` }
+	type UpsertPlanInput struct { MonthKey string `json:"monthKey" binding:"required"`; Category string `json:"category" binding:"required"`; PlannedAmount models.Money `json:"plannedAmount" binding:"required"`; Currency string `json:"currency" binding:"omitempty,iso4217"` }
 	api.POST("/plans", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		var input UpsertPlanInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
-		plan, updated, err := svc.UpsertPlan(userID, input.MonthKey, input.Category, input.PlannedAmount)
+		plan, updated, err := svc.UpsertPlan(userID, input.MonthKey, input.Category, input.PlannedAmount, input.Currency)
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to upsert plan"}); return }
 		if updated { c.JSON(http.StatusOK, plan) } else { c.JSON(http.StatusCreated, plan) }
 	})
@@ -206,7 +462,9 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list months"}); return }
 		c.JSON(http.StatusOK, months)
 	})
-	type CreateMonthInput struct { MonthKey string `json:"monthKey" binding:"required"` }
+	type CreateMonthInput struct { MonthKey string `json:"monthKey" binding:"required"`; FromTemplate string `json:"fromTemplate"`; CopyFrom string `json:"copyFrom"`; Rollover bool `json:"rollover"` }
+	// Plans are cloned from fromTemplate (ID or name) or copyFrom (YYYY-MM); rollover adds each category's
+	// unspent (or overspent) remainder of copyFrom, or of the previous month
 	api.POST("/months", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
 		claims := claimsAny.(map[string]interface{})
@@ -214,7 +472,13 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		var input CreateMonthInput
 		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
 		if len(input.MonthKey) != 7 || input.MonthKey[4] != '-' { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid month key"}); return }
-		m, err := svc.CreateMonthWithSeeds(userID, input.MonthKey)
+		if input.FromTemplate != "" && input.CopyFrom != "" { c.JSON(http.StatusBadRequest, gin.H{"error": "give fromTemplate or copyFrom, not both"}); return }
+		if input.CopyFrom != "" && (len(input.CopyFrom) != 7 || input.CopyFrom[4] != '-') { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid copyFrom month key"}); return }
+		m, err := svc.CreateMonth(fx.Converter(userID), userID, input.MonthKey, services.MonthSeed{Template: input.FromTemplate, CopyFrom: input.CopyFrom, Rollover: input.Rollover})
+		var missing *services.MissingRatesError
+		if errors.As(err, &missing) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing exchange rate", "missing": missing.Missing}); return }
+		if errors.Is(err, gorm.ErrRecordNotFound) && input.FromTemplate != "" { c.JSON(http.StatusNotFound, gin.H{"error": "template not found"}); return }
+		if errors.Is(err, gorm.ErrRecordNotFound) { c.JSON(http.StatusNotFound, gin.H{"error": "copyFrom month not found"}); return }
 		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create month"}); return }
 		c.JSON(http.StatusCreated, m)
 	})
@@ -223,8 +487,14 @@ func RegisterSpendingRoutes(api *gin.RouterGroup, db *gorm.DB) {
 		claims := claimsAny.(map[string]interface{})
 		userID := claims["sub"].(string)
 		mk := c.Param("month")
+		summary, err := svc.BudgetSummary(fx.Converter(userID), userID, mk)
+		var missing *services.MissingRatesError
+		if errors.As(err, &missing) { c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "missing exchange rate", "missing": missing.Missing}); return }
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build summary"}); return }
+		// The raw rows stay in the default response for existing clients; ?view=summary leaves them out
+		if c.Query("view") == "summary" { c.JSON(http.StatusOK, summary); return }
 		spending, earnings, borrows, plans := svc.MonthSummary(userID, mk)
-		c.JSON(http.StatusOK, gin.H{"monthKey": mk, "spending": spending, "earnings": earnings, "borrows": borrows, "plans": plans})
+		c.JSON(http.StatusOK, gin.H{"monthKey": mk, "currency": summary.Currency, "categories": summary.Categories, "totals": summary.Totals, "spending": spending, "earnings": earnings, "borrows": borrows, "plans": plans})
 	})
 	api.DELETE("/months/:month", func(c *gin.Context) {
 		claimsAny, _ := c.Get("claims")
@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+)
+
+// RegisterTemplateRoutes wires named plan templates that new months can be created from
+func RegisterTemplateRoutes(api *gin.RouterGroup, db *gorm.DB) {
+	svc := services.NewTemplateService(repository.NewTemplateRepository(db), repository.NewSpendingRepository(db))
+
+	templateError := func(c *gin.Context, err error, msg string) {
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		case errors.Is(err, services.ErrInvalidTemplate):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrUnknownCategory):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		case errors.Is(err, repository.ErrTemplateNameTaken):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": msg})
+		}
+	}
+
+	api.GET("/plan-templates", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		items, err := svc.List(userID)
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list plan templates"}); return }
+		c.JSON(http.StatusOK, items)
+	})
+
+	api.GET("/plan-templates/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		t, err := svc.Find(userID, c.Param("id"))
+		if err != nil { templateError(c, err, "failed to fetch plan template"); return }
+		c.JSON(http.StatusOK, t)
+	})
+
+	type TemplateItemInput struct {
+		Category      string       `json:"category" binding:"required"`
+		PlannedAmount models.Money `json:"plannedAmount"`
+		Currency      string       `json:"currency" binding:"omitempty,iso4217"`
+	}
+	toItems := func(in []TemplateItemInput) []models.PlanTemplateItem {
+		if in == nil { return nil }
+		out := make([]models.PlanTemplateItem, 0, len(in))
+		for _, it := range in { out = append(out, models.PlanTemplateItem{Category: it.Category, PlannedAmount: it.PlannedAmount, Currency: it.Currency}) }
+		return out
+	}
+
+	// fromMonth (YYYY-MM) snapshots that month's plans instead of taking items
+	type CreateTemplateInput struct {
+		Name      string              `json:"name" binding:"required"`
+		Items     []TemplateItemInput `json:"items" binding:"dive"`
+		FromMonth string              `json:"fromMonth"`
+	}
+
+	api.POST("/plan-templates", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input CreateTemplateInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if input.FromMonth != "" && len(input.Items) > 0 { c.JSON(http.StatusBadRequest, gin.H{"error": "give items or fromMonth, not both"}); return }
+		t, err := svc.Create(userID, input.Name, toItems(input.Items), input.FromMonth)
+		if errors.Is(err, gorm.ErrRecordNotFound) { c.JSON(http.StatusNotFound, gin.H{"error": "month not found"}); return }
+		if err != nil { templateError(c, err, "failed to create plan template"); return }
+		c.JSON(http.StatusCreated, t)
+	})
+
+	// items, when given, replace the template's items
+	type UpdateTemplateInput struct {
+		Name  *string             `json:"name"`
+		Items []TemplateItemInput `json:"items" binding:"omitempty,dive"`
+	}
+
+	api.PATCH("/plan-templates/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		var input UpdateTemplateInput
+		if err := c.ShouldBindJSON(&input); err != nil { c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"}); return }
+		if input.Name == nil && input.Items == nil { c.JSON(http.StatusBadRequest, gin.H{"error": "nothing to update"}); return }
+		t, err := svc.Update(userID, c.Param("id"), input.Name, toItems(input.Items))
+		if err != nil { templateError(c, err, "failed to update plan template"); return }
+		c.JSON(http.StatusOK, t)
+	})
+
+	api.DELETE("/plan-templates/:id", func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims := claimsAny.(map[string]interface{})
+		userID := claims["sub"].(string)
+		rows, err := svc.Delete(userID, c.Param("id"))
+		if err != nil { c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete plan template"}); return }
+		if rows == 0 { c.JSON(http.StatusNotFound, gin.H{"error": "not found"}); return }
+		c.Status(http.StatusNoContent)
+	})
+}
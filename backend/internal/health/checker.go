@@ -0,0 +1,89 @@
+package health
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Component statuses
+const (
+	StatusOK       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check probes one dependency; it should honor ctx's deadline
+type Check func(ctx context.Context) error
+
+// ComponentReport is the outcome of one check
+type ComponentReport struct {
+	Name    string  `json:"name"`
+	Status  string  `json:"status"`
+	Latency float64 `json:"latencyMs"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// Report is the outcome of a readiness check; Status is ok only when every component is
+type Report struct {
+	Status     string            `json:"status"`
+	Components []ComponentReport `json:"components"`
+	CheckedAt  time.Time         `json:"checkedAt"`
+}
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Checker runs the readiness checks
+type Checker struct {
+	Ready *Readiness
+	// Timeout bounds each check
+	Timeout time.Duration
+	started time.Time
+	checks  []namedCheck
+}
+
+func NewChecker(ready *Readiness, timeout time.Duration) *Checker {
+	return &Checker{Ready: ready, Timeout: timeout, started: time.Now()}
+}
+
+// Add registers a named check; call it before serving
+func (c *Checker) Add(name string, check Check) { c.checks = append(c.checks, namedCheck{name, check}) }
+
+// Uptime is how long ago the checker was created
+func (c *Checker) Uptime() time.Duration { return time.Since(c.started) }
+
+// Run runs every check concurrently. While draining the report fails without probing dependencies.
+func (c *Checker) Run(ctx context.Context) Report {
+	rep := Report{Status: StatusOK, CheckedAt: time.Now()}
+	if c.Ready.Draining() {
+		rep.Status = StatusDraining
+		rep.Components = []ComponentReport{{Name: "shutdown", Status: StatusDraining}}
+		return rep
+	}
+	rep.Components = make([]ComponentReport, len(c.checks))
+	var wg sync.WaitGroup
+	for i, nc := range c.checks {
+		wg.Add(1)
+		go func(i int, nc namedCheck) {
+			defer wg.Done()
+			cctx, cancel := context.WithTimeout(ctx, c.Timeout)
+			defer cancel()
+			start := time.Now()
+			err := nc.check(cctx)
+			cr := ComponentReport{Name: nc.name, Status: StatusOK, Latency: math.Round(float64(time.Since(start).Microseconds())) / 1000}
+			if err != nil {
+				cr.Status, cr.Error = StatusFail, err.Error()
+			}
+			rep.Components[i] = cr
+		}(i, nc)
+	}
+	wg.Wait()
+	for _, cr := range rep.Components {
+		if cr.Status != StatusOK { rep.Status = StatusFail }
+	}
+	return rep
+}
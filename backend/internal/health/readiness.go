@@ -0,0 +1,17 @@
+// Package health reports whether this instance is alive and ready to receive traffic
+package health
+
+import "sync/atomic"
+
+// Readiness holds the lifecycle state readiness depends on: once shutdown starts the instance is
+// draining and never becomes ready again
+type Readiness struct {
+	draining atomic.Bool
+}
+
+func NewReadiness() *Readiness { return &Readiness{} }
+
+// SetDraining marks the instance as shutting down
+func (r *Readiness) SetDraining() { r.draining.Store(true) }
+
+func (r *Readiness) Draining() bool { return r.draining.Load() }
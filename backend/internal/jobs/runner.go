@@ -0,0 +1,217 @@
+// Package jobs runs background work from the DB-backed jobs queue. Jobs are enqueued with
+// repository.EnqueueJob, ideally in the transaction of the write that causes them, and executed by
+// the Runner's workers with retries, exponential backoff and dead-lettering.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// Handler executes one job; returning an error retries it later unless it is Permanent
+type Handler func(ctx context.Context, job *models.Job) error
+
+type permanentError struct{ err error }
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying; the job is dead-lettered at once
+func Permanent(err error) error { return &permanentError{err} }
+
+// Decode unmarshals the job's payload into v; a malformed payload is permanent
+func Decode(job *models.Job, v interface{}) error {
+	if err := json.Unmarshal([]byte(job.Payload), v); err != nil { return Permanent(fmt.Errorf("decode %s payload: %w", job.Kind, err)) }
+	return nil
+}
+
+// Runner polls the queue with a pool of workers
+type Runner struct {
+	repo         *repository.JobRepository
+	handlers     map[string]Handler
+	// Workers is the number of concurrent workers (JOB_WORKERS, default 2)
+	Workers      int
+	// PollInterval is how long an idle worker waits before polling again (JOB_POLL_INTERVAL, default 2s)
+	PollInterval time.Duration
+	// Lease is how long a claimed job is reserved; a job still running after it may be claimed again
+	Lease        time.Duration
+	// Backoff is the wait after the first failure; it doubles per attempt up to MaxBackoff
+	Backoff      time.Duration
+	MaxBackoff   time.Duration
+
+	name    string
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	workers []*workerState
+}
+
+// workerState is what Health inspects: when the worker last polled, whether it is running a job
+// and whether its last poll failed
+type workerState struct {
+	beat    atomic.Int64 // unix nanoseconds
+	busy    atomic.Bool
+	lastErr atomic.Pointer[string]
+}
+
+func NewRunner(repo *repository.JobRepository) *Runner {
+	host, _ := os.Hostname()
+	return &Runner{
+		repo:         repo,
+		handlers:     map[string]Handler{},
+		Workers:      intFromEnv("JOB_WORKERS", 2),
+		PollInterval: durationFromEnv("JOB_POLL_INTERVAL", 2*time.Second),
+		Lease:        10 * time.Minute,
+		Backoff:      10 * time.Second,
+		MaxBackoff:   time.Hour,
+		name:         fmt.Sprintf("%s-%d", host, os.Getpid()),
+	}
+}
+
+// Handle registers the handler for a job kind; call it before Start
+func (r *Runner) Handle(kind string, h Handler) { r.handlers[kind] = h }
+
+// Start launches the workers; they run until Stop
+func (r *Runner) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.workers = make([]*workerState, r.Workers)
+	for i := range r.workers {
+		st := &workerState{}
+		st.beat.Store(time.Now().UnixNano())
+		r.workers[i] = st
+		r.wg.Add(1)
+		go r.work(ctx, fmt.Sprintf("%s-w%d", r.name, i), st)
+	}
+}
+
+// Health returns nil while every worker is running a job or has polled the queue recently without error
+func (r *Runner) Health() error {
+	if r.cancel == nil { return errors.New("job runner not started") }
+	stale := 2*r.PollInterval + 10*time.Second
+	now := time.Now()
+	for i, st := range r.workers {
+		if st.busy.Load() { continue }
+		if since := now.Sub(time.Unix(0, st.beat.Load())); since > stale {
+			return fmt.Errorf("worker %d has not polled for %s", i, since.Round(time.Second))
+		}
+		if msg := st.lastErr.Load(); msg != nil { return fmt.Errorf("worker %d: %s", i, *msg) }
+	}
+	return nil
+}
+
+// Stop stops claiming new jobs and waits for running ones to finish, or until ctx is done; jobs cut
+// off by ctx are claimed again once their lease expires
+func (r *Runner) Stop(ctx context.Context) error {
+	if r.cancel == nil { return nil }
+	r.cancel()
+	done := make(chan struct{})
+	go func() { r.wg.Wait(); close(done) }()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work(ctx context.Context, worker string, st *workerState) {
+	defer r.wg.Done()
+	for {
+		st.busy.Store(true)
+		ran, err := r.RunOne(worker)
+		st.busy.Store(false)
+		st.beat.Store(time.Now().UnixNano())
+		if err != nil {
+			msg := err.Error()
+			st.lastErr.Store(&msg)
+			log.Printf("jobs: %s: %v", worker, err)
+		} else {
+			st.lastErr.Store(nil)
+		}
+		if ran && err == nil {
+			// Keep draining while there is work, unless stopping
+			if ctx.Err() != nil { return }
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.PollInterval):
+		}
+	}
+}
+
+// RunOne claims and executes one due job; it reports whether there was one. The outcome is only
+// recorded while worker still holds the job, so a job that outran its lease and was claimed again
+// is left to the worker that holds it now.
+func (r *Runner) RunOne(worker string) (bool, error) {
+	job, err := r.repo.Claim(worker, time.Now(), r.Lease)
+	if err != nil || job == nil { return false, err }
+	err = r.execute(job)
+	now := time.Now()
+	var saveErr error
+	switch {
+	case err == nil:
+		saveErr = r.repo.Complete(job.ID, worker, now)
+	case isPermanent(err) || job.Attempts >= job.MaxAttempts:
+		saveErr = r.repo.Bury(job.ID, worker, now, err.Error())
+		if saveErr == nil { log.Printf("jobs: %s %s dead-lettered after %d attempt(s): %v", job.Kind, job.ID, job.Attempts, err) }
+	default:
+		saveErr = r.repo.Retry(job.ID, worker, now.Add(r.backoff(job.Attempts)), err.Error())
+	}
+	if errors.Is(saveErr, repository.ErrLeaseLost) {
+		log.Printf("jobs: %s %s outran its lease; its outcome (%v) was dropped", job.Kind, job.ID, err)
+		return true, nil
+	}
+	return true, saveErr
+}
+
+// execute runs the job's handler with a deadline of its lease; a panic fails the attempt
+func (r *Runner) execute(job *models.Job) (err error) {
+	h := r.handlers[job.Kind]
+	if h == nil { return Permanent(fmt.Errorf("no handler for job kind %q", job.Kind)) }
+	defer func() {
+		if p := recover(); p != nil { err = fmt.Errorf("panic: %v", p) }
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), r.Lease)
+	defer cancel()
+	return h(ctx, job)
+}
+
+// backoff returns the wait before the attempt after the given number of failed ones
+func (r *Runner) backoff(attempts int) time.Duration {
+	wait := r.Backoff
+	for i := 1; i < attempts && wait < r.MaxBackoff; i++ { wait *= 2 }
+	if wait > r.MaxBackoff { wait = r.MaxBackoff }
+	return wait
+}
+
+func isPermanent(err error) bool {
+	var p *permanentError
+	return errors.As(err, &p)
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+func intFromEnv(key string, fallback int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil && n > 0 {
+		return n
+	}
+	return fallback
+}
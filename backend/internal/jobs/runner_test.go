@@ -0,0 +1,139 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+// newRunner returns a runner over an empty queue whose "test" jobs run h
+func newRunner(t *testing.T, h Handler) (*Runner, *repository.JobRepository, *gorm.DB) {
+	db := testdb.Open(t)
+	repo := repository.NewJobRepository(db)
+	r := NewRunner(repo)
+	r.Handle("test", h)
+	return r, repo, db
+}
+
+func enqueue(t *testing.T, repo *repository.JobRepository) string {
+	t.Helper()
+	job, err := repo.Enqueue("test", map[string]string{}, time.Now().Add(-time.Second))
+	if err != nil { t.Fatal(err) }
+	return job.ID
+}
+
+func reload(t *testing.T, db *gorm.DB, id string) models.Job {
+	t.Helper()
+	var job models.Job
+	if err := db.First(&job, "id = ?", id).Error; err != nil { t.Fatal(err) }
+	return job
+}
+
+func runOne(t *testing.T, r *Runner) {
+	t.Helper()
+	ran, err := r.RunOne("w1")
+	if err != nil || !ran { t.Fatalf("RunOne = %v, %v", ran, err) }
+}
+
+// makeDue moves a retried job's run_at into the past so the next RunOne claims it
+func makeDue(t *testing.T, db *gorm.DB, id string) {
+	t.Helper()
+	if err := db.Model(&models.Job{}).Where("id = ?", id).Update("run_at", time.Now().Add(-time.Second)).Error; err != nil { t.Fatal(err) }
+}
+
+func TestRunOneCompletes(t *testing.T) {
+	r, repo, db := newRunner(t, func(context.Context, *models.Job) error { return nil })
+	id := enqueue(t, repo)
+	runOne(t, r)
+	job := reload(t, db, id)
+	if job.Status != models.JobDone || job.Attempts != 1 || job.FinishedAt == nil || job.LockedBy != "" { t.Errorf("job = %+v, want done after one attempt", job) }
+	if ran, err := r.RunOne("w1"); ran || err != nil { t.Errorf("empty queue: RunOne = %v, %v", ran, err) }
+}
+
+func TestRunOneRetriesWithBackoff(t *testing.T) {
+	r, repo, db := newRunner(t, func(context.Context, *models.Job) error { return errors.New("upstream down") })
+	id := enqueue(t, repo)
+	for attempt, wait := range []time.Duration{r.Backoff, 2 * r.Backoff, 4 * r.Backoff} {
+		before := time.Now()
+		runOne(t, r)
+		job := reload(t, db, id)
+		if job.Status != models.JobQueued || job.Attempts != attempt+1 || job.LastError != "upstream down" || job.LockedBy != "" {
+			t.Fatalf("attempt %d: job = %+v, want queued for a retry", attempt+1, job)
+		}
+		if job.RunAt.Before(before.Add(wait)) || job.RunAt.After(time.Now().Add(wait)) {
+			t.Errorf("attempt %d: retry in %s, want %s", attempt+1, job.RunAt.Sub(before).Round(time.Second), wait)
+		}
+		makeDue(t, db, id)
+	}
+	r.MaxBackoff = 15 * time.Second
+	if got := r.backoff(3); got != r.MaxBackoff { t.Errorf("backoff(3) = %s, want the %s cap", got, r.MaxBackoff) }
+}
+
+func TestRunOneDeadLettersAtMaxAttempts(t *testing.T) {
+	r, repo, db := newRunner(t, func(context.Context, *models.Job) error { return errors.New("upstream down") })
+	id := enqueue(t, repo)
+	if err := db.Model(&models.Job{}).Where("id = ?", id).Update("max_attempts", 2).Error; err != nil { t.Fatal(err) }
+	runOne(t, r)
+	makeDue(t, db, id)
+	runOne(t, r)
+	job := reload(t, db, id)
+	if job.Status != models.JobDead || job.Attempts != 2 || job.FinishedAt == nil { t.Errorf("job = %+v, want dead after 2 attempts", job) }
+	if ran, _ := r.RunOne("w1"); ran { t.Error("a dead job was claimed again") }
+}
+
+func TestRunOneBuriesPermanentErrors(t *testing.T) {
+	r, repo, db := newRunner(t, func(ctx context.Context, job *models.Job) error {
+		var payload []string
+		return Decode(job, &payload)
+	})
+	id := enqueue(t, repo)
+	runOne(t, r)
+	job := reload(t, db, id)
+	if job.Status != models.JobDead || job.Attempts != 1 || !strings.Contains(job.LastError, "decode test payload") { t.Errorf("job = %+v, want dead after one attempt", job) }
+
+	other, err := repo.Enqueue("unknown", nil, time.Now().Add(-time.Second))
+	if err != nil { t.Fatal(err) }
+	runOne(t, r)
+	if job := reload(t, db, other.ID); job.Status != models.JobDead { t.Errorf("job of an unknown kind is %s, want dead", job.Status) }
+}
+
+func TestRunOneRecoversPanics(t *testing.T) {
+	r, repo, db := newRunner(t, func(context.Context, *models.Job) error { panic("boom") })
+	id := enqueue(t, repo)
+	runOne(t, r)
+	job := reload(t, db, id)
+	if job.Status != models.JobQueued || job.LastError != "panic: boom" { t.Errorf("job = %+v, want queued for a retry after the panic", job) }
+}
+
+func TestRunOneAfterLeaseExpiry(t *testing.T) {
+	var repo *repository.JobRepository
+	r, repo, db := newRunner(t, func(ctx context.Context, job *models.Job) error {
+		// The handler outlives its lease and another worker claims the job again
+		again, err := repo.Claim("w2", time.Now().Add(2*time.Minute), time.Minute)
+		if err != nil || again == nil || again.ID != job.ID { t.Errorf("reclaim = %+v, %v", again, err) }
+		return nil
+	})
+	r.Lease = time.Minute
+	id := enqueue(t, repo)
+	if ran, err := r.RunOne("w1"); !ran || err != nil { t.Fatalf("RunOne = %v, %v; want the lost lease to be dropped", ran, err) }
+	job := reload(t, db, id)
+	if job.Status != models.JobRunning || job.LockedBy != "w2" || job.Attempts != 2 { t.Errorf("job = %+v, want still running for w2", job) }
+
+	for name, save := range map[string]func() error{
+		"complete": func() error { return repo.Complete(id, "w1", time.Now()) },
+		"retry":    func() error { return repo.Retry(id, "w1", time.Now(), "late") },
+		"bury":     func() error { return repo.Bury(id, "w1", time.Now(), "late") },
+	} {
+		if err := save(); !errors.Is(err, repository.ErrLeaseLost) { t.Errorf("%s by the old worker: err = %v, want ErrLeaseLost", name, err) }
+	}
+	if err := repo.Complete(id, "w2", time.Now()); err != nil { t.Fatalf("complete by the new worker: %v", err) }
+	if err := repo.Complete(id, "w2", time.Now()); !errors.Is(err, repository.ErrLeaseLost) { t.Errorf("completing a finished job: err = %v, want ErrLeaseLost", err) }
+}
@@ -7,6 +7,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
 )
 
 func jwtSecretFunc(t *jwt.Token) (interface{}, error) {
@@ -21,8 +24,9 @@ func jwtSecret() string {
 	return secret
 }
 
-// AuthRequired validates Bearer token and injects claims into context
-func AuthRequired() gin.HandlerFunc {
+// AuthRequired validates Bearer token, rejects revoked sessions and injects claims into context
+func AuthRequired(db *gorm.DB) gin.HandlerFunc {
+	sessions := repository.NewSessionRepository(db)
 	return func(c *gin.Context) {
 		auth := c.GetHeader("Authorization")
 		if auth == "" || !strings.HasPrefix(auth, "Bearer ") {
@@ -40,8 +44,41 @@ func AuthRequired() gin.HandlerFunc {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
+		// Tokens must belong to a live session; legacy tokens without jti are refused
+		jti, _ := claims["jti"].(string)
+		if jti == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+		active, err := sessions.IsActive(jti)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "failed to verify session"})
+			return
+		}
+		if !active {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "session revoked"})
+			return
+		}
 		// Normalize to plain map for handler assertions
 		c.Set("claims", map[string]interface{}(claims))
 		c.Next()
 	}
-}
\ No newline at end of file
+}
+
+// AdminRequired allows only users whose email is listed in ADMIN_EMAILS (comma-separated).
+// It must run after AuthRequired.
+func AdminRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claimsAny, _ := c.Get("claims")
+		claims, _ := claimsAny.(map[string]interface{})
+		email, _ := claims["email"].(string)
+		email = strings.ToLower(strings.TrimSpace(email))
+		for _, admin := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+			if email != "" && strings.ToLower(strings.TrimSpace(admin)) == email {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "admin only"})
+	}
+}
@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/services"
+	"achieving-backend/internal/testdb"
+)
+
+func TestAuthRequiredRejectsRevokedSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	sessions := services.NewSessionService(repository.NewSessionRepository(db))
+	pair, err := sessions.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	legacy, _, err := services.GenerateToken("u1", "U", "u1@example.com", "")
+	if err != nil { t.Fatal(err) }
+
+	r := gin.New()
+	r.GET("/me", AuthRequired(db), func(c *gin.Context) {
+		claims, _ := c.Get("claims")
+		c.String(http.StatusOK, claims.(map[string]interface{})["sub"].(string))
+	})
+	get := func(auth string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		if auth != "" { req.Header.Set("Authorization", auth) }
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := get("Bearer " + pair.Token); w.Code != http.StatusOK || w.Body.String() != "u1" { t.Fatalf("active session: %d %s", w.Code, w.Body) }
+	if w := get(""); w.Code != http.StatusUnauthorized { t.Errorf("no token: %d", w.Code) }
+	if w := get("Bearer " + legacy); w.Code != http.StatusUnauthorized { t.Errorf("token without jti: %d", w.Code) }
+	if _, err := sessions.Revoke("u1", pair.Claims["jti"].(string)); err != nil { t.Fatal(err) }
+	if w := get("Bearer " + pair.Token); w.Code != http.StatusUnauthorized { t.Errorf("revoked session: %d %s", w.Code, w.Body) }
+}
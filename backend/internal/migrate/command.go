@@ -0,0 +1,111 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const usage = `usage: migrate <command>
+  up            apply every pending migration
+  down [n] [--allow-baseline-down]
+                revert the latest n applied migrations (default 1); reverting the
+                baseline, which drops every table, needs --allow-baseline-down
+  status        list migrations and whether they are applied
+  schema [file] write the schema generated from the migrations (default stdout)`
+
+// Command runs the migrate subcommand given its arguments and returns the process exit code;
+// connect is only called by the subcommands that use the database
+func (m *Migrator) Command(args []string, connect func() (*sql.DB, error)) int {
+	if len(args) == 0 { fmt.Fprintln(os.Stderr, usage); return 2 }
+	if args[0] == "schema" {
+		var buf bytes.Buffer
+		m.WriteSchema(&buf)
+		var err error
+		if len(args) > 1 { err = os.WriteFile(args[1], buf.Bytes(), 0o644) } else { _, err = os.Stdout.Write(buf.Bytes()) }
+		if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+		return 0
+	}
+
+	ctx := context.Background()
+	var run func(db *sql.DB) error
+	switch args[0] {
+	case "up":
+		run = func(db *sql.DB) error {
+			ran, err := m.Up(ctx, db)
+			if err == nil && len(ran) == 0 { fmt.Println("already up to date") }
+			return err
+		}
+	case "down":
+		steps := 1
+		for _, arg := range args[1:] {
+			if arg == "--allow-baseline-down" { m.AllowBaselineDown = true; continue }
+			n, err := strconv.Atoi(arg)
+			if err != nil || n < 1 { fmt.Fprintln(os.Stderr, "down: n must be a positive number"); return 2 }
+			steps = n
+		}
+		run = func(db *sql.DB) error {
+			ran, err := m.Down(ctx, db, steps)
+			if err == nil && len(ran) == 0 { fmt.Println("nothing to revert") }
+			return err
+		}
+	case "status":
+		run = func(db *sql.DB) error {
+			states, err := m.Status(ctx, db)
+			if err != nil { return err }
+			return printStatus(os.Stdout, states)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return 2
+	}
+	db, err := connect()
+	if err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+	defer db.Close()
+	if err := run(db); err != nil { fmt.Fprintln(os.Stderr, err); return 1 }
+	return 0
+}
+
+func printStatus(w io.Writer, states []Status) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range states {
+		status, at := "pending", ""
+		switch {
+		case s.Unknown:
+			status = "applied, no file"
+		case s.Modified:
+			status = "applied, file modified"
+		case s.Applied:
+			status = "applied"
+		}
+		if s.AppliedAt != nil { at = s.AppliedAt.Format("2006-01-02 15:04:05") }
+		fmt.Fprintf(tw, "%04d\t%s\t%s\t%s\n", s.Version, s.Name, status, at)
+	}
+	return tw.Flush()
+}
+
+// WriteSchema writes every up migration in order followed by the rows recording them as applied,
+// so that a database loaded from the output is at the latest version
+func (m *Migrator) WriteSchema(w io.Writer) {
+	fmt.Fprintln(w, "-- Achieving App canonical MySQL schema")
+	fmt.Fprintln(w, "-- Code generated by `go generate ./db/migrations` (migrate schema) from db/migrations; DO NOT EDIT.")
+	fmt.Fprintln(w, "-- Engine: InnoDB, Charset: utf8mb4")
+	for _, mig := range m.Migrations {
+		fmt.Fprintf(w, "\n-- Migration %s\n\n%s\n", mig, strings.TrimSpace(mig.Up))
+	}
+	if len(m.Migrations) == 0 { return }
+	fmt.Fprintf(w, "\n-- Applied migrations\n\n%s;\n\n", createTable)
+	fmt.Fprintf(w, "INSERT IGNORE INTO `%s` (`version`, `name`, `checksum`, `applied_at`) VALUES\n", Table)
+	for i, mig := range m.Migrations {
+		sep := ","
+		if i == len(m.Migrations)-1 { sep = ";" }
+		fmt.Fprintf(w, "  (%d, '%s', '%s', NOW(3))%s\n", mig.Version, mig.Name, mig.Checksum, sep)
+	}
+}
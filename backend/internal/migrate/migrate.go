@@ -0,0 +1,285 @@
+// Package migrate applies the numbered SQL migrations of an fs.FS to MySQL. Applied versions are
+// recorded in schema_migrations with the checksum of their up file, and a MySQL advisory lock keeps
+// concurrently starting instances from migrating at the same time.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Table records the applied migrations
+const Table = "schema_migrations"
+
+const createTable = "CREATE TABLE IF NOT EXISTS `" + Table + "` (\n" +
+	"  `version` BIGINT NOT NULL,\n" +
+	"  `name` VARCHAR(255) NOT NULL,\n" +
+	"  `checksum` CHAR(64) NOT NULL,\n" +
+	"  `applied_at` DATETIME(3) NOT NULL,\n" +
+	"  PRIMARY KEY (`version`)\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4"
+
+var fileName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// ErrLocked is returned when another instance held the migration lock for the whole LockTimeout
+var ErrLocked = errors.New("migrate: timed out waiting for the migration lock")
+
+// BaselineVersion is the migration that creates the schema; reverting it drops every table
+const BaselineVersion = 1
+
+// ErrBaselineDown is returned when Down would revert the baseline without AllowBaselineDown
+var ErrBaselineDown = errors.New("migrate: refusing to revert the baseline migration, which drops every table")
+
+// Migration is one numbered schema change; Down is empty when it cannot be reverted
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string // hex SHA-256 of Up
+}
+
+func (m Migration) String() string { return fmt.Sprintf("%04d_%s", m.Version, m.Name) }
+
+// Status is the state of one migration: known from the files, applied to the database, or both
+type Status struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	// Modified means the up file changed after the migration was applied
+	Modified  bool       `json:"modified,omitempty"`
+	// Unknown means the version is applied but has no file, e.g. it was applied by a newer release
+	Unknown   bool       `json:"unknown,omitempty"`
+}
+
+type applied struct {
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Migrator applies the migrations it was loaded with
+type Migrator struct {
+	Migrations  []Migration
+	LockName    string
+	// LockTimeout is how long to wait for another instance's migration to finish (default 60s)
+	LockTimeout time.Duration
+	// Legacy aligns a database created before versioned migrations, i.e. one with tables but no
+	// applied version, before the pending migrations run over it; it may be nil
+	Legacy      func(ctx context.Context) error
+	// AllowBaselineDown lets Down revert BaselineVersion
+	AllowBaselineDown bool
+
+	// upToDate is set once Up succeeded, or Ready found nothing pending, and cleared by Down
+	upToDate atomic.Bool
+}
+
+// New loads and validates the migrations in the root of fsys
+func New(fsys fs.FS) (*Migrator, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil { return nil, err }
+	byVersion := map[int64]*Migration{}
+	for _, e := range entries {
+		match := fileName.FindStringSubmatch(e.Name())
+		if e.IsDir() || match == nil { continue }
+		version, _ := strconv.ParseInt(match[1], 10, 64)
+		body, err := fs.ReadFile(fsys, e.Name())
+		if err != nil { return nil, err }
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] { return nil, fmt.Errorf("migrate: version %d has two names: %s and %s", version, m.Name, match[2]) }
+		if match[3] == "up" {
+			sum := sha256.Sum256(body)
+			m.Up, m.Checksum = string(body), hex.EncodeToString(sum[:])
+		} else {
+			m.Down = string(body)
+		}
+	}
+	out := &Migrator{LockName: "achieving_schema_migrations", LockTimeout: 60 * time.Second}
+	for _, m := range byVersion {
+		if m.Checksum == "" { return nil, fmt.Errorf("migrate: %s has no up file", m) }
+		out.Migrations = append(out.Migrations, *m)
+	}
+	sort.Slice(out.Migrations, func(i, j int) bool { return out.Migrations[i].Version < out.Migrations[j].Version })
+	return out, nil
+}
+
+// Status lists every migration with its state, oldest first
+func (m *Migrator) Status(ctx context.Context, db *sql.DB) ([]Status, error) {
+	conn, err := db.Conn(ctx)
+	if err != nil { return nil, err }
+	defer conn.Close()
+	done, err := loadApplied(ctx, conn)
+	if err != nil { return nil, err }
+	var out []Status
+	for _, mig := range m.Migrations {
+		s := Status{Version: mig.Version, Name: mig.Name}
+		if a, ok := done[mig.Version]; ok {
+			at := a.appliedAt
+			s.Applied, s.AppliedAt, s.Modified = true, &at, a.checksum != mig.Checksum
+			delete(done, mig.Version)
+		}
+		out = append(out, s)
+	}
+	for v, a := range done {
+		at := a.appliedAt
+		out = append(out, Status{Version: v, Name: a.name, Applied: true, AppliedAt: &at, Unknown: true})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+// Pending counts the migrations not applied yet
+func (m *Migrator) Pending(ctx context.Context, db *sql.DB) (int, error) {
+	states, err := m.Status(ctx, db)
+	if err != nil { return 0, err }
+	n := 0
+	for _, s := range states {
+		if !s.Applied { n++ }
+	}
+	return n, nil
+}
+
+// Ready returns nil once every migration is known to be applied: after Up succeeded in this process,
+// or after a check found none pending. Until then it checks the database on each call; afterwards
+// it does not query it at all, which keeps readiness probes cheap.
+func (m *Migrator) Ready(ctx context.Context, db *sql.DB) error {
+	if m.upToDate.Load() { return nil }
+	n, err := m.Pending(ctx, db)
+	if err != nil { return err }
+	if n > 0 { return fmt.Errorf("%d migration(s) pending", n) }
+	m.upToDate.Store(true)
+	return nil
+}
+
+// Up applies every pending migration in version order and returns those it applied. It refuses to
+// run when an applied migration's up file was modified.
+func (m *Migrator) Up(ctx context.Context, db *sql.DB) ([]Migration, error) {
+	var ran []Migration
+	err := m.locked(ctx, db, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil { return err }
+		for _, mig := range m.Migrations {
+			if a, ok := done[mig.Version]; ok && a.checksum != mig.Checksum {
+				return fmt.Errorf("migrate: %s was modified after it was applied (checksum %s, file %s)", mig, a.checksum, mig.Checksum)
+			}
+		}
+		if len(done) == 0 && m.Legacy != nil {
+			legacy, err := hasTables(ctx, conn)
+			if err != nil { return err }
+			if legacy {
+				log.Printf("migrate: aligning a database created before versioned migrations")
+				if err := m.Legacy(ctx); err != nil { return fmt.Errorf("migrate: legacy alignment: %w", err) }
+			}
+		}
+		for _, mig := range m.Migrations {
+			if _, ok := done[mig.Version]; ok { continue }
+			start := time.Now()
+			if err := execScript(ctx, conn, mig.Up); err != nil { return fmt.Errorf("migrate: %s: %w", mig, err) }
+			_, err := conn.ExecContext(ctx, "INSERT INTO `"+Table+"` (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)", mig.Version, mig.Name, mig.Checksum, time.Now())
+			if err != nil { return fmt.Errorf("migrate: recording %s: %w", mig, err) }
+			log.Printf("migrate: applied %s in %s", mig, time.Since(start).Round(time.Millisecond))
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	if err == nil { m.upToDate.Store(true) }
+	return ran, err
+}
+
+// Down reverts the latest steps applied migrations, newest first, and returns those it reverted
+func (m *Migrator) Down(ctx context.Context, db *sql.DB, steps int) ([]Migration, error) {
+	byVersion := map[int64]Migration{}
+	for _, mig := range m.Migrations { byVersion[mig.Version] = mig }
+	var ran []Migration
+	m.upToDate.Store(false)
+	err := m.locked(ctx, db, func(conn *sql.Conn) error {
+		done, err := loadApplied(ctx, conn)
+		if err != nil { return err }
+		versions := make([]int64, 0, len(done))
+		for v := range done { versions = append(versions, v) }
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if steps < len(versions) { versions = versions[:steps] }
+		for _, v := range versions {
+			if v == BaselineVersion && !m.AllowBaselineDown { return ErrBaselineDown }
+		}
+		for _, v := range versions {
+			mig, ok := byVersion[v]
+			if !ok { return fmt.Errorf("migrate: version %d (%s) is applied but has no file", v, done[v].name) }
+			if mig.Down == "" { return fmt.Errorf("migrate: %s has no down file", mig) }
+			if err := execScript(ctx, conn, mig.Down); err != nil { return fmt.Errorf("migrate: reverting %s: %w", mig, err) }
+			if _, err := conn.ExecContext(ctx, "DELETE FROM `"+Table+"` WHERE version = ?", v); err != nil { return fmt.Errorf("migrate: unrecording %s: %w", mig, err) }
+			log.Printf("migrate: reverted %s", mig)
+			ran = append(ran, mig)
+		}
+		return nil
+	})
+	return ran, err
+}
+
+// locked runs fn on a dedicated connection holding the migration lock. GET_LOCK belongs to the
+// session, so everything runs on that one connection, which is discarded afterwards rather than
+// returned to the pool with whatever session settings the migrations changed.
+func (m *Migrator) locked(ctx context.Context, db *sql.DB, fn func(conn *sql.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil { return err }
+	defer conn.Close()
+	defer conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+
+	var got sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", m.LockName, int(m.LockTimeout.Seconds())).Scan(&got); err != nil { return err }
+	if got.Int64 != 1 { return ErrLocked }
+	defer conn.ExecContext(context.Background(), "DO RELEASE_LOCK(?)", m.LockName)
+
+	if _, err := conn.ExecContext(ctx, createTable); err != nil { return err }
+	return fn(conn)
+}
+
+func loadApplied(ctx context.Context, conn *sql.Conn) (map[int64]applied, error) {
+	out := map[int64]applied{}
+	var exists int
+	err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", Table).Scan(&exists)
+	if err != nil || exists == 0 { return out, err }
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, applied_at FROM `"+Table+"`")
+	if err != nil { return nil, err }
+	defer rows.Close()
+	for rows.Next() {
+		var v int64
+		var a applied
+		if err := rows.Scan(&v, &a.name, &a.checksum, &a.appliedAt); err != nil { return nil, err }
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+// hasTables reports whether the database has any table besides schema_migrations
+func hasTables(ctx context.Context, conn *sql.Conn) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx, "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME <> ?", Table).Scan(&n)
+	return n > 0, err
+}
+
+// execScript runs the statements of a migration file one by one. MySQL commits DDL implicitly, so
+// a failing file can leave its earlier statements applied; the error says which statement failed.
+func execScript(ctx context.Context, conn *sql.Conn, script string) error {
+	for i, stmt := range SplitStatements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil { return fmt.Errorf("statement %d: %w", i+1, err) }
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   []string
+	}{
+		{"plain", "CREATE TABLE a (id INT);\nCREATE TABLE b (id INT);\n", []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"}},
+		{"no final semicolon", "SELECT 1;SELECT 2", []string{"SELECT 1", "SELECT 2"}},
+		{"empty statements", ";;\n  ;SELECT 1;;", []string{"SELECT 1"}},
+		{"semicolon in string", "INSERT INTO t VALUES ('a;b', \"c;d\");", []string{"INSERT INTO t VALUES ('a;b', \"c;d\")"}},
+		{"escaped and doubled quotes", `INSERT INTO t VALUES ('it\'s;', 'it''s;');`, []string{`INSERT INTO t VALUES ('it\'s;', 'it''s;')`}},
+		{"backtick identifier", "ALTER TABLE `a;b` ADD `c\\` INT;", []string{"ALTER TABLE `a;b` ADD `c\\` INT"}},
+		{"line comments", "-- header; with semicolon\nSELECT 1; # trailing; comment\n--\nSELECT 2;", []string{"SELECT 1", "SELECT 2"}},
+		{"double dash without space", "SELECT 1--2;", []string{"SELECT 1--2"}},
+		{"block comments", "/* a; b */SELECT /*!40101 x; */1;\n/* only a comment; */;", []string{"SELECT 1"}},
+		{"unterminated string", "SELECT 'a;b", []string{"SELECT 'a;b"}},
+		{"unterminated comment", "SELECT 1; /* open", []string{"SELECT 1"}},
+		{"comments only", "-- nothing\n/* here */\n", nil},
+	}
+	for _, tt := range tests {
+		if got := SplitStatements(tt.script); !reflect.DeepEqual(got, tt.want) { t.Errorf("%s: got %q, want %q", tt.name, got, tt.want) }
+	}
+}
+
+func TestNew(t *testing.T) {
+	up1, up2 := "CREATE TABLE a (id INT);\n", "ALTER TABLE a ADD b INT;\n"
+	m, err := New(fstest.MapFS{
+		"0002_add_b.up.sql":      {Data: []byte(up2)},
+		"0001_baseline.up.sql":   {Data: []byte(up1)},
+		"0001_baseline.down.sql": {Data: []byte("DROP TABLE a;\n")},
+		"migrations.go":          {Data: []byte("package migrations\n")},
+		"README.md":              {Data: []byte("notes")},
+	})
+	if err != nil { t.Fatal(err) }
+	if len(m.Migrations) != 2 { t.Fatalf("migrations = %v", m.Migrations) }
+	sum := func(s string) string { h := sha256.Sum256([]byte(s)); return hex.EncodeToString(h[:]) }
+	want := []Migration{
+		{Version: 1, Name: "baseline", Up: up1, Down: "DROP TABLE a;\n", Checksum: sum(up1)},
+		{Version: 2, Name: "add_b", Up: up2, Checksum: sum(up2)},
+	}
+	if !reflect.DeepEqual(m.Migrations, want) { t.Errorf("migrations = %+v, want %+v", m.Migrations, want) }
+	if got := m.Migrations[0].String(); got != "0001_baseline" { t.Errorf("String = %s", got) }
+
+	// The checksum covers the up file exactly, so any edit shows up as a modified migration
+	edited, err := New(fstest.MapFS{"0001_baseline.up.sql": {Data: []byte(up1 + " ")}})
+	if err != nil { t.Fatal(err) }
+	if edited.Migrations[0].Checksum == want[0].Checksum { t.Error("checksum ignores an edit of the up file") }
+
+	for name, files := range map[string]fstest.MapFS{
+		"has no up file": {"0001_baseline.down.sql": {Data: []byte("DROP TABLE a;")}},
+		"has two names":  {"0001_baseline.up.sql": {Data: []byte(up1)}, "0001_other.down.sql": {Data: []byte("")}},
+	} {
+		if _, err := New(files); err == nil || !strings.Contains(err.Error(), name) { t.Errorf("err = %v, want %q", err, name) }
+	}
+}
+
+func TestReadyKeepsTheMigratedFlag(t *testing.T) {
+	m, err := New(fstest.MapFS{})
+	if err != nil { t.Fatal(err) }
+	m.upToDate.Store(true)
+	// Once the flag is set the database is not queried, so a nil one is fine
+	if err := m.Ready(context.Background(), nil); err != nil { t.Errorf("Ready = %v", err) }
+}
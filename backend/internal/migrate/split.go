@@ -0,0 +1,51 @@
+package migrate
+
+import "strings"
+
+// SplitStatements splits a SQL script on the semicolons that end statements, skipping those inside
+// quotes, backticks and comments. Comments (also /*! */ version comments) are removed and empty
+// statements dropped. DELIMITER is not supported, so migrations cannot define stored programs.
+func SplitStatements(script string) []string {
+	var out []string
+	var cur strings.Builder
+	hasCode := false
+	flush := func() {
+		if hasCode { out = append(out, strings.TrimSpace(cur.String())) }
+		cur.Reset()
+		hasCode = false
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			// Quoted string or identifier; backslash escapes only apply to strings
+			j := i + 1
+			for ; j < len(script); j++ {
+				if script[j] == '\\' && c != '`' { j++; continue }
+				if script[j] == c {
+					if j+1 < len(script) && script[j+1] == c { j++; continue } // doubled quote
+					break
+				}
+			}
+			if j >= len(script) { j = len(script) - 1 }
+			cur.WriteString(script[i : j+1])
+			hasCode = true
+			i = j
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "-- ")) || (c == '-' && strings.HasPrefix(script[i:], "--\n")):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 { end = len(script) - i }
+			i += end - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 { end = len(script) - i - 4 }
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			cur.WriteByte(c)
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' { hasCode = true }
+		}
+	}
+	flush()
+	return out
+}
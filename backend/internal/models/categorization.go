@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CategorizationRule assigns Category to spending entries posted without one. Every criterion that is
+// set must match: NoteContains and Merchant are case-insensitive substrings of the entry's note and
+// merchant, the amount must lie within [MinAmount, MaxAmount] and, when Currency is set, the entry
+// must be in that currency. Rules are tried in ascending Priority; the first match wins.
+type CategorizationRule struct {
+	ID           string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID       string    `gorm:"index:idx_categorization_user_priority;size:36" json:"userId"`
+	User         User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Priority     int       `gorm:"index:idx_categorization_user_priority;not null;default:0" json:"priority"`
+	Category     string    `gorm:"size:64;not null" json:"category"`
+	NoteContains string    `gorm:"size:255;not null;default:''" json:"noteContains"`
+	Merchant     string    `gorm:"size:255;not null;default:''" json:"merchant"`
+	MinAmount    *Money    `json:"minAmount"`
+	MaxAmount    *Money    `json:"maxAmount"`
+	Currency     string    `gorm:"size:3;not null;default:''" json:"currency"`
+	Active       bool      `gorm:"not null;default:true" json:"active"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateCategorization ensures the categorization rule table and its FK exist
+func MigrateCategorization(db *gorm.DB) {
+	_ = db.AutoMigrate(&CategorizationRule{})
+	if !db.Migrator().HasConstraint(&CategorizationRule{}, "User") {
+		_ = db.Migrator().CreateConstraint(&CategorizationRule{}, "User")
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// EnvelopeTransfer moves budget from one category's envelope to another's within a month
+type EnvelopeTransfer struct {
+	ID           string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID       string    `gorm:"index:idx_envelope_transfer_user_month;size:36" json:"userId"`
+	User         User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	MonthKey     string    `gorm:"index:idx_envelope_transfer_user_month;size:7" json:"monthKey"`
+	Month        Month     `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	FromCategory string    `gorm:"size:64;not null" json:"fromCategory"`
+	ToCategory   string    `gorm:"size:64;not null" json:"toCategory"`
+	Amount       Money     `json:"amount"`
+	Currency     string    `gorm:"size:3;not null;default:USD" json:"currency"`
+	Note         string    `gorm:"type:text" json:"note"`
+	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateEnvelopes ensures the envelope transfer table and its FKs exist
+func MigrateEnvelopes(db *gorm.DB) {
+	_ = db.AutoMigrate(&EnvelopeTransfer{})
+	if !db.Migrator().HasConstraint(&EnvelopeTransfer{}, "User") {
+		_ = db.Migrator().CreateConstraint(&EnvelopeTransfer{}, "User")
+	}
+	if !db.Migrator().HasConstraint(&EnvelopeTransfer{}, "Month") {
+		_ = db.Migrator().CreateConstraint(&EnvelopeTransfer{}, "Month")
+	}
+}
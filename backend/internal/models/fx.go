@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// FxRate is a daily exchange rate: 1 unit of Base buys Rate units of Quote.
+// Rates are shared across users and maintained by admins.
+type FxRate struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	Base      string    `gorm:"uniqueIndex:idx_fx_pair_date;size:3" json:"base"`
+	Quote     string    `gorm:"uniqueIndex:idx_fx_pair_date;size:3" json:"quote"`
+	Date      time.Time `gorm:"uniqueIndex:idx_fx_pair_date;type:date" json:"date"`
+	Rate      float64   `gorm:"not null" json:"rate"`
+	Source    string    `gorm:"size:64" json:"source"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateFx ensures the fx_rates table exists
+func MigrateFx(db *gorm.DB) {
+	_ = db.AutoMigrate(&FxRate{})
+}
@@ -1,8 +1,6 @@
 package models
 
 import (
-    "log"
-    "os"
     "time"
     "gorm.io/gorm"
 )
@@ -24,26 +22,86 @@ type Goal struct {
 	TargetDate    *time.Time `json:"targetDate"`
 	Status        string     `gorm:"type:varchar(20);not null;default:not_started" json:"status"`
 	CreatedAt     time.Time  `gorm:"autoCreateTime" json:"createdAt"`
-	TargetAmount  *float64   `json:"targetAmount"`
-	CurrentAmount *float64   `json:"currentAmount"`
+	TargetAmount  *Money     `json:"targetAmount"`
+	CurrentAmount *Money     `json:"currentAmount"`
+	Currency      string     `gorm:"size:3;not null;default:USD" json:"currency"`
+	// Progress converted to the owner's base currency; computed per request, not stored
+	BaseCurrency      string   `gorm:"-" json:"baseCurrency,omitempty"`
+	TargetAmountBase  *Money   `gorm:"-" json:"targetAmountBase,omitempty"`
+	CurrentAmountBase *Money   `gorm:"-" json:"currentAmountBase,omitempty"`
+}
+
+// GoalContribution is a single deposit (or withdrawal, when negative) towards a goal.
+// Goal.CurrentAmount is a cached sum of these rows and is never written directly.
+type GoalContribution struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	GoalID    string    `gorm:"index;size:36" json:"goalId"`
+	Goal      Goal      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserID    string    `gorm:"index;size:36" json:"userId"`
+	Amount    Money     `json:"amount"`
+	Currency  string    `gorm:"size:3;not null;default:USD" json:"currency"`
+	Date      time.Time `json:"date"`
+	Note      string    `gorm:"type:text" json:"note"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// GoalInstallment is one period of a goal's savings schedule ("badge").
+// Rows are regenerated when schedule-defining goal fields change; progress is
+// allocated from the contributions total in sequence order.
+type GoalInstallment struct {
+	ID             string     `gorm:"primaryKey;size:36" json:"id"`
+	GoalID         string     `gorm:"uniqueIndex:idx_goal_installment_seq;size:36" json:"goalId"`
+	Goal           Goal       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserID         string     `gorm:"index;size:36" json:"userId"`
+	Sequence       int        `gorm:"uniqueIndex:idx_goal_installment_seq" json:"sequence"`
+	DueDate        time.Time  `json:"dueDate"`
+	PlannedAmount  Money      `json:"plannedAmount"`
+	ProgressAmount Money      `json:"progressAmount"`
+	Completed      bool       `gorm:"not null;default:false" json:"completed"`
+	CompletedAt    *time.Time `json:"completedAt"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// ProgressStatus derives the goal status from a contribution total
+func (g *Goal) ProgressStatus(total Money) string {
+	if g.TargetAmount != nil && *g.TargetAmount > 0 && total >= *g.TargetAmount {
+		return "completed"
+	}
+	if total > 0 {
+		return "in_progress"
+	}
+	return "not_started"
 }
 
 // MigrateGoals performs auto-migration for the Goal model
 func MigrateGoals(db *gorm.DB) {
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") == "true" {
-        log.Println("AutoMigrate disabled; skipping goals table migration")
-    } else {
-        _ = db.AutoMigrate(&Goal{})
-    }
-    // Ensure column sizes for legacy schemas (guarded by env)
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") == "true" {
-        log.Println("Legacy migrations disabled; skipping goals legacy column alters")
-    } else {
-        db.Exec("ALTER TABLE `goals` MODIFY `id` VARCHAR(36) NOT NULL")
-        db.Exec("ALTER TABLE `goals` MODIFY `user_id` VARCHAR(36)")
+    _ = db.AutoMigrate(&Goal{})
+    // Ensure column sizes for legacy schemas
+    db.Exec("ALTER TABLE `goals` MODIFY `id` VARCHAR(36) NOT NULL")
+    db.Exec("ALTER TABLE `goals` MODIFY `user_id` VARCHAR(36)")
+    // Additive columns
+    if !db.Migrator().HasColumn(&Goal{}, "Currency") {
+        db.Exec("ALTER TABLE `goals` ADD COLUMN `currency` VARCHAR(3) NOT NULL DEFAULT 'USD'")
     }
     // Add FK only if missing to avoid duplicate constraint errors
     if !db.Migrator().HasConstraint(&Goal{}, "User") {
         _ = db.Migrator().CreateConstraint(&Goal{}, "User")
     }
+    // Contributions ledger always exists; it is the source of truth for current_amount
+    _ = db.AutoMigrate(&GoalContribution{})
+    if !db.Migrator().HasConstraint(&GoalContribution{}, "Goal") {
+        _ = db.Migrator().CreateConstraint(&GoalContribution{}, "Goal")
+    }
+    _ = db.AutoMigrate(&GoalInstallment{})
+    if !db.Migrator().HasConstraint(&GoalInstallment{}, "Goal") {
+        _ = db.Migrator().CreateConstraint(&GoalInstallment{}, "Goal")
+    }
+    // Money columns are exact DECIMAL(19,4)
+    migrateMoneyColumns(db, "goals", "target_amount", "current_amount")
+    migrateMoneyColumns(db, "goal_contributions", "amount")
+    migrateMoneyColumns(db, "goal_installments", "planned_amount", "progress_amount")
+    // Backfill an opening balance for goals saved before the ledger existed
+    db.Exec("INSERT INTO goal_contributions (id, goal_id, user_id, amount, date, note, created_at) " +
+        "SELECT UUID(), g.id, g.user_id, g.current_amount, COALESCE(g.start_date, g.created_at, NOW()), 'Opening balance', NOW() FROM goals g " +
+        "WHERE g.current_amount > 0 AND NOT EXISTS (SELECT 1 FROM goal_contributions c WHERE c.goal_id = g.id)")
 }
\ No newline at end of file
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Job statuses
+const (
+	JobQueued  = "queued"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead" // dead-lettered: out of attempts or permanently failed; kept for inspection and retry
+)
+
+// Job kinds
+const (
+	JobMonthSummary = "month_summary"
+)
+
+// MonthJobPayload is the payload of jobs about one of a user's months
+type MonthJobPayload struct {
+	UserID   string `json:"userId"`
+	MonthKey string `json:"monthKey"`
+}
+
+// Job is a unit of background work in the jobs queue. Payload is JSON for the kind's handler.
+// A running job whose LockedUntil has passed is considered abandoned and may be claimed again.
+type Job struct {
+	ID          string     `gorm:"primaryKey;size:36" json:"id"`
+	Kind        string     `gorm:"size:64;not null;index" json:"kind"`
+	Payload     string     `gorm:"type:text" json:"payload"`
+	Status      string     `gorm:"size:16;not null;default:queued;index:idx_job_status_run" json:"status"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null;default:5" json:"maxAttempts"`
+	RunAt       time.Time  `gorm:"index:idx_job_status_run" json:"runAt"`
+	LockedBy    string     `gorm:"size:64;not null;default:''" json:"lockedBy,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	LastError   string     `gorm:"size:1024;not null;default:''" json:"lastError,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// MigrateJobs ensures the jobs table exists
+func MigrateJobs(db *gorm.DB) {
+	_ = db.AutoMigrate(&Job{})
+}
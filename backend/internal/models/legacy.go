@@ -0,0 +1,22 @@
+package models
+
+import "gorm.io/gorm"
+
+// MigrateLegacy runs the startup migrations used before versioned SQL migrations: it creates missing
+// tables and aligns legacy schemas (column types, keys, FKs, backfills). It only runs once per
+// database, when one without applied migrations is baselined; internal/migrate alone decides when.
+// A failed data backfill is returned so that the baseline is not recorded over half-migrated data.
+func MigrateLegacy(db *gorm.DB) error {
+	MigrateGoals(db)
+	if err := MigrateSpending(db); err != nil { return err }
+	MigrateAuth(db)
+	MigrateSessions(db)
+	MigrateRecurring(db)
+	MigrateFx(db)
+	MigrateTemplates(db)
+	MigrateEnvelopes(db)
+	MigrateCategorization(db)
+	MigrateNotifications(db)
+	MigrateJobs(db)
+	return nil
+}
@@ -0,0 +1,160 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Money is an exact amount in ten-thousandths of a currency unit, matching DECIMAL(19,4) columns.
+// It marshals to a JSON fixed-point number ("12.50") and unmarshals from numbers or strings.
+type Money int64
+
+// moneyScale is the number of Money units in one currency unit
+const moneyScale = 10000
+
+var ErrInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney parses a decimal string ("12.34", "-0.5", "1e3") exactly, rounding half away from zero past 4 places
+func ParseMoney(s string) (Money, error) {
+	r, ok := new(big.Rat).SetString(strings.TrimSpace(s))
+	if !ok { return 0, fmt.Errorf("%w: %q", ErrInvalidMoney, s) }
+	return moneyFromRat(r)
+}
+
+// MoneyFromFloat converts a float using its shortest decimal representation, so 0.1 becomes exactly 0.1
+func MoneyFromFloat(f float64) Money {
+	m, _ := ParseMoney(fmt.Sprint(f))
+	return m
+}
+
+// MoneyFromInt returns n whole currency units
+func MoneyFromInt(n int64) Money { return Money(n * moneyScale) }
+
+func moneyFromRat(r *big.Rat) (Money, error) {
+	num := new(big.Int).Mul(r.Num(), big.NewInt(moneyScale))
+	q, rem := new(big.Int).QuoRem(num, r.Denom(), new(big.Int))
+	// Round half away from zero
+	if new(big.Int).Mul(new(big.Int).Abs(rem), big.NewInt(2)).Cmp(r.Denom()) >= 0 {
+		if num.Sign() < 0 { q.Sub(q, big.NewInt(1)) } else { q.Add(q, big.NewInt(1)) }
+	}
+	if !q.IsInt64() { return 0, fmt.Errorf("%w: out of range", ErrInvalidMoney) }
+	return Money(q.Int64()), nil
+}
+
+// String formats the amount with at least 2 and at most 4 decimals
+func (m Money) String() string {
+	u := uint64(m)
+	sign := ""
+	if m < 0 {
+		u = -u
+		sign = "-"
+	}
+	frac := fmt.Sprintf("%04d", u%moneyScale)
+	frac = strings.TrimRight(frac, "0")
+	for len(frac) < 2 { frac += "0" }
+	return fmt.Sprintf("%s%d.%s", sign, u/moneyScale, frac)
+}
+
+// Float64 is for ratios and display only; never sum the result
+func (m Money) Float64() float64 { return float64(m) / moneyScale }
+
+func (m Money) Abs() Money {
+	if m < 0 { return -m }
+	return m
+}
+
+// Quo divides by n, rounding half away from zero
+func (m Money) Quo(n int64) Money {
+	q, _ := moneyFromRat(new(big.Rat).SetFrac(big.NewInt(int64(m)), big.NewInt(n*moneyScale)))
+	return q
+}
+
+// RoundTo rounds to the given number of decimal places (0-4), half away from zero
+func (m Money) RoundTo(places int) Money {
+	if places >= 4 { return m }
+	unit := int64(math.Pow10(4 - places))
+	q, _ := moneyFromRat(new(big.Rat).SetFrac(big.NewInt(int64(m)), big.NewInt(unit*moneyScale)))
+	return q * Money(unit)
+}
+
+// MulRate multiplies by an exchange rate (taken at its exact binary value) and rounds to 4 places
+func (m Money) MulRate(rate float64) Money {
+	r := new(big.Rat).SetFloat64(rate)
+	if r == nil { return 0 }
+	r.Mul(r, new(big.Rat).SetFrac(big.NewInt(int64(m)), big.NewInt(moneyScale)))
+	out, _ := moneyFromRat(r)
+	return out
+}
+
+// Ratio returns m/d as a float for percentages; 0 when d is 0
+func (m Money) Ratio(d Money) float64 {
+	if d == 0 { return 0 }
+	f, _ := new(big.Rat).SetFrac(big.NewInt(int64(m)), big.NewInt(int64(d))).Float64()
+	return f
+}
+
+func (m Money) MarshalJSON() ([]byte, error) { return []byte(m.String()), nil }
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	s := string(b)
+	if s == "null" { return nil }
+	s = strings.Trim(s, `"`)
+	v, err := ParseMoney(s)
+	if err != nil { return err }
+	*m = v
+	return nil
+}
+
+// Scan reads DECIMAL values (returned as text by the MySQL driver) and legacy DOUBLE values
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case []byte:
+		p, err := ParseMoney(string(v))
+		if err != nil { return err }
+		*m = p
+	case string:
+		p, err := ParseMoney(v)
+		if err != nil { return err }
+		*m = p
+	case float64:
+		*m = MoneyFromFloat(v)
+	case float32:
+		*m = MoneyFromFloat(float64(v))
+	case int64:
+		*m = MoneyFromInt(v)
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidMoney, value)
+	}
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) { return m.String(), nil }
+
+func (Money) GormDataType() string { return "decimal" }
+
+func (Money) GormDBDataType(db *gorm.DB, field *schema.Field) string { return "DECIMAL(19,4)" }
+
+// migrateMoneyColumns converts legacy DOUBLE amount columns to DECIMAL(19,4), keeping nullability.
+// MySQL rounds each value to 4 decimals, which is exact for every amount entered through the API.
+func migrateMoneyColumns(db *gorm.DB, table string, columns ...string) {
+	for _, col := range columns {
+		var info struct {
+			DataType   string
+			IsNullable string
+		}
+		db.Raw("SELECT DATA_TYPE AS data_type, IS_NULLABLE AS is_nullable FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = ?", table, col).Scan(&info)
+		if info.DataType != "double" && info.DataType != "float" { continue }
+		null := "NOT NULL"
+		if info.IsNullable == "YES" { null = "NULL" }
+		db.Exec(fmt.Sprintf("ALTER TABLE `%s` MODIFY `%s` DECIMAL(19,4) %s", table, col, null))
+	}
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Money
+		err  bool
+	}{
+		{"12.34", 123400, false},
+		{" 12.5 ", 125000, false},
+		{"-0.5", -5000, false},
+		{"1e3", MoneyFromInt(1000), false},
+		{"0.12344", 1234, false},
+		{"0.12345", 1235, false}, // half away from zero
+		{"-0.12345", -1235, false},
+		{"0.00004999", 0, false},
+		{"0.00005", 1, false},
+		{"-0.00005", -1, false},
+		{"922337203685477.5807", 9223372036854775807, false},
+		{"1e20", 0, true},
+		{"", 0, true},
+		{"12,50", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if (err != nil) != tt.err { t.Errorf("ParseMoney(%q) err = %v", tt.in, err); continue }
+		if err != nil && !errors.Is(err, ErrInvalidMoney) { t.Errorf("ParseMoney(%q) err = %v, want ErrInvalidMoney", tt.in, err) }
+		if got != tt.want { t.Errorf("ParseMoney(%q) = %d, want %d", tt.in, got, tt.want) }
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := map[Money]string{0: "0.00", 1: "0.0001", -1: "-0.0001", 125000: "12.50", 123456: "12.3456", 123450: "12.345", MoneyFromInt(-3): "-3.00"}
+	for m, want := range tests {
+		if got := m.String(); got != want { t.Errorf("Money(%d).String() = %s, want %s", int64(m), got, want) }
+	}
+}
+
+func TestMoneyRounding(t *testing.T) {
+	mustParse := func(s string) Money { m, err := ParseMoney(s); if err != nil { t.Fatal(err) }; return m }
+	tests := []struct {
+		name      string
+		got, want Money
+	}{
+		{"quo thirds", MoneyFromInt(100).Quo(3), mustParse("33.3333")},
+		{"quo rounds up", MoneyFromInt(2).Quo(3), mustParse("0.6667")},
+		{"quo negative", MoneyFromInt(-2).Quo(3), mustParse("-0.6667")},
+		{"round half up", mustParse("1.005").RoundTo(2), mustParse("1.01")},
+		{"round half negative", mustParse("-1.005").RoundTo(2), mustParse("-1.01")},
+		{"round down", mustParse("1.0049").RoundTo(2), mustParse("1.00")},
+		{"round to units", mustParse("2.5").RoundTo(0), MoneyFromInt(3)},
+		{"round at 4 places", mustParse("1.2345").RoundTo(4), mustParse("1.2345")},
+		{"float shortest form", MoneyFromFloat(0.1), mustParse("0.1")},
+		{"float sum", MoneyFromFloat(0.1 + 0.2), mustParse("0.3")},
+		{"rate", MoneyFromInt(100).MulRate(0.1), MoneyFromInt(10)},
+		{"rate rounds", MoneyFromInt(1).MulRate(1.0 / 3), mustParse("0.3333")},
+		{"rate inverse", MoneyFromInt(8200).MulRate(1.0 / 4100), MoneyFromInt(2)},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want { t.Errorf("%s: got %s, want %s", tt.name, tt.got, tt.want) }
+	}
+	if r := MoneyFromInt(1).Ratio(MoneyFromInt(4)); r != 0.25 { t.Errorf("Ratio = %v, want 0.25", r) }
+	if r := MoneyFromInt(1).Ratio(0); r != 0 { t.Errorf("Ratio by zero = %v, want 0", r) }
+}
+
+func TestMoneyJSONAndScan(t *testing.T) {
+	var v struct{ A, B, C Money }
+	if err := json.Unmarshal([]byte(`{"A": 12.5, "B": "-0.0001", "C": null}`), &v); err != nil { t.Fatal(err) }
+	if v.A != 125000 || v.B != -1 || v.C != 0 { t.Errorf("unmarshal = %+v", v) }
+	out, _ := json.Marshal(v)
+	if string(out) != `{"A":12.50,"B":-0.0001,"C":0.00}` { t.Errorf("marshal = %s", out) }
+	if err := json.Unmarshal([]byte(`{"A": "ten"}`), &v); !errors.Is(err, ErrInvalidMoney) { t.Errorf("unmarshal of a word: err = %v", err) }
+
+	for _, in := range []interface{}{[]byte("12.5000"), "12.5", 12.5, float32(12.5)} {
+		var m Money
+		if err := m.Scan(in); err != nil || m != 125000 { t.Errorf("Scan(%#v) = %s, %v", in, m, err) }
+	}
+	var m Money = 7
+	if err := m.Scan(nil); err != nil || m != 0 { t.Errorf("Scan(nil) = %s, %v", m, err) }
+	if err := m.Scan(int64(3)); err != nil || m != MoneyFromInt(3) { t.Errorf("Scan(int64) = %s, %v", m, err) }
+	if err := m.Scan(true); !errors.Is(err, ErrInvalidMoney) { t.Errorf("Scan(bool) err = %v", err) }
+}
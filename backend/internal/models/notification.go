@@ -0,0 +1,89 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Notification kinds
+const (
+	NotificationBudgetThreshold = "budget_threshold"
+	NotificationBorrowDue       = "borrow_due"
+	NotificationBorrowOverdue   = "borrow_overdue"
+	NotificationGoalMilestone   = "goal_milestone"
+	NotificationMonthSummary    = "month_summary"
+)
+
+// Outbound notification channels
+const (
+	ChannelSignal   = "signal"
+	ChannelTelegram = "telegram"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed" // gave up after the last attempt or a permanent error
+)
+
+// Notification is a message for the user, such as a budget alert. DedupeKey identifies the event
+// (e.g. "budget:2024-05:Food:80"), so the same event is only ever notified once per user.
+type Notification struct {
+	ID        string     `gorm:"primaryKey;size:36" json:"id"`
+	UserID    string     `gorm:"uniqueIndex:idx_notification_user_key;index:idx_notification_user_created;size:36" json:"userId"`
+	User      User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Kind      string     `gorm:"size:32;not null" json:"kind"`
+	DedupeKey string     `gorm:"uniqueIndex:idx_notification_user_key;size:191;not null" json:"-"`
+	Title     string     `gorm:"size:255;not null" json:"title"`
+	Body      string     `gorm:"type:text" json:"body"`
+	MonthKey  string     `gorm:"size:7;not null;default:''" json:"monthKey,omitempty"`
+	Category  string     `gorm:"size:64;not null;default:''" json:"category,omitempty"`
+	ReadAt    *time.Time `json:"readAt"`
+	CreatedAt time.Time  `gorm:"autoCreateTime;index:idx_notification_user_created" json:"createdAt"`
+}
+
+// NotificationChannel is a user's setting for one outbound channel. Address is the Signal phone
+// number (E.164) or the Telegram chat ID the user's notifications are sent to.
+type NotificationChannel struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID    string    `gorm:"uniqueIndex:idx_channel_user_channel;size:36" json:"userId"`
+	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Channel   string    `gorm:"uniqueIndex:idx_channel_user_channel;size:16" json:"channel"`
+	Address   string    `gorm:"size:128;not null" json:"address"`
+	Enabled   bool      `gorm:"not null;default:true" json:"enabled"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updatedAt"`
+}
+
+// NotificationDelivery is an outbox row: one notification to be sent through one channel. Rows are
+// written in the transaction that creates the notification and retried until sent or failed.
+type NotificationDelivery struct {
+	ID             string       `gorm:"primaryKey;size:36" json:"id"`
+	NotificationID string       `gorm:"uniqueIndex:idx_delivery_notification_channel;size:36" json:"notificationId"`
+	Notification   Notification `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserID         string       `gorm:"index;size:36" json:"userId"`
+	Channel        string       `gorm:"uniqueIndex:idx_delivery_notification_channel;size:16" json:"channel"`
+	Address        string       `gorm:"size:128;not null" json:"address"`
+	Status         string       `gorm:"size:16;not null;default:pending;index:idx_delivery_status_next" json:"status"`
+	Attempts       int          `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time    `gorm:"index:idx_delivery_status_next" json:"nextAttemptAt"`
+	LastError      string       `gorm:"size:512;not null;default:''" json:"lastError,omitempty"`
+	SentAt         *time.Time   `json:"sentAt"`
+	CreatedAt      time.Time    `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateNotifications ensures the notification, channel and outbox tables and their FKs exist
+func MigrateNotifications(db *gorm.DB) {
+	_ = db.AutoMigrate(&Notification{}, &NotificationChannel{}, &NotificationDelivery{})
+	if !db.Migrator().HasConstraint(&Notification{}, "User") {
+		_ = db.Migrator().CreateConstraint(&Notification{}, "User")
+	}
+	if !db.Migrator().HasConstraint(&NotificationChannel{}, "User") {
+		_ = db.Migrator().CreateConstraint(&NotificationChannel{}, "User")
+	}
+	if !db.Migrator().HasConstraint(&NotificationDelivery{}, "Notification") {
+		_ = db.Migrator().CreateConstraint(&NotificationDelivery{}, "Notification")
+	}
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// RecurringRule materializes spending or earning entries on an RRULE-like schedule,
+// e.g. "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=1" for rent on the first of every month.
+// Category applies to spending rules, Source to earning rules.
+type RecurringRule struct {
+	ID          string     `gorm:"primaryKey;size:36" json:"id"`
+	UserID      string     `gorm:"index;size:36" json:"userId"`
+	User        User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Kind        string     `gorm:"type:varchar(16);not null" json:"kind"`
+	Amount      Money      `json:"amount"`
+	Currency    string     `gorm:"size:3;not null;default:USD" json:"currency"`
+	Category    string     `gorm:"size:64" json:"category"`
+	Source      string     `json:"source"`
+	Note        string     `gorm:"type:text" json:"note"`
+	RRule       string     `gorm:"column:rrule;size:255;not null" json:"rrule"`
+	StartDate   time.Time  `json:"startDate"`
+	EndDate     *time.Time `json:"endDate"`
+	NextRunDate *time.Time `gorm:"index" json:"nextRunDate"`
+	LastRunDate *time.Time `json:"lastRunDate"`
+	Active      bool       `gorm:"not null;default:true" json:"active"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// RecurringOccurrence records each materialized occurrence; the unique
+// (rule_id, occurrence_date) key makes materialization idempotent.
+type RecurringOccurrence struct {
+	ID             string        `gorm:"primaryKey;size:36" json:"id"`
+	RuleID         string        `gorm:"uniqueIndex:idx_rule_occurrence;size:36" json:"ruleId"`
+	Rule           RecurringRule `gorm:"foreignKey:RuleID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	OccurrenceDate time.Time     `gorm:"uniqueIndex:idx_rule_occurrence" json:"occurrenceDate"`
+	EntryID        string        `gorm:"size:36" json:"entryId"`
+	CreatedAt      time.Time     `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateRecurring ensures recurring rule tables and their FKs exist
+func MigrateRecurring(db *gorm.DB) {
+	_ = db.AutoMigrate(&RecurringRule{}, &RecurringOccurrence{})
+	if !db.Migrator().HasConstraint(&RecurringRule{}, "User") {
+		_ = db.Migrator().CreateConstraint(&RecurringRule{}, "User")
+	}
+	if !db.Migrator().HasConstraint(&RecurringOccurrence{}, "Rule") {
+		_ = db.Migrator().CreateConstraint(&RecurringOccurrence{}, "Rule")
+	}
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Session is a server-side login session backing a refresh token.
+// Its ID is carried as the `jti` claim of every access token issued for it,
+// so revoking the row invalidates those tokens before they expire.
+// RefreshTokenHash stores a SHA-256 digest; the raw token is never persisted.
+type Session struct {
+	ID                       string     `gorm:"primaryKey;size:36" json:"id"`
+	UserID                   string     `gorm:"index;size:36" json:"userId"`
+	User                     User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	RefreshTokenHash         string     `gorm:"uniqueIndex;size:64" json:"-"`
+	// PreviousRefreshTokenHash is the hash last rotated away from; presenting it again revokes the session
+	PreviousRefreshTokenHash string     `gorm:"index;size:64;not null;default:''" json:"-"`
+	ExpiresAt                time.Time  `json:"expiresAt"`
+	RevokedAt                *time.Time `json:"revokedAt"`
+	LastUsedAt               *time.Time `json:"lastUsedAt"`
+	CreatedAt                time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// MigrateSessions ensures the sessions table and its user FK exist
+func MigrateSessions(db *gorm.DB) {
+	_ = db.AutoMigrate(&Session{})
+	if !db.Migrator().HasConstraint(&Session{}, "User") {
+		_ = db.Migrator().CreateConstraint(&Session{}, "User")
+	}
+}
@@ -1,15 +1,15 @@
 package models
 
 import (
-    "log"
-    "os"
+    "fmt"
     "time"
     "gorm.io/gorm"
 )
 
 type SpendingEntry struct {
 	ID        string    `gorm:"primaryKey;size:36" json:"id"`
-	Amount    float64   `json:"amount"`
+	Amount    Money     `json:"amount"`
+	Currency  string    `gorm:"size:3;not null;default:USD" json:"currency"`
 	Category  string    `gorm:"index;size:64" json:"category"`
 	Date      time.Time `json:"date"`
 	MonthKey  string    `gorm:"index;size:7" json:"monthKey"`
@@ -17,38 +17,109 @@ type SpendingEntry struct {
 	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 	Month     Month     `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 	Note      string    `gorm:"type:text" json:"note"`
+	Merchant  string    `gorm:"size:255;not null;default:''" json:"merchant"`
+	// Version is bumped on every update for optimistic concurrency
+	Version   int64     `gorm:"not null;default:1" json:"version"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 type EarningEntry struct {
 	ID        string    `gorm:"primaryKey;size:36" json:"id"`
 	Source    string    `json:"source"`
-	Amount    float64   `json:"amount"`
+	Amount    Money     `json:"amount"`
+	Currency  string    `gorm:"size:3;not null;default:USD" json:"currency"`
 	Date      time.Time `json:"date"`
 	MonthKey  string    `gorm:"index;size:7" json:"monthKey"`
 	UserID    string    `gorm:"index;size:36" json:"userId"`
 	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 	Month     Month     `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Version   int64     `gorm:"not null;default:1" json:"version"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
+// Debt directions: money I borrowed from the counterparty, or lent to them
+const (
+	DirectionBorrowed = "borrowed"
+	DirectionLent     = "lent"
+)
+
+// Interest compounding modes; simple interest accrues on the outstanding principal only
+const (
+	CompoundSimple  = "simple"
+	CompoundDaily   = "daily"
+	CompoundMonthly = "monthly"
+	CompoundYearly  = "yearly"
+)
+
+// BorrowEntry is a debt with a counterparty in either direction
 type BorrowEntry struct {
-	ID           string     `gorm:"primaryKey;size:36" json:"id"`
-	From         string     `json:"from"`
-	Amount       float64    `json:"amount"`
-	Date         time.Time  `json:"date"`
-	MonthKey     string     `gorm:"index;size:7" json:"monthKey"`
-	UserID       string     `gorm:"index;size:36" json:"userId"`
-	User         User       `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
-	Month        Month      `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
-	RepaidAmount *float64   `json:"repaidAmount"`
-	RepaidDate   *time.Time `json:"repaidDate"`
-	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"createdAt"`
+	ID             string       `gorm:"primaryKey;size:36" json:"id"`
+	Direction      string       `gorm:"size:8;not null;default:borrowed" json:"direction"`
+	CounterpartyID string       `gorm:"index;size:36" json:"counterpartyId"`
+	Counterparty   Counterparty `gorm:"constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	Amount         Money        `json:"amount"`
+	Currency       string       `gorm:"size:3;not null;default:USD" json:"currency"`
+	Date           time.Time    `json:"date"`
+	DueDate        *time.Time   `gorm:"type:date;index" json:"dueDate"`
+	// InterestRate is a yearly percentage (5 = 5%/year), accrued per day on a 365-day year
+	InterestRate   float64      `gorm:"type:decimal(9,4);not null;default:0" json:"interestRate"`
+	Compounding    string       `gorm:"size:8;not null;default:simple" json:"compounding"`
+	MonthKey       string       `gorm:"index;size:7" json:"monthKey"`
+	UserID         string       `gorm:"index;size:36" json:"userId"`
+	User           User         `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Month          Month        `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Version        int64        `gorm:"not null;default:1" json:"version"`
+	CreatedAt      time.Time    `gorm:"autoCreateTime" json:"createdAt"`
+	// Derived from borrow_repayments when loaded through the repository; never stored
+	RepaidAmount   Money        `gorm:"->;-:migration" json:"repaidAmount"`
+	RepaidDate     *time.Time   `gorm:"->;-:migration" json:"repaidDate"`
+	Outstanding    Money        `gorm:"->;-:migration" json:"outstanding"`
+	Settled        bool         `gorm:"->;-:migration" json:"settled"`
+	// From is the counterparty name, kept for clients of the former free-text field
+	From           string       `gorm:"->;-:migration" json:"from"`
 }
 
+// Counterparty is a person or organisation the user borrows from or lends to
+type Counterparty struct {
+	ID        string    `gorm:"primaryKey;size:36" json:"id"`
+	UserID    string    `gorm:"uniqueIndex:idx_counterparty_user_name;size:36" json:"userId"`
+	User      User      `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Name      string    `gorm:"uniqueIndex:idx_counterparty_user_name;size:255" json:"name"`
+	Note      string    `gorm:"type:text" json:"note"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// BorrowRepayment is one (possibly partial) repayment of a borrow, in the borrow's currency.
+// The ledger is the source of truth for a borrow's repaid and outstanding amounts.
+type BorrowRepayment struct {
+	ID        string      `gorm:"primaryKey;size:36" json:"id"`
+	BorrowID  string      `gorm:"index;size:36" json:"borrowId"`
+	Borrow    BorrowEntry `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserID    string      `gorm:"index;size:36" json:"userId"`
+	Amount    Money       `json:"amount"`
+	Date      time.Time   `json:"date"`
+	Note      string      `gorm:"type:text" json:"note"`
+	CreatedAt time.Time   `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// Category kinds for budgeting: needs vs wants
+const (
+	CategoryEssential     = "essential"
+	CategoryDiscretionary = "discretionary"
+)
+
+// Category names are referenced by value from spending entries, plans and recurring rules
 type Category struct {
 	UserID    string    `gorm:"primaryKey;size:36" json:"userId"`
 	Name      string    `gorm:"primaryKey;size:64" json:"name"`
+	// Parent is the parent category's name; empty for top-level categories
+	Parent    string    `gorm:"size:64;not null;default:''" json:"parent"`
+	Color     string    `gorm:"size:7;not null;default:''" json:"color"` // #rrggbb
+	Icon      string    `gorm:"size:64;not null;default:''" json:"icon"`
+	Type      string    `gorm:"size:16;not null;default:discretionary" json:"type"`
+	SortOrder int       `gorm:"not null;default:0" json:"sortOrder"`
+	// Archived categories are not seeded into new months but keep their history
+	Archived  bool      `gorm:"not null;default:false" json:"archived"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
@@ -58,7 +129,8 @@ type Plan struct {
 	MonthKey      string    `gorm:"uniqueIndex:idx_user_month_category;size:7" json:"monthKey"`
 	Month         Month     `gorm:"foreignKey:UserID,MonthKey;references:UserID,MonthKey;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
 	Category      string    `gorm:"uniqueIndex:idx_user_month_category;size:64" json:"category"`
-	PlannedAmount float64   `json:"plannedAmount"`
+	PlannedAmount Money     `json:"plannedAmount"`
+	Currency      string    `gorm:"size:3;not null;default:USD" json:"currency"`
 	CreatedAt     time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
@@ -68,10 +140,40 @@ type Month struct {
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
-func MigrateSpending(db *gorm.DB) {
+// MigrateSpending aligns the spending tables; it returns an error when a data backfill fails, since
+// the columns it reads are dropped and a later start could not redo it
+func MigrateSpending(db *gorm.DB) error {
     // Always ensure core tables exist using AutoMigrate
     // Create parent tables first to avoid FK issues
-    _ = db.AutoMigrate(&Month{}, &Category{}, &Plan{}, &SpendingEntry{}, &EarningEntry{}, &BorrowEntry{})
+    _ = db.AutoMigrate(&Month{}, &Category{}, &Plan{}, &SpendingEntry{}, &EarningEntry{}, &Counterparty{}, &BorrowEntry{}, &BorrowRepayment{})
+    // Money columns are exact DECIMAL(19,4); convert legacy DOUBLE columns in place
+    migrateMoneyColumns(db, "plans", "planned_amount")
+    migrateMoneyColumns(db, "spending_entries", "amount")
+    migrateMoneyColumns(db, "earning_entries", "amount")
+    migrateMoneyColumns(db, "borrow_entries", "amount")
+    // Move the legacy single repaid_amount/repaid_date pair into the repayments ledger, then drop it
+    if db.Migrator().HasColumn("borrow_entries", "repaid_amount") {
+        err := db.Transaction(func(tx *gorm.DB) error {
+            // DDL commits implicitly, so a failed drop leaves the inserted rows behind; skip borrows
+            // that already have repayments when the backfill is retried
+            if err := tx.Exec(`INSERT INTO borrow_repayments (id, borrow_id, user_id, amount, date, note, created_at)
+                SELECT UUID(), b.id, b.user_id, b.repaid_amount, COALESCE(b.repaid_date, b.date), '', NOW(3)
+                FROM borrow_entries b WHERE b.repaid_amount IS NOT NULL AND b.repaid_amount <> 0
+                AND NOT EXISTS (SELECT 1 FROM borrow_repayments r WHERE r.borrow_id = b.id)`).Error; err != nil { return err }
+            if err := tx.Exec("ALTER TABLE borrow_entries DROP COLUMN repaid_amount").Error; err != nil { return err }
+            return tx.Exec("ALTER TABLE borrow_entries DROP COLUMN repaid_date").Error
+        })
+        if err != nil { return fmt.Errorf("borrow repayments backfill: %w", err) }
+    }
+    // Replace the legacy free-text `from` with one counterparty per distinct name, then drop it
+    if db.Migrator().HasColumn("borrow_entries", "from") {
+        err := db.Transaction(func(tx *gorm.DB) error {
+            if err := tx.Exec("INSERT IGNORE INTO counterparties (id, user_id, name, note, created_at) SELECT UUID(), user_id, name, '', NOW(3) FROM (SELECT DISTINCT user_id, TRIM(`from`) AS name FROM borrow_entries) d").Error; err != nil { return err }
+            if err := tx.Exec("UPDATE borrow_entries b JOIN counterparties c ON c.user_id = b.user_id AND c.name = TRIM(b.`from`) SET b.counterparty_id = c.id WHERE b.counterparty_id IS NULL OR b.counterparty_id = ''").Error; err != nil { return err }
+            return tx.Exec("ALTER TABLE borrow_entries DROP COLUMN `from`").Error
+        })
+        if err != nil { return fmt.Errorf("counterparties backfill: %w", err) }
+    }
     // Backfill month_key for existing records
     db.Exec("UPDATE spending_entries SET month_key = DATE_FORMAT(date, '%Y-%m') WHERE month_key IS NULL OR month_key = ''")
     db.Exec("UPDATE earning_entries SET month_key = DATE_FORMAT(date, '%Y-%m') WHERE month_key IS NULL OR month_key = ''")
@@ -96,6 +198,15 @@ func MigrateSpending(db *gorm.DB) {
         if !db.Migrator().HasConstraint(&BorrowEntry{}, "Month") {
             _ = db.Migrator().CreateConstraint(&BorrowEntry{}, "Month")
         }
+        if !db.Migrator().HasConstraint(&Counterparty{}, "User") {
+            _ = db.Migrator().CreateConstraint(&Counterparty{}, "User")
+        }
+        if !db.Migrator().HasConstraint(&BorrowEntry{}, "Counterparty") {
+            _ = db.Migrator().CreateConstraint(&BorrowEntry{}, "Counterparty")
+        }
+        if !db.Migrator().HasConstraint(&BorrowRepayment{}, "Borrow") {
+            _ = db.Migrator().CreateConstraint(&BorrowRepayment{}, "Borrow")
+        }
         if !db.Migrator().HasConstraint(&Plan{}, "Month") {
             _ = db.Migrator().CreateConstraint(&Plan{}, "Month")
         }
@@ -103,8 +214,7 @@ func MigrateSpending(db *gorm.DB) {
 
     // Safety: drop any reversed foreign keys mistakenly attached to `months`
     // This prevents failures on creating a month before entries exist.
-    // Only executes when legacy migrations are enabled explicitly.
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") != "true" {
+    {
         // Check and drop months -> earning/borrow/spending FKs if present
         var cnt int64
         db.Raw("SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLE_CONSTRAINTS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = 'months' AND CONSTRAINT_NAME = 'fk_earning_entries_month'").Scan(&cnt)
@@ -116,10 +226,8 @@ func MigrateSpending(db *gorm.DB) {
     }
 
     // --- Legacy schema alignment for production upgrades ---
-    // Guarded by env to avoid ALTER TABLE on live DBs with FKs
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") == "true" {
-        log.Println("Legacy migrations disabled; skipping spending legacy schema alignment")
-    } else {
+    // Runs only while a legacy database is baselined (see MigrateLegacy)
+    {
         // Categories: ensure user_id column and composite primary key (user_id, name)
         var cnt int64
         db.Raw("SELECT COUNT(*) FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'user_id'", "categories").Scan(&cnt)
@@ -177,4 +285,5 @@ func MigrateSpending(db *gorm.DB) {
         db.Exec("ALTER TABLE `borrow_entries` MODIFY `user_id` VARCHAR(36) NOT NULL")
         db.Exec("ALTER TABLE `borrow_entries` MODIFY `month_key` VARCHAR(7) NOT NULL")
     }
+    return nil
 }
\ No newline at end of file
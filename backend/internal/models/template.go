@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// PlanTemplate is a named budget: planned amounts per category that a new month can be created from
+type PlanTemplate struct {
+	ID        string             `gorm:"primaryKey;size:36" json:"id"`
+	UserID    string             `gorm:"uniqueIndex:idx_template_user_name;size:36" json:"userId"`
+	User      User               `gorm:"constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Name      string             `gorm:"uniqueIndex:idx_template_user_name;size:64;not null" json:"name"`
+	Items     []PlanTemplateItem `gorm:"foreignKey:TemplateID" json:"items"`
+	CreatedAt time.Time          `gorm:"autoCreateTime" json:"createdAt"`
+}
+
+// PlanTemplateItem is one category's planned amount in a template; UserID lets category
+// renames and merges rewrite items like they rewrite plans
+type PlanTemplateItem struct {
+	ID            string       `gorm:"primaryKey;size:36" json:"id"`
+	TemplateID    string       `gorm:"uniqueIndex:idx_template_category;size:36" json:"templateId"`
+	Template      PlanTemplate `gorm:"foreignKey:TemplateID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	UserID        string       `gorm:"index;size:36" json:"-"`
+	Category      string       `gorm:"uniqueIndex:idx_template_category;size:64" json:"category"`
+	PlannedAmount Money        `json:"plannedAmount"`
+	Currency      string       `gorm:"size:3;not null;default:USD" json:"currency"`
+}
+
+// MigrateTemplates ensures the plan template tables and their FKs exist
+func MigrateTemplates(db *gorm.DB) {
+	_ = db.AutoMigrate(&PlanTemplate{}, &PlanTemplateItem{})
+	if !db.Migrator().HasConstraint(&PlanTemplate{}, "User") {
+		_ = db.Migrator().CreateConstraint(&PlanTemplate{}, "User")
+	}
+	if !db.Migrator().HasConstraint(&PlanTemplateItem{}, "Template") {
+		_ = db.Migrator().CreateConstraint(&PlanTemplateItem{}, "Template")
+	}
+}
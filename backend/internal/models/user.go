@@ -1,8 +1,6 @@
 package models
 
 import (
-    "log"
-    "os"
     "time"
     
     "gorm.io/gorm"
@@ -12,29 +10,34 @@ import (
 // Keep tags minimal and aligned with API responses
 // PasswordHash is omitted from JSON
 type User struct {
-	ID           string    `gorm:"primaryKey" json:"id"`
-	Email        string    `gorm:"uniqueIndex;size:255" json:"email"`
-	Name         string    `json:"name"`
-	PasswordHash string    `json:"-"`
-	CreatedAt    time.Time `gorm:"autoCreateTime" json:"createdAt"`
+	ID                    string    `gorm:"primaryKey" json:"id"`
+	Email                 string    `gorm:"uniqueIndex;size:255" json:"email"`
+	Name                  string    `json:"name"`
+	PasswordHash          string    `json:"-"`
+	BaseCurrency          string    `gorm:"size:3;not null;default:USD" json:"baseCurrency"`
+	// BudgetAlertThresholds lists the percentages of a category's plan that raise a notification
+	// when spending reaches them, e.g. "80,100"; empty disables budget alerts
+	BudgetAlertThresholds string    `gorm:"size:64;not null;default:'80,100'" json:"budgetAlertThresholds"`
+	CreatedAt             time.Time `gorm:"autoCreateTime" json:"createdAt"`
 }
 
 func MigrateAuth(db *gorm.DB) {
-    // Ensure table exists (guarded)
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") == "true" {
-        log.Println("AutoMigrate disabled; skipping users table migration")
-    } else {
-        _ = db.AutoMigrate(&User{})
-    }
+    // Ensure table exists
+    _ = db.AutoMigrate(&User{})
 	// Verify the column type of `users.id`; fix if it's integer from legacy schema
 	var dataType string
 	db.Raw("SELECT DATA_TYPE FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND COLUMN_NAME = 'id'", "users").Scan(&dataType)
-    if os.Getenv("DISABLE_LEGACY_MIGRATIONS") == "true" {
-        log.Println("Legacy migrations disabled; skipping users.id type change")
-    } else if dataType != "varchar" {
+    if dataType != "varchar" {
         // Convert id to VARCHAR(36) to store UUIDs
         db.Exec("ALTER TABLE `users` MODIFY COLUMN `id` VARCHAR(36) NOT NULL")
         // Re-apply migration to ensure constraints (primary key, indexes)
         _ = db.AutoMigrate(&User{})
     }
+    // Additive columns
+    if !db.Migrator().HasColumn(&User{}, "BaseCurrency") {
+        db.Exec("ALTER TABLE `users` ADD COLUMN `base_currency` VARCHAR(3) NOT NULL DEFAULT 'USD'")
+    }
+    if !db.Migrator().HasColumn(&User{}, "BudgetAlertThresholds") {
+        db.Exec("ALTER TABLE `users` ADD COLUMN `budget_alert_thresholds` VARCHAR(64) NOT NULL DEFAULT '80,100'")
+    }
 }
\ No newline at end of file
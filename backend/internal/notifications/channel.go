@@ -0,0 +1,119 @@
+// Package notifications delivers the users' notifications through outbound channels (Signal, Telegram).
+// Deliveries are queued in the notification_deliveries outbox when a notification is created and sent
+// by the Dispatcher, which retries failed attempts with exponential backoff.
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+)
+
+// Message is what a channel sends
+type Message struct {
+	Title string
+	Body  string
+}
+
+// Text renders the message as plain text
+func (m Message) Text() string {
+	if m.Body == "" { return m.Title }
+	return m.Title + "\n\n" + m.Body
+}
+
+// Channel sends messages to an address (a phone number, a chat ID, ...) on one service
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, address string, msg Message) error
+}
+
+// PermanentError marks a failure that retrying cannot fix, such as an unknown recipient
+type PermanentError struct{ Err error }
+
+func (e *PermanentError) Error() string { return e.Err.Error() }
+func (e *PermanentError) Unwrap() error { return e.Err }
+
+// IsPermanent reports whether err should not be retried
+func IsPermanent(err error) bool {
+	var p *PermanentError
+	return errors.As(err, &p)
+}
+
+var (
+	signalAddress   = regexp.MustCompile(`^(\+[1-9][0-9]{6,14}|group\.[A-Za-z0-9+/=]+)$`)
+	telegramAddress = regexp.MustCompile(`^(-?[0-9]{1,20}|@[A-Za-z][A-Za-z0-9_]{4,31})$`)
+)
+
+// ValidAddress reports whether address is well-formed for the channel: an E.164 number or a
+// signal-cli group ID for Signal, a numeric chat ID or @channel name for Telegram
+func ValidAddress(channel, address string) bool {
+	switch channel {
+	case models.ChannelSignal:
+		return signalAddress.MatchString(address)
+	case models.ChannelTelegram:
+		return telegramAddress.MatchString(address)
+	}
+	return false
+}
+
+// FromEnv builds the channels configured in the environment, keyed by name:
+// Signal with SIGNAL_API_URL and SIGNAL_NUMBER (the registered sender), Telegram with
+// TELEGRAM_BOT_TOKEN (TELEGRAM_API_URL overrides https://api.telegram.org, e.g. for a local stand-in)
+func FromEnv() map[string]Channel {
+	client := &http.Client{Timeout: durationFromEnv("NOTIFY_HTTP_TIMEOUT", 10*time.Second)}
+	out := map[string]Channel{}
+	if url, number := os.Getenv("SIGNAL_API_URL"), os.Getenv("SIGNAL_NUMBER"); url != "" && number != "" {
+		out[models.ChannelSignal] = &SignalClient{BaseURL: url, Number: number, HTTP: client}
+	}
+	if token := os.Getenv("TELEGRAM_BOT_TOKEN"); token != "" {
+		out[models.ChannelTelegram] = &TelegramClient{BaseURL: os.Getenv("TELEGRAM_API_URL"), Token: token, HTTP: client}
+	}
+	return out
+}
+
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// postJSON posts body as JSON and decodes a JSON response into out when given. 4xx responses other
+// than 408 and 429 are permanent errors; network errors and other statuses may be retried.
+func postJSON(ctx context.Context, client *http.Client, url string, body, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil { return &PermanentError{err} }
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil { return &PermanentError{err} }
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil { client = http.DefaultClient }
+	resp, err := client.Do(req)
+	// The URL may carry a bot token; keep it out of stored errors
+	var ue *neturl.Error
+	if errors.As(err, &ue) { return fmt.Errorf("%s: %w", ue.Op, ue.Err) }
+	if err != nil { return err }
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode >= 300 {
+		err := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return &PermanentError{err}
+		}
+		return err
+	}
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil { return fmt.Errorf("decode response: %w", err) }
+	}
+	return nil
+}
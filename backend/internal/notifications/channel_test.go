@@ -0,0 +1,104 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// recorder is a stand-in API that records the last request and answers with status and reply
+type recorder struct {
+	status int
+	reply  string
+	path   string
+	body   map[string]interface{}
+}
+
+func (r *recorder) serve(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		r.path = req.URL.Path
+		r.body = nil
+		if err := json.NewDecoder(req.Body).Decode(&r.body); err != nil { t.Errorf("request body: %v", err) }
+		if ct := req.Header.Get("Content-Type"); ct != "application/json" { t.Errorf("Content-Type = %q", ct) }
+		w.WriteHeader(r.status)
+		w.Write([]byte(r.reply))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSignalClientSend(t *testing.T) {
+	rec := &recorder{status: http.StatusCreated, reply: `{"timestamp":"1"}`}
+	srv := rec.serve(t)
+	c := &SignalClient{BaseURL: srv.URL + "/", Number: "+15550001111", HTTP: srv.Client()}
+
+	if err := c.Send(context.Background(), "+15552223333", Message{Title: "Food is over budget", Body: "Spent 120"}); err != nil { t.Fatal(err) }
+	if rec.path != "/v2/send" { t.Errorf("path = %s, want /v2/send", rec.path) }
+	if rec.body["number"] != "+15550001111" || rec.body["message"] != "Food is over budget\n\nSpent 120" { t.Errorf("body = %v", rec.body) }
+	if r, _ := rec.body["recipients"].([]interface{}); len(r) != 1 || r[0] != "+15552223333" { t.Errorf("recipients = %v", rec.body["recipients"]) }
+
+	statuses := []struct {
+		status    int
+		permanent bool
+	}{
+		{http.StatusBadRequest, true},
+		{http.StatusNotFound, true},
+		{http.StatusRequestTimeout, false},
+		{http.StatusTooManyRequests, false},
+		{http.StatusInternalServerError, false},
+		{http.StatusBadGateway, false},
+	}
+	for _, st := range statuses {
+		rec.status, rec.reply = st.status, `{"error":"nope"}`
+		err := c.Send(context.Background(), "+15552223333", Message{Title: "x"})
+		if err == nil { t.Errorf("status %d: no error", st.status); continue }
+		if IsPermanent(err) != st.permanent { t.Errorf("status %d: permanent = %v, want %v (%v)", st.status, IsPermanent(err), st.permanent, err) }
+		if !strings.Contains(err.Error(), "nope") { t.Errorf("status %d: error %q lacks the response body", st.status, err) }
+	}
+}
+
+func TestTelegramClientSend(t *testing.T) {
+	rec := &recorder{status: http.StatusOK, reply: `{"ok":true,"result":{}}`}
+	srv := rec.serve(t)
+	c := &TelegramClient{BaseURL: srv.URL, Token: "123:secret", HTTP: srv.Client()}
+
+	if err := c.Send(context.Background(), "-100200", Message{Title: "Rent due"}); err != nil { t.Fatal(err) }
+	if rec.path != "/bot123:secret/sendMessage" { t.Errorf("path = %s", rec.path) }
+	if rec.body["chat_id"] != "-100200" || rec.body["text"] != "Rent due" || rec.body["disable_web_page_preview"] != true { t.Errorf("body = %v", rec.body) }
+
+	rec.reply = `{"ok":false,"description":"chat not found"}`
+	if err := c.Send(context.Background(), "-100200", Message{Title: "x"}); err == nil || !strings.Contains(err.Error(), "chat not found") { t.Errorf("ok=false: err = %v", err) }
+
+	rec.status, rec.reply = http.StatusForbidden, `{"ok":false,"description":"bot was blocked by the user"}`
+	if err := c.Send(context.Background(), "-100200", Message{Title: "x"}); !IsPermanent(err) { t.Errorf("403: err = %v, want permanent", err) }
+
+	rec.status = http.StatusTooManyRequests
+	if err := c.Send(context.Background(), "-100200", Message{Title: "x"}); err == nil || IsPermanent(err) { t.Errorf("429: err = %v, want retryable", err) }
+
+	// Network errors must not leak the token that is part of the URL
+	srv.Close()
+	err := c.Send(context.Background(), "-100200", Message{Title: "x"})
+	if err == nil || IsPermanent(err) { t.Fatalf("closed server: err = %v, want retryable", err) }
+	if strings.Contains(err.Error(), "secret") { t.Errorf("error leaks the token: %v", err) }
+}
+
+func TestValidAddress(t *testing.T) {
+	tests := []struct {
+		channel, address string
+		ok               bool
+	}{
+		{"signal", "+15552223333", true},
+		{"signal", "group.YWJjZA==", true},
+		{"signal", "15552223333", false},
+		{"telegram", "-100200", true},
+		{"telegram", "@my_channel", true},
+		{"telegram", "@abc", false},
+		{"email", "a@example.com", false},
+	}
+	for _, tt := range tests {
+		if got := ValidAddress(tt.channel, tt.address); got != tt.ok { t.Errorf("ValidAddress(%s, %s) = %v", tt.channel, tt.address, got) }
+	}
+}
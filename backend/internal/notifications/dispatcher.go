@@ -0,0 +1,98 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// Dispatcher sends pending outbox deliveries through their channels
+type Dispatcher struct {
+	repo        *repository.NotificationRepository
+	channels    map[string]Channel
+	// MaxAttempts is how often a delivery is tried before it is marked failed
+	MaxAttempts int
+	// Backoff is the wait after the first failed attempt; it doubles per attempt up to MaxBackoff
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	// BatchSize bounds the deliveries claimed per pass
+	BatchSize   int
+}
+
+func NewDispatcher(repo *repository.NotificationRepository, channels map[string]Channel) *Dispatcher {
+	return &Dispatcher{repo: repo, channels: channels, MaxAttempts: 8, Backoff: 30 * time.Second, MaxBackoff: 6 * time.Hour, BatchSize: 50}
+}
+
+// Interval is how often the dispatcher polls the outbox (NOTIFY_INTERVAL, default 30s)
+func Interval() time.Duration { return durationFromEnv("NOTIFY_INTERVAL", 30*time.Second) }
+
+// Channels returns the names of the configured channels
+func (d *Dispatcher) Channels() []string {
+	names := make([]string, 0, len(d.channels))
+	for _, name := range []string{models.ChannelSignal, models.ChannelTelegram} {
+		if d.channels[name] != nil { names = append(names, name) }
+	}
+	return names
+}
+
+// Send delivers msg right away, bypassing the outbox (used to test a user's channel setting)
+func (d *Dispatcher) Send(ctx context.Context, channel, address string, msg Message) error {
+	ch := d.channels[channel]
+	if ch == nil { return &PermanentError{fmt.Errorf("channel %s is not configured", channel)} }
+	return ch.Send(ctx, address, msg)
+}
+
+// backoff returns the wait before the attempt after the given number of failed ones
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.Backoff
+	for i := 1; i < attempts && wait < d.MaxBackoff; i++ { wait *= 2 }
+	if wait > d.MaxBackoff { wait = d.MaxBackoff }
+	return wait
+}
+
+// DispatchDue sends the deliveries due at now and records each outcome; it returns how many were sent
+func (d *Dispatcher) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	// A claimed delivery is retried after the lease if this process dies mid-send
+	items, err := d.repo.ClaimDeliveries(now, 10*time.Minute, d.BatchSize)
+	if err != nil { return 0, err }
+	sent := 0
+	for _, it := range items {
+		// On shutdown, leave the rest claimed; they are retried when the lease expires
+		if ctx.Err() != nil { return sent, nil }
+		attempts := it.Attempts + 1
+		err := d.Send(ctx, it.Channel, it.Address, Message{Title: it.Notification.Title, Body: it.Notification.Body})
+		if err != nil && ctx.Err() != nil { return sent, nil }
+		if err == nil {
+			if err := d.repo.MarkSent(it.ID, attempts, time.Now()); err != nil { return sent, err }
+			sent++
+			continue
+		}
+		var next time.Time
+		if !IsPermanent(err) && attempts < d.MaxAttempts { next = time.Now().Add(d.backoff(attempts)) }
+		if next.IsZero() { log.Printf("notifications: delivery %s via %s failed for good: %v", it.ID, it.Channel, err) }
+		if err := d.repo.MarkAttemptFailed(it.ID, attempts, next, err.Error()); err != nil { return sent, err }
+	}
+	return sent, nil
+}
+
+// Run runs DispatchDue immediately and then every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := d.DispatchDue(ctx, time.Now()); err != nil {
+			log.Printf("notifications: dispatch pass failed: %v", err)
+		} else if n > 0 {
+			log.Printf("notifications: sent %d message(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+// fakeChannel fails with the queued errors, then succeeds
+type fakeChannel struct {
+	errs []error
+	sent []string
+}
+
+func (f *fakeChannel) Name() string { return models.ChannelTelegram }
+
+func (f *fakeChannel) Send(_ context.Context, address string, msg Message) error {
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return err
+	}
+	f.sent = append(f.sent, address+": "+msg.Text())
+	return nil
+}
+
+// queue stores a notification for a user with a Telegram channel, which queues one delivery
+func queue(t *testing.T, ch Channel) (*Dispatcher, *gorm.DB, string) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	if err := db.Create(&models.NotificationChannel{ID: "c1", UserID: "u1", Channel: models.ChannelTelegram, Address: "42", Enabled: true}).Error; err != nil { t.Fatal(err) }
+	n := models.Notification{UserID: "u1", Kind: models.NotificationBudgetThreshold, DedupeKey: "k", Title: "Over budget", Body: "Spent 120"}
+	if _, err := repository.NewSpendingRepository(db).Notify(&n); err != nil { t.Fatal(err) }
+	d := NewDispatcher(repository.NewNotificationRepository(db), map[string]Channel{models.ChannelTelegram: ch})
+	d.Backoff, d.MaxBackoff = time.Minute, 4*time.Minute
+	return d, db, n.ID
+}
+
+func delivery(t *testing.T, db *gorm.DB, notificationID string) models.NotificationDelivery {
+	t.Helper()
+	var d models.NotificationDelivery
+	if err := db.Where("notification_id = ?", notificationID).First(&d).Error; err != nil { t.Fatal(err) }
+	return d
+}
+
+func TestDispatchRetriesThenMarksSent(t *testing.T) {
+	ch := &fakeChannel{errs: []error{errors.New("502 Bad Gateway")}}
+	d, db, id := queue(t, ch)
+	ctx := context.Background()
+
+	before := time.Now()
+	if n, err := d.DispatchDue(ctx, before); err != nil || n != 0 { t.Fatalf("first pass = %d, %v", n, err) }
+	got := delivery(t, db, id)
+	if got.Status != models.DeliveryPending || got.Attempts != 1 || got.LastError != "502 Bad Gateway" { t.Fatalf("after a failure: %+v", got) }
+	if wait := got.NextAttemptAt.Sub(before); wait < time.Minute || wait > time.Minute+5*time.Second { t.Errorf("retry in %s, want the 1m backoff", wait) }
+
+	// Not due again before the backoff ends
+	if n, _ := d.DispatchDue(ctx, before.Add(30*time.Second)); n != 0 || len(ch.sent) != 0 { t.Fatalf("sent %d before the backoff ended", n) }
+
+	if n, err := d.DispatchDue(ctx, before.Add(2*time.Minute)); err != nil || n != 1 { t.Fatalf("retry pass = %d, %v", n, err) }
+	got = delivery(t, db, id)
+	if got.Status != models.DeliverySent || got.Attempts != 2 || got.LastError != "" || got.SentAt == nil { t.Errorf("after sending: %+v", got) }
+	if len(ch.sent) != 1 || ch.sent[0] != "42: Over budget\n\nSpent 120" { t.Errorf("sent = %q", ch.sent) }
+
+	// Sent deliveries are not claimed again
+	if n, _ := d.DispatchDue(ctx, before.Add(time.Hour)); n != 0 { t.Errorf("re-sent %d", n) }
+}
+
+func TestDispatchGivesUp(t *testing.T) {
+	t.Run("permanent error", func(t *testing.T) {
+		d, db, id := queue(t, &fakeChannel{errs: []error{&PermanentError{errors.New("403 Forbidden")}}})
+		if _, err := d.DispatchDue(context.Background(), time.Now()); err != nil { t.Fatal(err) }
+		if got := delivery(t, db, id); got.Status != models.DeliveryFailed || got.Attempts != 1 { t.Errorf("%+v", got) }
+	})
+	t.Run("max attempts", func(t *testing.T) {
+		fail := errors.New("timeout")
+		d, db, id := queue(t, &fakeChannel{errs: []error{fail, fail, fail}})
+		d.MaxAttempts = 3
+		at := time.Now()
+		for i := 0; i < 3; i++ {
+			if _, err := d.DispatchDue(context.Background(), at); err != nil { t.Fatal(err) }
+			at = at.Add(10 * time.Minute)
+		}
+		if got := delivery(t, db, id); got.Status != models.DeliveryFailed || got.Attempts != 3 || got.LastError != "timeout" { t.Errorf("%+v", got) }
+	})
+	t.Run("unconfigured channel", func(t *testing.T) {
+		d, db, id := queue(t, nil)
+		d.channels = map[string]Channel{}
+		if _, err := d.DispatchDue(context.Background(), time.Now()); err != nil { t.Fatal(err) }
+		if got := delivery(t, db, id); got.Status != models.DeliveryFailed { t.Errorf("%+v", got) }
+	})
+}
+
+func TestDispatcherBackoff(t *testing.T) {
+	d := &Dispatcher{Backoff: 30 * time.Second, MaxBackoff: 3 * time.Minute}
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 3 * time.Minute, 3 * time.Minute}
+	for i, w := range want {
+		if got := d.backoff(i + 1); got != w { t.Errorf("backoff(%d) = %s, want %s", i+1, got, w) }
+	}
+}
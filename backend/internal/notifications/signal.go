@@ -0,0 +1,24 @@
+package notifications
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"achieving-backend/internal/models"
+)
+
+// SignalClient sends through a signal-cli-rest-api instance (see devops/signal-api-install.sh)
+type SignalClient struct {
+	BaseURL string // e.g. http://localhost:8080
+	Number  string // the registered sender number
+	HTTP    *http.Client
+}
+
+func (c *SignalClient) Name() string { return models.ChannelSignal }
+
+// Send posts to /v2/send; address is a phone number or a group ID
+func (c *SignalClient) Send(ctx context.Context, address string, msg Message) error {
+	body := map[string]interface{}{"message": msg.Text(), "number": c.Number, "recipients": []string{address}}
+	return postJSON(ctx, c.HTTP, strings.TrimRight(c.BaseURL, "/")+"/v2/send", body, nil)
+}
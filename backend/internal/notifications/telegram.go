@@ -0,0 +1,35 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"achieving-backend/internal/models"
+)
+
+const telegramAPI = "https://api.telegram.org"
+
+// TelegramClient sends through the Telegram Bot API
+type TelegramClient struct {
+	BaseURL string // defaults to https://api.telegram.org
+	Token   string
+	HTTP    *http.Client
+}
+
+func (c *TelegramClient) Name() string { return models.ChannelTelegram }
+
+// Send calls sendMessage; address is a chat ID or @channel name the bot may post to
+func (c *TelegramClient) Send(ctx context.Context, address string, msg Message) error {
+	base := c.BaseURL
+	if base == "" { base = telegramAPI }
+	var resp struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	body := map[string]interface{}{"chat_id": address, "text": msg.Text(), "disable_web_page_preview": true}
+	if err := postJSON(ctx, c.HTTP, strings.TrimRight(base, "/")+"/bot"+c.Token+"/sendMessage", body, &resp); err != nil { return err }
+	if !resp.OK { return errors.New("telegram: " + resp.Description) }
+	return nil
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+var (
+	// ErrUnknownCategory is returned when a categorization rule targets a category the user does not have
+	ErrUnknownCategory = errors.New("unknown category")
+	// ErrUnknownRule is returned when a reorder references a rule the user does not have
+	ErrUnknownRule = errors.New("unknown categorization rule")
+)
+
+type CategorizationRepository struct {
+	db *gorm.DB
+}
+
+func NewCategorizationRepository(db *gorm.DB) *CategorizationRepository {
+	return &CategorizationRepository{db: db}
+}
+
+// List returns the user's rules in evaluation order
+func (r *CategorizationRepository) List(userID string) ([]models.CategorizationRule, error) {
+	var rules []models.CategorizationRule
+	if err := r.db.Where("user_id = ?", userID).Order("priority asc, created_at asc").Find(&rules).Error; err != nil { return nil, err }
+	return rules, nil
+}
+
+func (r *CategorizationRepository) Find(userID, id string) (*models.CategorizationRule, error) {
+	var rule models.CategorizationRule
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil { return nil, err }
+	return &rule, nil
+}
+
+// Create inserts the rule; without an explicit priority it goes after the user's existing rules
+func (r *CategorizationRepository) Create(rule *models.CategorizationRule, priority *int) error {
+	if err := checkCategory(r.db, rule.UserID, rule.Category); err != nil { return err }
+	rule.ID = uuid.NewString()
+	if priority != nil { rule.Priority = *priority } else {
+		var max *int
+		if err := r.db.Model(&models.CategorizationRule{}).Where("user_id = ?", rule.UserID).Select("MAX(priority)").Scan(&max).Error; err != nil { return err }
+		if max != nil { rule.Priority = *max + 1 }
+	}
+	// Select all columns so Active=false is not replaced by the column default
+	return r.db.Omit(clause.Associations).Select("*").Create(rule).Error
+}
+
+func (r *CategorizationRepository) Save(rule *models.CategorizationRule) error {
+	if err := checkCategory(r.db, rule.UserID, rule.Category); err != nil { return err }
+	return r.db.Omit(clause.Associations).Save(rule).Error
+}
+
+func (r *CategorizationRepository) Delete(userID, id string) (int64, error) {
+	res := r.db.Where("user_id = ?", userID).Delete(&models.CategorizationRule{}, "id = ?", id)
+	return res.RowsAffected, res.Error
+}
+
+// Reorder assigns priorities 0..n-1 to ids in the given order; rules not listed keep their
+// relative order after them
+func (r *CategorizationRepository) Reorder(userID string, ids []string) ([]models.CategorizationRule, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rules []models.CategorizationRule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ?", userID).Order("priority asc, created_at asc").Find(&rules).Error; err != nil { return err }
+		pos := map[string]int{}
+		for i, id := range ids {
+			if _, dup := pos[id]; dup { continue }
+			pos[id] = i
+		}
+		next := len(ids)
+		found := 0
+		for _, rule := range rules {
+			p, listed := pos[rule.ID]
+			if listed { found++ } else {
+				p = next
+				next++
+			}
+			if p == rule.Priority { continue }
+			if err := tx.Model(&models.CategorizationRule{}).Where("id = ?", rule.ID).Update("priority", p).Error; err != nil { return err }
+		}
+		if found != len(pos) { return ErrUnknownRule }
+		return nil
+	})
+	if err != nil { return nil, err }
+	return r.List(userID)
+}
+
+// activeCategorizationRules returns the rules that take part in matching, in evaluation order
+func activeCategorizationRules(db *gorm.DB, userID string) ([]models.CategorizationRule, error) {
+	var rules []models.CategorizationRule
+	if err := db.Where("user_id = ? AND active = ?", userID, true).Order("priority asc, created_at asc").Find(&rules).Error; err != nil { return nil, err }
+	return rules, nil
+}
+
+func checkCategory(db *gorm.DB, userID, name string) error {
+	var n int64
+	if err := db.Model(&models.Category{}).Where("user_id = ? AND name = ?", userID, name).Count(&n).Error; err != nil { return err }
+	if n == 0 { return ErrUnknownCategory }
+	return nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+var (
+	// ErrUnknownCounterparty is returned when a debt references a counterparty the user does not have
+	ErrUnknownCounterparty = errors.New("unknown counterparty")
+	// ErrCounterpartyInUse is returned when deleting a counterparty that still has debts
+	ErrCounterpartyInUse = errors.New("counterparty has debts")
+	// ErrCounterpartyNameTaken is returned when renaming onto another counterparty's name
+	ErrCounterpartyNameTaken = errors.New("counterparty name already exists")
+)
+
+type CounterpartyRepository struct {
+	db *gorm.DB
+}
+
+func NewCounterpartyRepository(db *gorm.DB) *CounterpartyRepository {
+	return &CounterpartyRepository{db: db}
+}
+
+func (r *CounterpartyRepository) List(userID string) ([]models.Counterparty, error) {
+	var items []models.Counterparty
+	if err := r.db.Where("user_id = ?", userID).Order("name asc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+func (r *CounterpartyRepository) Find(userID, id string) (*models.Counterparty, error) {
+	var item models.Counterparty
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&item).Error; err != nil { return nil, err }
+	return &item, nil
+}
+
+// Create adds a counterparty, or returns the existing one with the same name
+func (r *CounterpartyRepository) Create(userID, name, note string) (*models.Counterparty, error) {
+	id, err := resolveCounterparty(r.db, userID, "", name)
+	if err != nil { return nil, err }
+	if note != "" {
+		if err := r.db.Model(&models.Counterparty{}).Where("id = ?", id).Update("note", note).Error; err != nil { return nil, err }
+	}
+	return r.Find(userID, id)
+}
+
+// Update applies name/note changes; gorm.ErrRecordNotFound or ErrCounterpartyNameTaken on failure
+func (r *CounterpartyRepository) Update(userID, id string, updates map[string]interface{}) (*models.Counterparty, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var cp models.Counterparty
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&cp).Error; err != nil { return err }
+		if name, ok := updates["name"].(string); ok {
+			var n int64
+			if err := tx.Model(&models.Counterparty{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, id).Count(&n).Error; err != nil { return err }
+			if n > 0 { return ErrCounterpartyNameTaken }
+		}
+		return tx.Model(&cp).Updates(updates).Error
+	})
+	if err != nil { return nil, err }
+	return r.Find(userID, id)
+}
+
+// Delete removes a counterparty without debts; ErrCounterpartyInUse otherwise
+func (r *CounterpartyRepository) Delete(userID, id string) (int64, error) {
+	var rows int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var n int64
+		if err := tx.Model(&models.BorrowEntry{}).Where("user_id = ? AND counterparty_id = ?", userID, id).Count(&n).Error; err != nil { return err }
+		if n > 0 { return ErrCounterpartyInUse }
+		res := tx.Where("user_id = ?", userID).Delete(&models.Counterparty{}, "id = ?", id)
+		rows = res.RowsAffected
+		return res.Error
+	})
+	return rows, err
+}
+
+// Debts lists every debt with the counterparty across all months, oldest first, with derived balances
+func (r *CounterpartyRepository) Debts(userID, id string) ([]models.BorrowEntry, error) {
+	var items []models.BorrowEntry
+	err := r.db.Scopes(withBorrowBalances).Where("borrow_entries.user_id = ? AND borrow_entries.counterparty_id = ?", userID, id).
+		Order("borrow_entries.date asc, borrow_entries.created_at asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// Repayments lists the repayments of every debt with the counterparty, oldest first
+func (r *CounterpartyRepository) Repayments(userID, id string) ([]models.BorrowRepayment, error) {
+	var items []models.BorrowRepayment
+	err := r.db.Joins("JOIN borrow_entries b ON b.id = borrow_repayments.borrow_id").
+		Where("borrow_repayments.user_id = ? AND b.counterparty_id = ?", userID, id).
+		Order("borrow_repayments.date asc, borrow_repayments.created_at asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// resolveCounterparty returns the ID of the user's counterparty: id when given (checked for
+// ownership), otherwise the one named name, which is created when missing
+func resolveCounterparty(db *gorm.DB, userID, id, name string) (string, error) {
+	if id != "" {
+		var n int64
+		if err := db.Model(&models.Counterparty{}).Where("id = ? AND user_id = ?", id, userID).Count(&n).Error; err != nil { return "", err }
+		if n == 0 { return "", ErrUnknownCounterparty }
+		return id, nil
+	}
+	name = strings.TrimSpace(name)
+	if name == "" { return "", ErrUnknownCounterparty }
+	cp := models.Counterparty{ID: uuid.NewString(), UserID: userID, Name: name}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(&cp).Error; err != nil { return "", err }
+	var existing models.Counterparty
+	if err := db.Where("user_id = ? AND name = ?", userID, name).First(&existing).Error; err != nil { return "", err }
+	return existing.ID, nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+// EnvelopeRow is a month's plan (Day empty), spending on one day, or net transfers (Day empty) of a
+// category in one currency
+type EnvelopeRow struct {
+	MonthKey    string
+	Category    string
+	Currency    string
+	Day         string
+	Planned     models.Money
+	Spent       models.Money
+	TransferIn  models.Money
+	TransferOut models.Money
+}
+
+type EnvelopeRepository struct {
+	db *gorm.DB
+}
+
+func NewEnvelopeRepository(db *gorm.DB) *EnvelopeRepository {
+	return &EnvelopeRepository{db: db}
+}
+
+// HasMonth reports whether the user has the month
+func (r *EnvelopeRepository) HasMonth(userID, monthKey string) (bool, error) {
+	var n int64
+	err := r.db.Model(&models.Month{}).Where("user_id = ? AND month_key = ?", userID, monthKey).Count(&n).Error
+	return n > 0, err
+}
+
+// Rows returns plans, per-day spending and transfers of every month up to and including upTo, oldest first
+func (r *EnvelopeRepository) Rows(userID, upTo string) ([]EnvelopeRow, error) {
+	var rows []EnvelopeRow
+	err := r.db.Raw(`SELECT month_key, category, currency, '' AS day, SUM(planned_amount) AS planned, 0 AS spent, 0 AS transfer_in, 0 AS transfer_out
+			FROM plans WHERE user_id = @user AND month_key <= @upTo GROUP BY month_key, category, currency
+		UNION ALL
+		SELECT month_key, category, currency, DATE_FORMAT(date, '%Y-%m-%d') AS day, 0, SUM(amount), 0, 0
+			FROM spending_entries WHERE user_id = @user AND month_key <= @upTo GROUP BY month_key, category, currency, day
+		UNION ALL
+		SELECT month_key, to_category, currency, '', 0, 0, SUM(amount), 0
+			FROM envelope_transfers WHERE user_id = @user AND month_key <= @upTo GROUP BY month_key, to_category, currency
+		UNION ALL
+		SELECT month_key, from_category, currency, '', 0, 0, 0, SUM(amount)
+			FROM envelope_transfers WHERE user_id = @user AND month_key <= @upTo GROUP BY month_key, from_category, currency
+		ORDER BY month_key ASC, category ASC`, sql.Named("user", userID), sql.Named("upTo", upTo)).Scan(&rows).Error
+	if err != nil { return nil, err }
+	return rows, nil
+}
+
+func (r *EnvelopeRepository) ListTransfers(userID, monthKey string) ([]models.EnvelopeTransfer, error) {
+	var items []models.EnvelopeTransfer
+	q := r.db.Where("user_id = ?", userID).Order("month_key desc, created_at asc")
+	if monthKey != "" { q = q.Where("month_key = ?", monthKey) }
+	if err := q.Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+// CreateTransfer records a transfer between two of the user's categories, creating its month when missing
+func (r *EnvelopeRepository) CreateTransfer(t *models.EnvelopeTransfer) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkCategory(tx, t.UserID, t.FromCategory); err != nil { return err }
+		if err := checkCategory(tx, t.UserID, t.ToCategory); err != nil { return err }
+		if err := NewSpendingRepository(tx).EnsureMonth(t.UserID, t.MonthKey); err != nil { return err }
+		t.ID = uuid.NewString()
+		t.Currency = currencyOr(tx, t.UserID, t.Currency)
+		return tx.Omit(clause.Associations).Create(t).Error
+	})
+}
+
+func (r *EnvelopeRepository) DeleteTransfer(userID, id string) (int64, error) {
+	res := r.db.Where("user_id = ?", userID).Delete(&models.EnvelopeTransfer{}, "id = ?", id)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+// Bundle is the full per-user data set moved by export and restore
+type Bundle struct {
+	Months               []models.Month               `json:"months"`
+	Categories           []models.Category            `json:"categories"`
+	Plans                []models.Plan                `json:"plans"`
+	EnvelopeTransfers    []models.EnvelopeTransfer    `json:"envelopeTransfers"`
+	Spending             []models.SpendingEntry       `json:"spending"`
+	Earnings             []models.EarningEntry        `json:"earnings"`
+	Counterparties       []models.Counterparty        `json:"counterparties"`
+	Borrows              []models.BorrowEntry         `json:"borrows"`
+	BorrowRepayments     []models.BorrowRepayment     `json:"borrowRepayments"`
+	Goals                []models.Goal                `json:"goals"`
+	GoalContributions    []models.GoalContribution    `json:"goalContributions"`
+	RecurringRules       []models.RecurringRule       `json:"recurringRules"`
+	CategorizationRules  []models.CategorizationRule  `json:"categorizationRules"`
+	PlanTemplates        []models.PlanTemplate        `json:"planTemplates"`
+	NotificationChannels []models.NotificationChannel `json:"notificationChannels"`
+}
+
+type ExportRepository struct {
+	db *gorm.DB
+}
+
+func NewExportRepository(db *gorm.DB) *ExportRepository {
+	return &ExportRepository{db: db}
+}
+
+func (r *ExportRepository) LoadBundle(userID string) (*Bundle, error) {
+	var b Bundle
+	steps := []struct {
+		dest  interface{}
+		order string
+	}{
+		{&b.Months, "month_key asc"},
+		{&b.Categories, "name asc"},
+		{&b.Plans, "month_key asc, category asc"},
+		{&b.EnvelopeTransfers, "month_key asc, created_at asc"},
+		{&b.Spending, "date asc"},
+		{&b.Earnings, "date asc"},
+		{&b.Counterparties, "name asc"},
+		{&b.BorrowRepayments, "date asc"},
+		{&b.Goals, "created_at asc"},
+		{&b.GoalContributions, "date asc"},
+		{&b.RecurringRules, "created_at asc"},
+		{&b.CategorizationRules, "priority asc, created_at asc"},
+		{&b.NotificationChannels, "channel asc"},
+	}
+	for _, st := range steps {
+		if err := r.db.Where("user_id = ?", userID).Order(st.order).Find(st.dest).Error; err != nil { return nil, err }
+	}
+	if err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("category asc") }).Where("user_id = ?", userID).Order("name asc").Find(&b.PlanTemplates).Error; err != nil { return nil, err }
+	// Borrows carry their derived repayment totals
+	if err := r.db.Scopes(withBorrowBalances).Where("borrow_entries.user_id = ?", userID).Order("borrow_entries.date asc").Find(&b.Borrows).Error; err != nil { return nil, err }
+	return &b, nil
+}
+
+// ExistingKeys returns the user's existing month keys, category names and goal IDs
+func (r *ExportRepository) ExistingKeys(userID string) (months, categories, goals []string, err error) {
+	if err = r.db.Model(&models.Month{}).Where("user_id = ?", userID).Pluck("month_key", &months).Error; err != nil { return }
+	if err = r.db.Model(&models.Category{}).Where("user_id = ?", userID).Pluck("name", &categories).Error; err != nil { return }
+	err = r.db.Model(&models.Goal{}).Where("user_id = ?", userID).Pluck("id", &goals).Error
+	return
+}
+
+// RestoreID maps a bundle row ID to a stable ID in the target account, so restoring
+// the same bundle twice hits the same rows and bundles can move between instances
+// (or accounts) without primary key collisions.
+func RestoreID(userID, id string) string {
+	return uuid.NewSHA1(uuid.NameSpaceOID, []byte(userID+":"+id)).String()
+}
+
+// RestoreBundle inserts every bundle row for userID in one transaction; rows that already exist are left untouched
+func (r *ExportRepository) RestoreBundle(userID string, b *Bundle) error {
+	// Bundles exported before currencies existed are in the user's base currency
+	cur := baseCurrencyOf(r.db, userID)
+	orBase := func(c *string) { if *c == "" { *c = cur } }
+	for i := range b.Months { b.Months[i].UserID = userID }
+	for i := range b.Categories { b.Categories[i].UserID = userID }
+	for i := range b.Plans { b.Plans[i].UserID = userID; b.Plans[i].ID = RestoreID(userID, b.Plans[i].ID); orBase(&b.Plans[i].Currency) }
+	for i := range b.EnvelopeTransfers { b.EnvelopeTransfers[i].UserID = userID; b.EnvelopeTransfers[i].ID = RestoreID(userID, b.EnvelopeTransfers[i].ID); orBase(&b.EnvelopeTransfers[i].Currency) }
+	for i := range b.Spending { b.Spending[i].UserID = userID; b.Spending[i].ID = RestoreID(userID, b.Spending[i].ID); orBase(&b.Spending[i].Currency) }
+	for i := range b.Earnings { b.Earnings[i].UserID = userID; b.Earnings[i].ID = RestoreID(userID, b.Earnings[i].ID); orBase(&b.Earnings[i].Currency) }
+	// Bundles exported before the repayment ledger only carry each borrow's repaid total
+	hasLedger := map[string]bool{}
+	for _, rp := range b.BorrowRepayments { hasLedger[rp.BorrowID] = true }
+	for _, bw := range b.Borrows {
+		if hasLedger[bw.ID] || bw.RepaidAmount == 0 { continue }
+		d := bw.Date
+		if bw.RepaidDate != nil { d = *bw.RepaidDate }
+		b.BorrowRepayments = append(b.BorrowRepayments, models.BorrowRepayment{ID: bw.ID + ":repaid", BorrowID: bw.ID, Amount: bw.RepaidAmount, Date: d})
+	}
+	// Counterparties are matched by name, so restoring next to existing ones does not duplicate them
+	var existing []models.Counterparty
+	if err := r.db.Where("user_id = ?", userID).Find(&existing).Error; err != nil { return err }
+	byName := map[string]string{}
+	for _, cp := range existing { byName[strings.ToLower(cp.Name)] = cp.ID }
+	cpIDs := map[string]string{}
+	for i := range b.Counterparties {
+		cp := &b.Counterparties[i]
+		id, ok := byName[strings.ToLower(cp.Name)]
+		if !ok { id = RestoreID(userID, cp.ID); byName[strings.ToLower(cp.Name)] = id }
+		cpIDs[cp.ID] = id
+		cp.ID = id
+		cp.UserID = userID
+	}
+	for i := range b.Borrows {
+		bw := &b.Borrows[i]
+		bw.UserID = userID
+		bw.ID = RestoreID(userID, bw.ID)
+		orBase(&bw.Currency)
+		if bw.Direction == "" { bw.Direction = models.DirectionBorrowed }
+		if id, ok := cpIDs[bw.CounterpartyID]; ok { bw.CounterpartyID = id; continue }
+		// Bundles exported before counterparties only name them in from
+		name := strings.TrimSpace(bw.From)
+		id, ok := byName[strings.ToLower(name)]
+		if !ok {
+			id = RestoreID(userID, "counterparty:"+strings.ToLower(name))
+			byName[strings.ToLower(name)] = id
+			b.Counterparties = append(b.Counterparties, models.Counterparty{ID: id, UserID: userID, Name: name})
+		}
+		bw.CounterpartyID = id
+	}
+	for i := range b.BorrowRepayments {
+		rp := &b.BorrowRepayments[i]
+		rp.UserID = userID
+		rp.ID = RestoreID(userID, rp.ID)
+		rp.BorrowID = RestoreID(userID, rp.BorrowID)
+	}
+	for i := range b.Goals { b.Goals[i].UserID = userID; b.Goals[i].ID = RestoreID(userID, b.Goals[i].ID); orBase(&b.Goals[i].Currency) }
+	for i := range b.GoalContributions {
+		gc := &b.GoalContributions[i]
+		gc.UserID = userID
+		gc.ID = RestoreID(userID, gc.ID)
+		gc.GoalID = RestoreID(userID, gc.GoalID)
+		orBase(&gc.Currency)
+	}
+	for i := range b.RecurringRules { b.RecurringRules[i].UserID = userID; b.RecurringRules[i].ID = RestoreID(userID, b.RecurringRules[i].ID); orBase(&b.RecurringRules[i].Currency) }
+	for i := range b.CategorizationRules { b.CategorizationRules[i].UserID = userID; b.CategorizationRules[i].ID = RestoreID(userID, b.CategorizationRules[i].ID) }
+	for i := range b.NotificationChannels { b.NotificationChannels[i].UserID = userID; b.NotificationChannels[i].ID = RestoreID(userID, b.NotificationChannels[i].ID) }
+	// Templates are matched by name like counterparties; their items are inserted on their own
+	var templates []models.PlanTemplate
+	if err := r.db.Where("user_id = ?", userID).Find(&templates).Error; err != nil { return err }
+	templateIDs := map[string]string{}
+	for _, t := range templates { templateIDs[strings.ToLower(t.Name)] = t.ID }
+	var items []models.PlanTemplateItem
+	for i := range b.PlanTemplates {
+		t := &b.PlanTemplates[i]
+		id, ok := templateIDs[strings.ToLower(t.Name)]
+		if !ok { id = RestoreID(userID, t.ID); templateIDs[strings.ToLower(t.Name)] = id }
+		t.ID, t.UserID = id, userID
+		for _, it := range t.Items {
+			it.ID, it.TemplateID, it.UserID = RestoreID(userID, it.ID), id, userID
+			orBase(&it.Currency)
+			items = append(items, it)
+		}
+	}
+	// Create replaces false booleans with their default (true), so note which rows are off first
+	var inactiveRules, inactiveCategorization, disabledChannels []string
+	for _, rr := range b.RecurringRules { if !rr.Active { inactiveRules = append(inactiveRules, rr.ID) } }
+	for _, cr := range b.CategorizationRules { if !cr.Active { inactiveCategorization = append(inactiveCategorization, cr.ID) } }
+	for _, ch := range b.NotificationChannels { if !ch.Enabled { disabledChannels = append(disabledChannels, ch.ID) } }
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ins := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations)
+		// Parents first so month and goal foreign keys resolve
+		batches := []interface{}{&b.Months, &b.Categories, &b.Plans, &b.EnvelopeTransfers, &b.Spending, &b.Earnings, &b.Counterparties, &b.Borrows, &b.BorrowRepayments, &b.Goals, &b.GoalContributions,
+			&b.PlanTemplates, &items, &b.RecurringRules, &b.CategorizationRules, &b.NotificationChannels}
+		for _, batch := range batches {
+			if reflect.ValueOf(batch).Elem().Len() == 0 { continue }
+			if err := ins.CreateInBatches(batch, 500).Error; err != nil { return err }
+		}
+		// Switch the rows noted above back off
+		off := []struct {
+			model  interface{}
+			column string
+			ids    []string
+		}{
+			{&models.RecurringRule{}, "active", inactiveRules},
+			{&models.CategorizationRule{}, "active", inactiveCategorization},
+			{&models.NotificationChannel{}, "enabled", disabledChannels},
+		}
+		for _, o := range off {
+			if len(o.ids) == 0 { continue }
+			if err := tx.Model(o.model).Where("user_id = ? AND id IN ?", userID, o.ids).Update(o.column, false).Error; err != nil { return err }
+		}
+		return nil
+	})
+}
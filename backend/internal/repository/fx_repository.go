@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+type FxRepository struct {
+	db *gorm.DB
+}
+
+func NewFxRepository(db *gorm.DB) *FxRepository {
+	return &FxRepository{db: db}
+}
+
+// UpsertRates inserts rates, replacing the rate of an existing (base, quote, date)
+func (r *FxRepository) UpsertRates(rates []models.FxRate) error {
+	if len(rates) == 0 { return nil }
+	for i := range rates {
+		if rates[i].ID == "" { rates[i].ID = uuid.NewString() }
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "base"}, {Name: "quote"}, {Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"rate", "source"}),
+	}).CreateInBatches(&rates, 500).Error
+}
+
+func (r *FxRepository) ListRates(base, quote string, from, to *time.Time) ([]models.FxRate, error) {
+	var rates []models.FxRate
+	q := r.db.Order("date desc, base asc, quote asc")
+	if base != "" { q = q.Where("base = ?", base) }
+	if quote != "" { q = q.Where("quote = ?", quote) }
+	if from != nil { q = q.Where("date >= ?", *from) }
+	if to != nil { q = q.Where("date <= ?", *to) }
+	if err := q.Limit(1000).Find(&rates).Error; err != nil { return nil, err }
+	return rates, nil
+}
+
+// LatestRate returns the most recent base->quote rate on or before date
+func (r *FxRepository) LatestRate(base, quote string, date time.Time) (*models.FxRate, error) {
+	var rate models.FxRate
+	err := r.db.Where("base = ? AND quote = ? AND date <= ?", base, quote, date).Order("date desc").First(&rate).Error
+	if err != nil { return nil, err }
+	return &rate, nil
+}
+
+// BaseCurrency returns the user's base currency, defaulting to USD
+func (r *FxRepository) BaseCurrency(userID string) string {
+	return baseCurrencyOf(r.db, userID)
+}
+
+func baseCurrencyOf(db *gorm.DB, userID string) string {
+	var cur string
+	db.Model(&models.User{}).Where("id = ?", userID).Pluck("base_currency", &cur)
+	if cur == "" { return "USD" }
+	return cur
+}
+
+// currencyOr returns cur, or the user's base currency when cur is empty
+func currencyOr(db *gorm.DB, userID, cur string) string {
+	if cur != "" { return cur }
+	return baseCurrencyOf(db, userID)
+}
@@ -1,10 +1,12 @@
 package repository
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"achieving-backend/internal/models"
 )
@@ -23,7 +25,12 @@ func (r *GoalRepository) ListGoals(userID string) ([]models.Goal, error) {
 	return goals, nil
 }
 
-func (r *GoalRepository) CreateGoal(userID, title, description, category string, saveFrequency string, duration *int, startDate, endDate, targetDate *time.Time, targetAmount *float64) (*models.Goal, error) {
+// ScheduleFunc builds a goal's installments from its saved state
+type ScheduleFunc func(g *models.Goal) ([]models.GoalInstallment, error)
+
+// CreateGoal saves a goal, its schedule and its opening balance, if any, as the first ledger entry in
+// one transaction, so the goal never exists without the schedule and progress it was created with
+func (r *GoalRepository) CreateGoal(userID, title, description, category string, saveFrequency string, duration *int, startDate, endDate, targetDate *time.Time, targetAmount *models.Money, currency string, opening models.Money, schedule ScheduleFunc) (*models.Goal, error) {
 	g := models.Goal{
 		ID:            uuid.NewString(),
 		UserID:        userID,
@@ -36,15 +43,59 @@ func (r *GoalRepository) CreateGoal(userID, title, description, category string,
 		EndDate:       endDate,
 		TargetDate:    targetDate,
 		TargetAmount:  targetAmount,
-		Status:        "active",
+		Currency:      currencyOr(r.db, userID, currency),
 	}
-	if err := r.db.Create(&g).Error; err != nil { return nil, err }
+	g.Status = g.ProgressStatus(0)
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&g).Error; err != nil { return err }
+		if schedule != nil {
+			items, err := schedule(&g)
+			if err != nil { return err }
+			if err := replaceSchedule(tx, &g, items); err != nil { return err }
+		}
+		if opening == 0 { return nil }
+		item := models.GoalContribution{ID: uuid.NewString(), GoalID: g.ID, UserID: userID, Amount: opening, Currency: g.Currency, Date: time.Now(), Note: "Opening balance"}
+		if err := tx.Create(&item).Error; err != nil { return err }
+		return recomputeGoalProgress(tx, &g)
+	})
+	if err != nil { return nil, err }
 	return &g, nil
 }
 
-func (r *GoalRepository) UpdateGoal(userID, id string, updates map[string]interface{}) (int64, error) {
-	res := r.db.Model(&models.Goal{}).Where("id = ? AND user_id = ?", id, userID).Updates(updates)
-	return res.RowsAffected, res.Error
+// UpdateGoal applies updates and re-derives the cached amount and status under the goal lock, so a
+// changed target moves the goal in or out of completed. A non-nil schedule rebuilds the installments
+// from the updated goal in the same transaction; its error rolls the update back.
+func (r *GoalRepository) UpdateGoal(userID, id string, updates map[string]interface{}, schedule ScheduleFunc) (int64, error) {
+	var rows int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if _, err := lockGoal(tx, userID, id); err != nil { return err }
+		res := tx.Model(&models.Goal{}).Where("id = ? AND user_id = ?", id, userID).Updates(updates)
+		if res.Error != nil { return res.Error }
+		rows = res.RowsAffected
+		// Reload for the updated target
+		g, err := lockGoal(tx, userID, id)
+		if err != nil { return err }
+		if schedule != nil {
+			items, err := schedule(g)
+			if err != nil { return err }
+			if err := replaceSchedule(tx, g, items); err != nil { return err }
+		}
+		return recomputeGoalProgress(tx, g)
+	})
+	if err == gorm.ErrRecordNotFound { return 0, nil }
+	return rows, err
+}
+
+// RecomputeProgress re-derives the goal's cached amount and status from the ledger and returns the goal
+func (r *GoalRepository) RecomputeProgress(userID, goalID string) (*models.Goal, error) {
+	var g *models.Goal
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if g, err = lockGoal(tx, userID, goalID); err != nil { return err }
+		return recomputeGoalProgress(tx, g)
+	})
+	if err != nil { return nil, err }
+	return g, nil
 }
 
 func (r *GoalRepository) FindGoal(userID, id string) (*models.Goal, error) {
@@ -56,4 +107,170 @@ func (r *GoalRepository) FindGoal(userID, id string) (*models.Goal, error) {
 func (r *GoalRepository) DeleteGoal(userID, id string) (int64, error) {
 	res := r.db.Where("user_id = ?", userID).Delete(&models.Goal{}, "id = ?", id)
 	return res.RowsAffected, res.Error
-}
\ No newline at end of file
+}
+
+func (r *GoalRepository) ListContributions(userID, goalID string) ([]models.GoalContribution, error) {
+	var items []models.GoalContribution
+	if err := r.db.Where("user_id = ? AND goal_id = ?", userID, goalID).Order("date desc, created_at desc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+// CreateContribution appends to the ledger and refreshes the goal's cached amount and status atomically.
+// The amount is in the goal's currency. Returns gorm.ErrRecordNotFound when the goal does not belong to the user.
+func (r *GoalRepository) CreateContribution(userID, goalID string, amount models.Money, date time.Time, note string) (*models.GoalContribution, error) {
+	item := models.GoalContribution{ID: uuid.NewString(), GoalID: goalID, UserID: userID, Amount: amount, Date: date, Note: note}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		g, err := lockGoal(tx, userID, goalID)
+		if err != nil { return err }
+		item.Currency = g.Currency
+		if err := tx.Create(&item).Error; err != nil { return err }
+		return recomputeGoalProgress(tx, g)
+	})
+	if err != nil { return nil, err }
+	return &item, nil
+}
+
+// AdjustToAmount records the difference between amount and the ledger total as one contribution,
+// reading the total under the goal lock so concurrent adjustments cannot both apply the full delta.
+// It returns nil when the total already equals amount.
+func (r *GoalRepository) AdjustToAmount(userID, goalID string, amount models.Money, date time.Time, note string) (*models.GoalContribution, error) {
+	var item *models.GoalContribution
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		g, err := lockGoal(tx, userID, goalID)
+		if err != nil { return err }
+		var total models.Money
+		if err := tx.Model(&models.GoalContribution{}).Where("goal_id = ?", goalID).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil { return err }
+		if amount == total { return nil }
+		item = &models.GoalContribution{ID: uuid.NewString(), GoalID: goalID, UserID: userID, Amount: amount - total, Currency: g.Currency, Date: date, Note: note}
+		if err := tx.Create(item).Error; err != nil { return err }
+		return recomputeGoalProgress(tx, g)
+	})
+	if err != nil { return nil, err }
+	return item, nil
+}
+
+// DeleteContribution removes a ledger row and refreshes the goal's cached amount and status atomically
+func (r *GoalRepository) DeleteContribution(userID, goalID, id string) (int64, error) {
+	var rows int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		g, err := lockGoal(tx, userID, goalID)
+		if err != nil { return err }
+		res := tx.Where("user_id = ? AND goal_id = ?", userID, goalID).Delete(&models.GoalContribution{}, "id = ?", id)
+		if res.Error != nil { return res.Error }
+		rows = res.RowsAffected
+		if rows == 0 { return nil }
+		return recomputeGoalProgress(tx, g)
+	})
+	return rows, err
+}
+
+// GoalDayAmount is the sum of a goal's contributions on one day
+type GoalDayAmount struct {
+	GoalID string
+	Day    string
+	Amount models.Money
+}
+
+// ContributionsByDay sums the user's contributions per goal and day, for converting progress at historical rates
+func (r *GoalRepository) ContributionsByDay(userID string) ([]GoalDayAmount, error) {
+	var rows []GoalDayAmount
+	err := r.db.Model(&models.GoalContribution{}).
+		Select("goal_id AS goal_id, DATE_FORMAT(date, '%Y-%m-%d') AS day, SUM(amount) AS amount").
+		Where("user_id = ?", userID).Group("goal_id, day").Scan(&rows).Error
+	return rows, err
+}
+
+// lockGoal loads the goal FOR UPDATE so concurrent ledger writes serialize per goal
+func lockGoal(tx *gorm.DB, userID, goalID string) (*models.Goal, error) {
+	var g models.Goal
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", goalID, userID).First(&g).Error; err != nil { return nil, err }
+	return &g, nil
+}
+
+// recomputeGoalProgress sums the ledger into goals.current_amount and derives the status
+func recomputeGoalProgress(tx *gorm.DB, g *models.Goal) error {
+	var total models.Money
+	if err := tx.Model(&models.GoalContribution{}).Where("goal_id = ?", g.ID).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error; err != nil { return err }
+	updates := map[string]interface{}{"current_amount": total, "status": g.ProgressStatus(total)}
+	if err := tx.Model(&models.Goal{}).Where("id = ?", g.ID).Updates(updates).Error; err != nil { return err }
+	g.CurrentAmount = &total
+	g.Status = updates["status"].(string)
+	if err := notifyGoalMilestones(tx, g, total); err != nil { return err }
+	return allocateInstallments(tx, g.ID, total)
+}
+
+// goalMilestones are the percentages of a goal's target that notify the user once reached
+var goalMilestones = []int{25, 50, 75, 100}
+
+// notifyGoalMilestones notifies each milestone the saved total has reached, once per goal
+func notifyGoalMilestones(tx *gorm.DB, g *models.Goal, total models.Money) error {
+	if g.TargetAmount == nil || *g.TargetAmount <= 0 { return nil }
+	pct := total.Ratio(*g.TargetAmount) * 100
+	for _, m := range goalMilestones {
+		if pct < float64(m) { break }
+		title := fmt.Sprintf("%s is %d%% saved", g.Title, m)
+		if m == 100 { title = fmt.Sprintf("Goal reached: %s", g.Title) }
+		n := models.Notification{
+			UserID:    g.UserID,
+			Kind:      models.NotificationGoalMilestone,
+			DedupeKey: fmt.Sprintf("goal:%s:%d", g.ID, m),
+			Title:     title,
+			Body:      fmt.Sprintf("Saved %s of %s %s.", total, *g.TargetAmount, g.Currency),
+		}
+		if _, err := createNotification(tx, &n); err != nil { return err }
+	}
+	return nil
+}
+
+func (r *GoalRepository) ListInstallments(userID, goalID string) ([]models.GoalInstallment, error) {
+	var items []models.GoalInstallment
+	if err := r.db.Where("user_id = ? AND goal_id = ?", userID, goalID).Order("sequence asc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+// ReplaceSchedule swaps the goal's installments for the given ones and allocates current progress to them
+func (r *GoalRepository) ReplaceSchedule(userID, goalID string, items []models.GoalInstallment) ([]models.GoalInstallment, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		g, err := lockGoal(tx, userID, goalID)
+		if err != nil { return err }
+		if err := replaceSchedule(tx, g, items); err != nil { return err }
+		var total models.Money
+		if g.CurrentAmount != nil { total = *g.CurrentAmount }
+		return allocateInstallments(tx, goalID, total)
+	})
+	if err != nil { return nil, err }
+	return r.ListInstallments(userID, goalID)
+}
+
+// replaceSchedule swaps the goal's installments for items; callers allocate progress afterwards
+func replaceSchedule(tx *gorm.DB, g *models.Goal, items []models.GoalInstallment) error {
+	if err := tx.Where("goal_id = ?", g.ID).Delete(&models.GoalInstallment{}).Error; err != nil { return err }
+	for i := range items {
+		items[i].ID = uuid.NewString()
+		items[i].GoalID = g.ID
+		items[i].UserID = g.UserID
+	}
+	if len(items) == 0 { return nil }
+	return tx.CreateInBatches(&items, 500).Error
+}
+
+// allocateInstallments fills installments in sequence order with the saved total
+func allocateInstallments(tx *gorm.DB, goalID string, total models.Money) error {
+	var items []models.GoalInstallment
+	if err := tx.Where("goal_id = ?", goalID).Order("sequence asc").Find(&items).Error; err != nil { return err }
+	remaining := total
+	now := time.Now()
+	for _, it := range items {
+		progress := remaining
+		if progress > it.PlannedAmount { progress = it.PlannedAmount }
+		if progress < 0 { progress = 0 }
+		remaining -= progress
+		completed := it.PlannedAmount > 0 && progress >= it.PlannedAmount
+		if progress == it.ProgressAmount && completed == it.Completed { continue }
+		updates := map[string]interface{}{"progress_amount": progress, "completed": completed}
+		if completed && it.CompletedAt == nil { updates["completed_at"] = now }
+		if !completed { updates["completed_at"] = nil }
+		if err := tx.Model(&models.GoalInstallment{}).Where("id = ?", it.ID).Updates(updates).Error; err != nil { return err }
+	}
+	return nil
+}
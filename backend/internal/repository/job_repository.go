@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+// EnqueueJob adds a job to the queue through db, which may be the transaction of the domain write
+// that causes it, so the job exists if and only if that write commits
+func EnqueueJob(db *gorm.DB, kind string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	data, err := json.Marshal(payload)
+	if err != nil { return nil, err }
+	job := models.Job{ID: uuid.NewString(), Kind: kind, Payload: string(data), Status: models.JobQueued, MaxAttempts: 5, RunAt: runAt}
+	if err := db.Create(&job).Error; err != nil { return nil, err }
+	return &job, nil
+}
+
+// JobCount is the number of jobs of a kind in a status
+type JobCount struct {
+	Kind   string `json:"kind"`
+	Status string `json:"status"`
+	Count  int64  `json:"count"`
+}
+
+type JobRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+func (r *JobRepository) Enqueue(kind string, payload interface{}, runAt time.Time) (*models.Job, error) {
+	return EnqueueJob(r.db, kind, payload, runAt)
+}
+
+// Claim locks the oldest job due at now (or abandoned by a worker whose lease expired), marks it
+// running for worker until now+lease and counts the attempt; nil when there is none
+func (r *JobRepository) Claim(worker string, now time.Time, lease time.Duration) (*models.Job, error) {
+	var job models.Job
+	found := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("(status = ? AND run_at <= ?) OR (status = ? AND locked_until < ?)", models.JobQueued, now, models.JobRunning, now).
+			Order("run_at asc").Limit(1).Find(&job).Error
+		if err != nil || job.ID == "" { return err }
+		found = true
+		until := now.Add(lease)
+		job.Status, job.LockedBy, job.LockedUntil, job.Attempts = models.JobRunning, worker, &until, job.Attempts+1
+		return tx.Model(&job).Select("status", "locked_by", "locked_until", "attempts").Updates(&job).Error
+	})
+	if err != nil || !found { return nil, err }
+	return &job, nil
+}
+
+// ErrLeaseLost is returned when a worker records the outcome of a job it no longer holds: its lease
+// expired and the job was claimed again (or finished) by another worker
+var ErrLeaseLost = errors.New("job lease lost")
+
+// Complete marks a job done
+func (r *JobRepository) Complete(id, worker string, at time.Time) error {
+	return r.finish(id, worker, map[string]interface{}{"status": models.JobDone, "finished_at": at, "locked_by": "", "locked_until": nil, "last_error": ""})
+}
+
+// Retry puts a failed job back in the queue to run at runAt
+func (r *JobRepository) Retry(id, worker string, runAt time.Time, reason string) error {
+	return r.finish(id, worker, map[string]interface{}{"status": models.JobQueued, "run_at": runAt, "locked_by": "", "locked_until": nil, "last_error": truncate(reason, 1024)})
+}
+
+// Bury dead-letters a failed job
+func (r *JobRepository) Bury(id, worker string, at time.Time, reason string) error {
+	return r.finish(id, worker, map[string]interface{}{"status": models.JobDead, "finished_at": at, "locked_by": "", "locked_until": nil, "last_error": truncate(reason, 1024)})
+}
+
+// finish applies the outcome of an attempt if worker still holds the job, and ErrLeaseLost otherwise
+func (r *JobRepository) finish(id, worker string, updates map[string]interface{}) error {
+	res := r.db.Model(&models.Job{}).Where("id = ? AND locked_by = ? AND status = ?", id, worker, models.JobRunning).Updates(updates)
+	if res.Error != nil { return res.Error }
+	if res.RowsAffected == 0 { return ErrLeaseLost }
+	return nil
+}
+
+// Requeue gives a dead job a fresh set of attempts; it returns 0 rows when the job is not dead
+func (r *JobRepository) Requeue(id string, now time.Time) (int64, error) {
+	res := r.db.Model(&models.Job{}).Where("id = ? AND status = ?", id, models.JobDead).
+		Updates(map[string]interface{}{"status": models.JobQueued, "attempts": 0, "run_at": now, "finished_at": nil})
+	return res.RowsAffected, res.Error
+}
+
+// Counts returns the number of jobs per kind and status
+func (r *JobRepository) Counts() ([]JobCount, error) {
+	var rows []JobCount
+	err := r.db.Model(&models.Job{}).Select("kind, status, COUNT(*) AS count").Group("kind, status").Order("kind, status").Scan(&rows).Error
+	if err != nil { return nil, err }
+	return rows, nil
+}
+
+// OldestQueued returns when the longest-waiting due job became due, nil when none is waiting
+func (r *JobRepository) OldestQueued(now time.Time) (*time.Time, error) {
+	var jobs []models.Job
+	err := r.db.Where("status = ? AND run_at <= ?", models.JobQueued, now).Order("run_at asc").Limit(1).Find(&jobs).Error
+	if err != nil || len(jobs) == 0 { return nil, err }
+	return &jobs[0].RunAt, nil
+}
+
+// List returns the newest jobs, filtered by status and kind when set
+func (r *JobRepository) List(status, kind string, limit int) ([]models.Job, error) {
+	var items []models.Job
+	q := r.db.Order("updated_at desc").Limit(limit)
+	if status != "" { q = q.Where("status = ?", status) }
+	if kind != "" { q = q.Where("kind = ?", kind) }
+	if err := q.Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n { return s[:n] }
+	return s
+}
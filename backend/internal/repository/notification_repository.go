@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository(db *gorm.DB) *NotificationRepository {
+	return &NotificationRepository{db: db}
+}
+
+// List returns the user's newest notifications first, only unread ones when unreadOnly
+func (r *NotificationRepository) List(userID string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var items []models.Notification
+	q := r.db.Where("user_id = ?", userID).Order("created_at desc").Limit(limit)
+	if unreadOnly { q = q.Where("read_at IS NULL") }
+	if err := q.Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+func (r *NotificationRepository) UnreadCount(userID string) (int64, error) {
+	var n int64
+	err := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&n).Error
+	return n, err
+}
+
+// SetRead marks one notification read (keeping the first read time) or unread
+func (r *NotificationRepository) SetRead(userID, id string, read bool) (*models.Notification, error) {
+	var n models.Notification
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&n).Error; err != nil { return nil, err }
+	if read == (n.ReadAt != nil) { return &n, nil }
+	var readAt *time.Time
+	if read {
+		now := time.Now()
+		readAt = &now
+	}
+	if err := r.db.Model(&n).Update("read_at", readAt).Error; err != nil { return nil, err }
+	n.ReadAt = readAt
+	return &n, nil
+}
+
+// MarkAllRead marks every unread notification of the user read
+func (r *NotificationRepository) MarkAllRead(userID string) (int64, error) {
+	res := r.db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Update("read_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+// createNotification inserts n unless the user already has a notification with its DedupeKey, and
+// queues its delivery to each of the user's enabled channels in the same transaction; it reports
+// whether n was created
+func createNotification(db *gorm.DB, n *models.Notification) (bool, error) {
+	created := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		n.ID = uuid.NewString()
+		res := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations).Create(n)
+		if res.Error != nil || res.RowsAffected == 0 { return res.Error }
+		created = true
+		var channels []models.NotificationChannel
+		if err := tx.Where("user_id = ? AND enabled = ?", n.UserID, true).Find(&channels).Error; err != nil { return err }
+		for _, ch := range channels {
+			d := models.NotificationDelivery{ID: uuid.NewString(), NotificationID: n.ID, UserID: n.UserID, Channel: ch.Channel, Address: ch.Address, Status: models.DeliveryPending, NextAttemptAt: time.Now()}
+			if err := tx.Omit(clause.Associations).Create(&d).Error; err != nil { return err }
+		}
+		return nil
+	})
+	return created, err
+}
+
+func (r *NotificationRepository) ListChannels(userID string) ([]models.NotificationChannel, error) {
+	var items []models.NotificationChannel
+	if err := r.db.Where("user_id = ?", userID).Order("channel asc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+func (r *NotificationRepository) FindChannel(userID, channel string) (*models.NotificationChannel, error) {
+	var ch models.NotificationChannel
+	if err := r.db.Where("user_id = ? AND channel = ?", userID, channel).First(&ch).Error; err != nil { return nil, err }
+	return &ch, nil
+}
+
+// SaveChannel creates or replaces the user's setting for a channel
+func (r *NotificationRepository) SaveChannel(userID, channel, address string, enabled bool) (*models.NotificationChannel, error) {
+	var ch models.NotificationChannel
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Where("user_id = ? AND channel = ?", userID, channel).First(&ch).Error
+		if err == gorm.ErrRecordNotFound {
+			ch = models.NotificationChannel{ID: uuid.NewString(), UserID: userID, Channel: channel, Address: address, Enabled: enabled}
+			return tx.Omit(clause.Associations).Select("*").Create(&ch).Error
+		}
+		if err != nil { return err }
+		ch.Address, ch.Enabled = address, enabled
+		return tx.Model(&ch).Select("address", "enabled").Updates(&ch).Error
+	})
+	if err != nil { return nil, err }
+	return &ch, nil
+}
+
+func (r *NotificationRepository) DeleteChannel(userID, channel string) (int64, error) {
+	res := r.db.Where("user_id = ? AND channel = ?", userID, channel).Delete(&models.NotificationChannel{})
+	return res.RowsAffected, res.Error
+}
+
+// Deliveries lists the outbox rows of one of the user's notifications
+func (r *NotificationRepository) Deliveries(userID, notificationID string) ([]models.NotificationDelivery, error) {
+	var items []models.NotificationDelivery
+	err := r.db.Where("user_id = ? AND notification_id = ?", userID, notificationID).Order("channel asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// ClaimDeliveries locks up to limit pending deliveries due at now, with their notifications, and pushes
+// their next attempt lease into the future so that concurrent dispatchers skip them
+func (r *NotificationRepository) ClaimDeliveries(now time.Time, lease time.Duration, limit int) ([]models.NotificationDelivery, error) {
+	var items []models.NotificationDelivery
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.DeliveryPending, now).
+			Order("next_attempt_at asc").Limit(limit).Find(&items).Error
+		if err != nil || len(items) == 0 { return err }
+		ids := make([]string, len(items))
+		for i := range items { ids[i] = items[i].ID }
+		if err := tx.Model(&models.NotificationDelivery{}).Where("id IN ?", ids).Update("next_attempt_at", now.Add(lease)).Error; err != nil { return err }
+		var notes []models.Notification
+		nids := make([]string, len(items))
+		for i := range items { nids[i] = items[i].NotificationID }
+		if err := tx.Where("id IN ?", nids).Find(&notes).Error; err != nil { return err }
+		byID := map[string]models.Notification{}
+		for _, n := range notes { byID[n.ID] = n }
+		for i := range items { items[i].Notification = byID[items[i].NotificationID] }
+		return nil
+	})
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// MarkSent records a successful attempt
+func (r *NotificationRepository) MarkSent(id string, attempts int, at time.Time) error {
+	return r.db.Model(&models.NotificationDelivery{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"status": models.DeliverySent, "attempts": attempts, "sent_at": at, "last_error": ""}).Error
+}
+
+// MarkAttemptFailed records a failed attempt; a zero next gives up and marks the delivery failed
+func (r *NotificationRepository) MarkAttemptFailed(id string, attempts int, next time.Time, reason string) error {
+	updates := map[string]interface{}{"attempts": attempts, "last_error": truncate(reason, 512)}
+	if next.IsZero() { updates["status"] = models.DeliveryFailed } else { updates["next_attempt_at"] = next }
+	return r.db.Model(&models.NotificationDelivery{}).Where("id = ?", id).Updates(updates).Error
+}
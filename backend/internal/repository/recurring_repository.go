@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+type RecurringRepository struct {
+	db *gorm.DB
+}
+
+func NewRecurringRepository(db *gorm.DB) *RecurringRepository {
+	return &RecurringRepository{db: db}
+}
+
+func (r *RecurringRepository) ListRules(userID string) ([]models.RecurringRule, error) {
+	var rules []models.RecurringRule
+	if err := r.db.Where("user_id = ?", userID).Order("created_at asc").Find(&rules).Error; err != nil { return nil, err }
+	return rules, nil
+}
+
+func (r *RecurringRepository) FindRule(userID, id string) (*models.RecurringRule, error) {
+	var rule models.RecurringRule
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil { return nil, err }
+	return &rule, nil
+}
+
+func (r *RecurringRepository) CreateRule(rule *models.RecurringRule) error {
+	rule.ID = uuid.NewString()
+	rule.Currency = currencyOr(r.db, rule.UserID, rule.Currency)
+	return r.db.Omit(clause.Associations).Create(rule).Error
+}
+
+func (r *RecurringRepository) SaveRule(rule *models.RecurringRule) error {
+	return r.db.Omit(clause.Associations).Save(rule).Error
+}
+
+func (r *RecurringRepository) DeleteRule(userID, id string) (int64, error) {
+	res := r.db.Where("user_id = ?", userID).Delete(&models.RecurringRule{}, "id = ?", id)
+	return res.RowsAffected, res.Error
+}
+
+// ListDueRuleIDs returns active rules of every user whose next run is on or before asOf
+func (r *RecurringRepository) ListDueRuleIDs(asOf time.Time) ([]string, error) {
+	var ids []string
+	err := r.db.Model(&models.RecurringRule{}).
+		Where("active = ? AND next_run_date IS NOT NULL AND next_run_date <= ?", true, asOf).
+		Order("next_run_date asc").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// Materialize creates the entry for the rule's current NextRunDate with create, which runs on a
+// repository bound to the transaction and returns the entry's ID, and advances it with next.
+// The rule row is locked and the occurrence key is unique, so concurrent schedulers and
+// retries after a crash never create the same occurrence twice. It returns false when the
+// rule is no longer due (already advanced by someone else, paused or deleted).
+func (r *RecurringRepository) Materialize(ruleID string, asOf time.Time, next func(rule *models.RecurringRule) *time.Time, create func(tx *SpendingRepository, rule *models.RecurringRule, occurrence time.Time) (string, error)) (bool, error) {
+	done := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var rule models.RecurringRule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", ruleID).First(&rule).Error; err != nil {
+			if err == gorm.ErrRecordNotFound { return nil }
+			return err
+		}
+		if !rule.Active || rule.NextRunDate == nil || rule.NextRunDate.After(asOf) { return nil }
+		occurrence := *rule.NextRunDate
+
+		var existing int64
+		if err := tx.Model(&models.RecurringOccurrence{}).Where("rule_id = ? AND occurrence_date = ?", rule.ID, occurrence).Count(&existing).Error; err != nil { return err }
+		if existing == 0 {
+			entryID, err := create(NewSpendingRepository(tx), &rule, occurrence)
+			if err != nil { return err }
+			occ := models.RecurringOccurrence{ID: uuid.NewString(), RuleID: rule.ID, OccurrenceDate: occurrence, EntryID: entryID}
+			if err := tx.Omit(clause.Associations).Create(&occ).Error; err != nil { return err }
+		}
+
+		updates := map[string]interface{}{"last_run_date": occurrence, "next_run_date": next(&rule)}
+		if updates["next_run_date"].(*time.Time) == nil { updates["active"] = false }
+		if err := tx.Model(&models.RecurringRule{}).Where("id = ?", rule.ID).Updates(updates).Error; err != nil { return err }
+		done = true
+		return nil
+	})
+	return done, err
+}
@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+)
+
+// MonthAmount is a per-month aggregate, optionally split by a group key (category or source),
+// in one currency and on one day so it can be converted at that day's rate
+type MonthAmount struct {
+	MonthKey string
+	GroupKey string
+	Currency string
+	Day      string
+	Amount   models.Money
+}
+
+// MonthPlanUsage is a planned category's plan (Day empty) or actual spending on one day, in one currency
+type MonthPlanUsage struct {
+	MonthKey string
+	Category string
+	Currency string
+	Day      string
+	Planned  models.Money
+	Actual   models.Money
+}
+
+type ReportRepository struct {
+	db *gorm.DB
+}
+
+func NewReportRepository(db *gorm.DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+// SpendingByMonth sums spending per month (and per category when byCategory) over [from, to]
+func (r *ReportRepository) SpendingByMonth(userID, from, to string, byCategory bool) ([]MonthAmount, error) {
+	return r.sumByMonth("spending_entries", "category", userID, from, to, byCategory)
+}
+
+// EarningsByMonth sums earnings per month (and per source when bySource) over [from, to]
+func (r *ReportRepository) EarningsByMonth(userID, from, to string, bySource bool) ([]MonthAmount, error) {
+	return r.sumByMonth("earning_entries", "source", userID, from, to, bySource)
+}
+
+// sumByMonth groups on (user_id, month_key) so the entry tables' month_key indexes serve the range scan
+func (r *ReportRepository) sumByMonth(table, groupCol, userID, from, to string, grouped bool) ([]MonthAmount, error) {
+	group := "''"
+	if grouped { group = "COALESCE(" + groupCol + ", '')" }
+	var rows []MonthAmount
+	err := r.db.Raw("SELECT month_key AS month_key, "+group+" AS group_key, currency, DATE_FORMAT(date, '%Y-%m-%d') AS day, SUM(amount) AS amount FROM "+table+
+		" WHERE user_id = @user AND month_key BETWEEN @from AND @to GROUP BY month_key, group_key, currency, day ORDER BY month_key ASC, group_key ASC",
+		sql.Named("user", userID), sql.Named("from", from), sql.Named("to", to)).Scan(&rows).Error
+	return rows, err
+}
+
+// PlanUsageByMonth returns plan rows and per-day actual spending of every planned category per month over [from, to]
+func (r *ReportRepository) PlanUsageByMonth(userID, from, to string) ([]MonthPlanUsage, error) {
+	var rows []MonthPlanUsage
+	err := r.db.Raw(`SELECT month_key, category, currency, '' AS day, planned_amount AS planned, 0 AS actual
+			FROM plans WHERE user_id = @user AND month_key BETWEEN @from AND @to AND planned_amount > 0
+		UNION ALL
+		SELECT s.month_key, s.category, s.currency, DATE_FORMAT(s.date, '%Y-%m-%d') AS day, 0 AS planned, SUM(s.amount) AS actual
+			FROM spending_entries s
+			JOIN plans p ON p.user_id = s.user_id AND p.month_key = s.month_key AND p.category = s.category AND p.planned_amount > 0
+			WHERE s.user_id = @user AND s.month_key BETWEEN @from AND @to
+			GROUP BY s.month_key, s.category, s.currency, day
+		ORDER BY month_key ASC, category ASC`,
+		sql.Named("user", userID), sql.Named("from", from), sql.Named("to", to)).Scan(&rows).Error
+	return rows, err
+}
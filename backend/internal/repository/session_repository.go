@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+)
+
+type SessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) *SessionRepository {
+	return &SessionRepository{db: db}
+}
+
+func (r *SessionRepository) CreateSession(userID, refreshTokenHash string, expiresAt time.Time) (*models.Session, error) {
+	s := models.Session{ID: uuid.NewString(), UserID: userID, RefreshTokenHash: refreshTokenHash, ExpiresAt: expiresAt}
+	if err := r.db.Create(&s).Error; err != nil { return nil, err }
+	return &s, nil
+}
+
+// FindActiveByRefreshHash returns the unrevoked, unexpired session owning the refresh token hash
+func (r *SessionRepository) FindActiveByRefreshHash(refreshTokenHash string) (*models.Session, error) {
+	var s models.Session
+	err := r.db.Preload("User").
+		Where("refresh_token_hash = ? AND revoked_at IS NULL AND expires_at > ?", refreshTokenHash, time.Now()).
+		First(&s).Error
+	if err != nil { return nil, err }
+	return &s, nil
+}
+
+// IsActive reports whether the session exists and has been neither revoked nor expired
+func (r *SessionRepository) IsActive(id string) (bool, error) {
+	var cnt int64
+	err := r.db.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL AND expires_at > ?", id, time.Now()).
+		Count(&cnt).Error
+	return cnt > 0, err
+}
+
+// RotateRefreshToken swaps the refresh token hash only if it still matches oldHash,
+// so two concurrent refreshes with the same token cannot both succeed
+func (r *SessionRepository) RotateRefreshToken(id, oldHash, newHash string, expiresAt time.Time) (int64, error) {
+	updates := map[string]interface{}{"refresh_token_hash": newHash, "previous_refresh_token_hash": oldHash, "expires_at": expiresAt, "last_used_at": time.Now()}
+	res := r.db.Model(&models.Session{}).
+		Where("id = ? AND refresh_token_hash = ? AND revoked_at IS NULL", id, oldHash).
+		Updates(updates)
+	return res.RowsAffected, res.Error
+}
+
+// RevokeByPreviousRefreshHash revokes the active session whose last rotated-away refresh token has
+// the given hash, i.e. the session whose old token is being replayed
+func (r *SessionRepository) RevokeByPreviousRefreshHash(refreshTokenHash string) (int64, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("previous_refresh_token_hash = ? AND revoked_at IS NULL", refreshTokenHash).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+func (r *SessionRepository) RevokeSession(userID, id string) (int64, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
+
+// RevokeOtherSessions revokes every active session of the user except keepID
+func (r *SessionRepository) RevokeOtherSessions(userID, keepID string) (int64, error) {
+	res := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, keepID).
+		Update("revoked_at", time.Now())
+	return res.RowsAffected, res.Error
+}
@@ -1,14 +1,34 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"achieving-backend/internal/models"
 )
 
+// ErrVersionConflict is returned when an entry changed since the client read it
+var ErrVersionConflict = errors.New("version conflict")
+
+// ErrRepaymentExceedsBalance is returned when a repayment is larger than the borrow's outstanding amount
+var ErrRepaymentExceedsBalance = errors.New("repayment exceeds outstanding balance")
+
+var (
+	ErrCategoryExists = errors.New("category already exists")
+	ErrCategoryInUse  = errors.New("category is in use")
+	ErrInvalidParent  = errors.New("parent category does not exist or would create a cycle")
+
+	// ErrPlanCurrencyConflict is returned when merging plans of the same month in different currencies
+	ErrPlanCurrencyConflict = errors.New("plans in different currencies")
+)
+
 type SpendingRepository struct {
 	db *gorm.DB
 }
@@ -17,6 +37,30 @@ func NewSpendingRepository(db *gorm.DB) *SpendingRepository {
 	return &SpendingRepository{db: db}
 }
 
+// Transaction runs fn with a repository bound to a single DB transaction
+func (r *SpendingRepository) Transaction(fn func(txRepo *SpendingRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error { return fn(&SpendingRepository{db: tx}) })
+}
+
+// CountDuplicateKeys counts the existing entries in the given months by their EntryKey, where text
+// is the spending note or the earning source
+func (r *SpendingRepository) CountDuplicateKeys(userID string, monthKeys []string) (map[string]int, error) {
+	keys := map[string]int{}
+	if len(monthKeys) == 0 { return keys, nil }
+	var spending []models.SpendingEntry
+	if err := r.db.Where("user_id = ? AND month_key IN ?", userID, monthKeys).Find(&spending).Error; err != nil { return nil, err }
+	for _, e := range spending { keys[EntryKey("spending", e.Date, e.Amount, e.Note)]++ }
+	var earnings []models.EarningEntry
+	if err := r.db.Where("user_id = ? AND month_key IN ?", userID, monthKeys).Find(&earnings).Error; err != nil { return nil, err }
+	for _, e := range earnings { keys[EntryKey("earning", e.Date, e.Amount, e.Source)]++ }
+	return keys, nil
+}
+
+// EntryKey is the identity used for duplicate detection on imports
+func EntryKey(kind string, date time.Time, amount models.Money, text string) string {
+	return fmt.Sprintf("%s|%s|%s|%s", kind, date.Format("2006-01-02"), amount, strings.ToLower(strings.TrimSpace(text)))
+}
+
 func (r *SpendingRepository) EnsureMonth(userID, monthKey string) error {
 	var m models.Month
 	if err := r.db.Where("user_id = ? AND month_key = ?", userID, monthKey).First(&m).Error; err != nil {
@@ -36,14 +80,57 @@ func (r *SpendingRepository) ListSpending(userID, monthKey string) ([]models.Spe
 	return entries, nil
 }
 
-func (r *SpendingRepository) CreateSpending(userID string, amount float64, category string, date time.Time, note string) (*models.SpendingEntry, error) {
+// CreateSpending records a spending entry; an empty currency means the user's base currency
+func (r *SpendingRepository) CreateSpending(userID string, amount models.Money, category string, date time.Time, note, merchant, currency string) (*models.SpendingEntry, error) {
 	mk := date.Format("2006-01")
 	_ = r.EnsureMonth(userID, mk)
-	entry := models.SpendingEntry{ID: uuid.NewString(), UserID: userID, Amount: amount, Category: category, Date: date, MonthKey: mk, Note: note}
+	entry := models.SpendingEntry{ID: uuid.NewString(), UserID: userID, Amount: amount, Currency: currencyOr(r.db, userID, currency), Category: category, Date: date, MonthKey: mk, Note: note, Merchant: merchant}
 	if err := r.db.Create(&entry).Error; err != nil { return nil, err }
 	return &entry, nil
 }
 
+// UpdateSpending applies a partial update if the entry is still at version; see updateEntry
+func (r *SpendingRepository) UpdateSpending(userID, id string, version int64, updates map[string]interface{}) (*models.SpendingEntry, error) {
+	var entry models.SpendingEntry
+	if err := r.updateEntry(&entry, userID, id, version, updates); err != nil { return nil, err }
+	return &entry, nil
+}
+
+// CurrencyOr returns cur, or the user's base currency when cur is empty
+func (r *SpendingRepository) CurrencyOr(userID, cur string) string { return currencyOr(r.db, userID, cur) }
+
+// CategorizationRules returns the user's active categorization rules in evaluation order
+func (r *SpendingRepository) CategorizationRules(userID string) ([]models.CategorizationRule, error) {
+	return activeCategorizationRules(r.db, userID)
+}
+
+// BudgetAlertThresholds returns the user's budget alert thresholds setting, e.g. "80,100"
+func (r *SpendingRepository) BudgetAlertThresholds(userID string) (string, error) {
+	var out []string
+	if err := r.db.Model(&models.User{}).Where("id = ?", userID).Pluck("budget_alert_thresholds", &out).Error; err != nil { return "", err }
+	if len(out) == 0 { return "", gorm.ErrRecordNotFound }
+	return out[0], nil
+}
+
+// Notify stores n unless the user was already notified of its DedupeKey; it reports whether n was created
+func (r *SpendingRepository) Notify(n *models.Notification) (bool, error) { return createNotification(r.db, n) }
+
+// Recategorize sets the category of each spending entry in changes (entry ID to new category) and
+// bumps its version. Entries whose category is no longer from[id] were edited meanwhile and are skipped.
+func (r *SpendingRepository) Recategorize(userID string, changes, from map[string]string) (int64, error) {
+	var total int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for id, category := range changes {
+			res := tx.Model(&models.SpendingEntry{}).Where("id = ? AND user_id = ? AND category = ?", id, userID, from[id]).
+				Updates(map[string]interface{}{"category": category, "version": gorm.Expr("version + 1")})
+			if res.Error != nil { return res.Error }
+			total += res.RowsAffected
+		}
+		return nil
+	})
+	return total, err
+}
+
 func (r *SpendingRepository) DeleteSpending(userID, id string) (int64, error) {
 	res := r.db.Where("user_id = ?", userID).Delete(&models.SpendingEntry{}, "id = ?", id)
 	return res.RowsAffected, res.Error
@@ -57,38 +144,214 @@ func (r *SpendingRepository) ListEarnings(userID, monthKey string) ([]models.Ear
 	return items, nil
 }
 
-func (r *SpendingRepository) CreateEarning(userID, source string, amount float64, date time.Time) (*models.EarningEntry, error) {
+func (r *SpendingRepository) CreateEarning(userID, source string, amount models.Money, date time.Time, currency string) (*models.EarningEntry, error) {
 	mk := date.Format("2006-01")
 	_ = r.EnsureMonth(userID, mk)
-	item := models.EarningEntry{ID: uuid.NewString(), UserID: userID, Source: source, Amount: amount, Date: date, MonthKey: mk}
+	item := models.EarningEntry{ID: uuid.NewString(), UserID: userID, Source: source, Amount: amount, Currency: currencyOr(r.db, userID, currency), Date: date, MonthKey: mk}
 	if err := r.db.Create(&item).Error; err != nil { return nil, err }
 	return &item, nil
 }
 
+func (r *SpendingRepository) UpdateEarning(userID, id string, version int64, updates map[string]interface{}) (*models.EarningEntry, error) {
+	var item models.EarningEntry
+	if err := r.updateEntry(&item, userID, id, version, updates); err != nil { return nil, err }
+	return &item, nil
+}
+
 func (r *SpendingRepository) DeleteEarning(userID, id string) (int64, error) {
 	res := r.db.Where("user_id = ?", userID).Delete(&models.EarningEntry{}, "id = ?", id)
 	return res.RowsAffected, res.Error
 }
 
-func (r *SpendingRepository) ListBorrows(userID, monthKey string) ([]models.BorrowEntry, error) {
+// withBorrowBalances selects borrow entries together with totals derived from borrow_repayments
+// and the counterparty name
+func withBorrowBalances(db *gorm.DB) *gorm.DB {
+	return db.Table("borrow_entries").
+		Select(`borrow_entries.*, COALESCE(r.repaid, 0) AS repaid_amount, r.last_date AS repaid_date,
+			GREATEST(borrow_entries.amount - COALESCE(r.repaid, 0), 0) AS outstanding,
+			COALESCE(r.repaid, 0) >= borrow_entries.amount AS settled, cp.name AS ` + "`from`").
+		Joins("LEFT JOIN (SELECT borrow_id, SUM(amount) AS repaid, MAX(date) AS last_date FROM borrow_repayments GROUP BY borrow_id) r ON r.borrow_id = borrow_entries.id").
+		Joins("LEFT JOIN counterparties cp ON cp.id = borrow_entries.counterparty_id")
+}
+
+// ListBorrows lists the user's debts; an empty monthKey spans all months, direction ("borrowed"
+// or "lent") and status ("outstanding" or "settled") filter when set
+func (r *SpendingRepository) ListBorrows(userID, monthKey, direction, status string) ([]models.BorrowEntry, error) {
 	var items []models.BorrowEntry
-	q := r.db.Where("user_id = ?", userID).Order("date desc")
-	if monthKey != "" { q = q.Where("month_key = ?", monthKey) }
+	q := r.db.Scopes(withBorrowBalances).Where("borrow_entries.user_id = ?", userID).Order("borrow_entries.date desc")
+	if monthKey != "" { q = q.Where("borrow_entries.month_key = ?", monthKey) }
+	if direction != "" { q = q.Where("borrow_entries.direction = ?", direction) }
+	switch status {
+	case "outstanding":
+		q = q.Where("COALESCE(r.repaid, 0) < borrow_entries.amount")
+	case "settled":
+		q = q.Where("COALESCE(r.repaid, 0) >= borrow_entries.amount")
+	}
 	if err := q.Find(&items).Error; err != nil { return nil, err }
 	return items, nil
 }
 
-func (r *SpendingRepository) CreateBorrow(userID, from string, amount float64, date time.Time) (*models.BorrowEntry, error) {
-	mk := date.Format("2006-01")
-	_ = r.EnsureMonth(userID, mk)
-	item := models.BorrowEntry{ID: uuid.NewString(), UserID: userID, From: from, Amount: amount, Date: date, MonthKey: mk}
-	if err := r.db.Create(&item).Error; err != nil { return nil, err }
+func (r *SpendingRepository) FindBorrow(userID, id string) (*models.BorrowEntry, error) {
+	var item models.BorrowEntry
+	if err := r.db.Scopes(withBorrowBalances).Where("borrow_entries.id = ? AND borrow_entries.user_id = ?", id, userID).First(&item).Error; err != nil { return nil, err }
+	return &item, nil
+}
+
+// CreateBorrow records item (amount, date, direction, currency and loan terms) as a debt with the
+// counterparty counterpartyID, or the one named from (created when missing); ErrUnknownCounterparty
+// when neither resolves. Empty direction, currency and compounding take their defaults.
+func (r *SpendingRepository) CreateBorrow(userID, counterpartyID, from string, item models.BorrowEntry) (*models.BorrowEntry, error) {
+	cpID, err := resolveCounterparty(r.db, userID, counterpartyID, from)
+	if err != nil { return nil, err }
+	if item.Direction == "" { item.Direction = models.DirectionBorrowed }
+	if item.Compounding == "" { item.Compounding = models.CompoundSimple }
+	item.ID, item.UserID, item.CounterpartyID = uuid.NewString(), userID, cpID
+	item.Currency = currencyOr(r.db, userID, item.Currency)
+	item.MonthKey = item.Date.Format("2006-01")
+	_ = r.EnsureMonth(userID, item.MonthKey)
+	if err := r.db.Omit(clause.Associations).Create(&item).Error; err != nil { return nil, err }
+	return r.FindBorrow(userID, item.ID)
+}
+
+// ListOverdue lists debts due before asOf that may still be owed (principal outstanding, or interest-bearing)
+func (r *SpendingRepository) ListOverdue(userID string, asOf time.Time) ([]models.BorrowEntry, error) {
+	var items []models.BorrowEntry
+	err := r.db.Scopes(withBorrowBalances).
+		Where("borrow_entries.user_id = ? AND borrow_entries.due_date < ?", userID, asOf.Format("2006-01-02")).
+		Where("COALESCE(r.repaid, 0) < borrow_entries.amount OR borrow_entries.interest_rate > 0").
+		Order("borrow_entries.due_date asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// ListDueBy lists every user's debts due on or before the given day that may still be owed, by user
+func (r *SpendingRepository) ListDueBy(day time.Time) ([]models.BorrowEntry, error) {
+	var items []models.BorrowEntry
+	err := r.db.Scopes(withBorrowBalances).
+		Where("borrow_entries.due_date IS NOT NULL AND borrow_entries.due_date <= ?", day.Format("2006-01-02")).
+		Where("COALESCE(r.repaid, 0) < borrow_entries.amount OR borrow_entries.interest_rate > 0").
+		Order("borrow_entries.user_id asc, borrow_entries.due_date asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// ListInterestBearing lists debts with a non-zero interest rate taken out before the given time
+func (r *SpendingRepository) ListInterestBearing(userID string, before time.Time) ([]models.BorrowEntry, error) {
+	var items []models.BorrowEntry
+	err := r.db.Scopes(withBorrowBalances).
+		Where("borrow_entries.user_id = ? AND borrow_entries.interest_rate > 0 AND borrow_entries.date < ?", userID, before).
+		Order("borrow_entries.date asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// RepaymentsFor returns the repayments of the given debts, oldest first
+func (r *SpendingRepository) RepaymentsFor(userID string, borrowIDs []string) ([]models.BorrowRepayment, error) {
+	var items []models.BorrowRepayment
+	if len(borrowIDs) == 0 { return items, nil }
+	if err := r.db.Where("user_id = ? AND borrow_id IN ?", userID, borrowIDs).Order("date asc, created_at asc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+// UpdateBorrow is updateEntry for debts; a "from" name or "counterparty_id" update is resolved like CreateBorrow.
+// A new "amount" or "interest_rate" is checked against the repayments under the borrow lock:
+// ErrRepaymentExceedsBalance is returned when an interest-free borrow would end up overpaid.
+func (r *SpendingRepository) UpdateBorrow(userID, id string, version int64, updates map[string]interface{}) (*models.BorrowEntry, error) {
+	from, byName := updates["from"].(string)
+	cpID, byID := updates["counterparty_id"].(string)
+	if byName || byID {
+		delete(updates, "from")
+		resolved, err := resolveCounterparty(r.db, userID, cpID, from)
+		if err != nil { return nil, err }
+		updates["counterparty_id"] = resolved
+	}
+	_, newAmount := updates["amount"]
+	_, newRate := updates["interest_rate"]
+	err := r.Transaction(func(tx *SpendingRepository) error {
+		if newAmount || newRate {
+			b, repaid, err := lockBorrow(tx.db, userID, id)
+			if err != nil { return err }
+			amount, rate := b.Amount, b.InterestRate
+			if v, ok := updates["amount"].(models.Money); ok { amount = v }
+			if v, ok := updates["interest_rate"].(float64); ok { rate = v }
+			if rate == 0 && repaid > amount { return ErrRepaymentExceedsBalance }
+		}
+		var item models.BorrowEntry
+		return tx.updateEntry(&item, userID, id, version, updates)
+	})
+	if err != nil { return nil, err }
+	return r.FindBorrow(userID, id)
+}
+
+// updateEntry locks the user's entry, applies updates when its version still matches and bumps the version.
+// A changed "date" moves the entry to the matching month, created in the same transaction.
+// dest receives the updated row; gorm.ErrRecordNotFound or ErrVersionConflict are returned on failure.
+func (r *SpendingRepository) updateEntry(dest interface{}, userID, id string, version int64, updates map[string]interface{}) error {
+	return r.Transaction(func(tx *SpendingRepository) error {
+		if err := tx.db.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(dest).Error; err != nil { return err }
+		if d, ok := updates["date"].(time.Time); ok {
+			mk := d.Format("2006-01")
+			if err := tx.EnsureMonth(userID, mk); err != nil { return err }
+			updates["month_key"] = mk
+		}
+		updates["version"] = gorm.Expr("version + 1")
+		res := tx.db.Model(dest).Where("version = ?", version).Updates(updates)
+		if res.Error != nil { return res.Error }
+		if res.RowsAffected == 0 { return ErrVersionConflict }
+		return tx.db.Where("id = ?", id).First(dest).Error
+	})
+}
+
+// lockBorrow loads the user's borrow FOR UPDATE and returns how much of it has been repaid
+func lockBorrow(tx *gorm.DB, userID, id string) (*models.BorrowEntry, models.Money, error) {
+	var b models.BorrowEntry
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&b).Error; err != nil { return nil, 0, err }
+	var repaid models.Money
+	if err := tx.Model(&models.BorrowRepayment{}).Where("borrow_id = ?", id).Select("COALESCE(SUM(amount), 0)").Scan(&repaid).Error; err != nil { return nil, 0, err }
+	return &b, repaid, nil
+}
+
+// UpdateBorrowRepayment sets the total repaid amount by recording the difference as a
+// "Balance adjustment" repayment; kept for clients of the former single repayment field.
+// Like CreateRepayment, it returns ErrRepaymentExceedsBalance when the total would overpay an
+// interest-free borrow; interest-bearing borrows are checked against the accrued amount by the caller.
+func (r *SpendingRepository) UpdateBorrowRepayment(userID, id string, repaidAmount models.Money, repaidDate time.Time) (int64, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		b, repaid, err := lockBorrow(tx, userID, id)
+		if err != nil { return err }
+		if repaidAmount == repaid { return nil }
+		if b.InterestRate == 0 && repaidAmount > b.Amount { return ErrRepaymentExceedsBalance }
+		item := models.BorrowRepayment{ID: uuid.NewString(), BorrowID: id, UserID: userID, Amount: repaidAmount - repaid, Date: repaidDate, Note: "Balance adjustment"}
+		return tx.Create(&item).Error
+	})
+	if err == gorm.ErrRecordNotFound { return 0, nil }
+	if err != nil { return 0, err }
+	return 1, nil
+}
+
+func (r *SpendingRepository) ListRepayments(userID, borrowID string) ([]models.BorrowRepayment, error) {
+	var items []models.BorrowRepayment
+	if err := r.db.Where("user_id = ? AND borrow_id = ?", userID, borrowID).Order("date desc, created_at desc").Find(&items).Error; err != nil { return nil, err }
+	return items, nil
+}
+
+// CreateRepayment appends to the borrow's repayment ledger. Returns gorm.ErrRecordNotFound when the
+// borrow does not belong to the user and ErrRepaymentExceedsBalance when it would overpay an
+// interest-free borrow; interest-bearing borrows are checked against the accrued amount by the caller.
+func (r *SpendingRepository) CreateRepayment(userID, borrowID string, amount models.Money, date time.Time, note string) (*models.BorrowRepayment, error) {
+	item := models.BorrowRepayment{ID: uuid.NewString(), BorrowID: borrowID, UserID: userID, Amount: amount, Date: date, Note: note}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		b, repaid, err := lockBorrow(tx, userID, borrowID)
+		if err != nil { return err }
+		if b.InterestRate == 0 && repaid+amount > b.Amount { return ErrRepaymentExceedsBalance }
+		return tx.Create(&item).Error
+	})
+	if err != nil { return nil, err }
 	return &item, nil
 }
 
-func (r *SpendingRepository) UpdateBorrowRepayment(userID, id string, repaidAmount float64, repaidDate time.Time) (int64, error) {
-	updates := map[string]interface{}{"repaid_amount": repaidAmount, "repaid_date": repaidDate}
-	res := r.db.Model(&models.BorrowEntry{}).Where("id = ? AND user_id = ?", id, userID).Updates(updates)
+func (r *SpendingRepository) DeleteRepayment(userID, borrowID, id string) (int64, error) {
+	res := r.db.Where("user_id = ? AND borrow_id = ?", userID, borrowID).Delete(&models.BorrowRepayment{}, "id = ?", id)
 	return res.RowsAffected, res.Error
 }
 
@@ -97,21 +360,200 @@ func (r *SpendingRepository) DeleteBorrow(userID, id string) (int64, error) {
 	return res.RowsAffected, res.Error
 }
 
-func (r *SpendingRepository) ListCategories(userID string) ([]models.Category, error) {
+// ListCategories lists categories in display order; archived filters on the archive flag when set
+func (r *SpendingRepository) ListCategories(userID string, archived *bool) ([]models.Category, error) {
 	var cats []models.Category
-	if err := r.db.Where("user_id = ?", userID).Order("name asc").Find(&cats).Error; err != nil { return nil, err }
+	q := r.db.Where("user_id = ?", userID).Order("sort_order asc, name asc")
+	if archived != nil { q = q.Where("archived = ?", *archived) }
+	if err := q.Find(&cats).Error; err != nil { return nil, err }
 	return cats, nil
 }
 
-func (r *SpendingRepository) CreateCategory(userID, name string) (*models.Category, error) {
-	cat := models.Category{UserID: userID, Name: name}
-	if err := r.db.Create(&cat).Error; err != nil { return nil, err }
+// CreateCategory adds cat (name, optional parent and presentation fields) for the user;
+// ErrCategoryExists or ErrInvalidParent on conflicts
+func (r *SpendingRepository) CreateCategory(userID string, cat models.Category) (*models.Category, error) {
+	var n int64
+	if err := r.db.Model(&models.Category{}).Where("user_id = ? AND name = ?", userID, cat.Name).Count(&n).Error; err != nil { return nil, err }
+	if n > 0 { return nil, ErrCategoryExists }
+	if cat.Parent != "" {
+		if err := checkParent(r.db, userID, cat.Name, cat.Parent); err != nil { return nil, err }
+	}
+	cat.UserID = userID
+	if err := r.db.Omit(clause.Associations).Create(&cat).Error; err != nil { return nil, err }
 	return &cat, nil
 }
 
-func (r *SpendingRepository) DeleteCategory(userID, name string) (int64, error) {
-	res := r.db.Delete(&models.Category{}, "user_id = ? AND name = ?", userID, name)
-	return res.RowsAffected, res.Error
+// UpdateCategory applies presentation attrs (color, icon, type, sort_order, archived), then renames the
+// category and/or moves it under another parent ("" for top level). A rename rewrites every spending
+// entry, plan, template item, envelope transfer, recurring rule, categorization rule and child category
+// that references it.
+func (r *SpendingRepository) UpdateCategory(userID, name string, newName, parent *string, attrs map[string]interface{}) (*models.Category, error) {
+	var out models.Category
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		cat, err := lockCategory(tx, userID, name)
+		if err != nil { return err }
+		if len(attrs) > 0 {
+			if err := tx.Model(cat).Updates(attrs).Error; err != nil { return err }
+			if err := tx.Where("user_id = ? AND name = ?", userID, name).First(cat).Error; err != nil { return err }
+		}
+		if parent != nil && *parent != cat.Parent {
+			if *parent != "" {
+				if err := checkParent(tx, userID, name, *parent); err != nil { return err }
+			}
+			if err := tx.Model(cat).Update("parent", *parent).Error; err != nil { return err }
+			cat.Parent = *parent
+		}
+		// Names compare case-insensitively, so a change of case is rewritten in place
+		if newName != nil && *newName != name && strings.EqualFold(*newName, name) {
+			for _, m := range []interface{}{&models.SpendingEntry{}, &models.Plan{}, &models.RecurringRule{}, &models.CategorizationRule{}, &models.PlanTemplateItem{}} {
+				if err := tx.Model(m).Where("user_id = ? AND category = ?", userID, name).Update("category", *newName).Error; err != nil { return err }
+			}
+			for _, col := range []string{"from_category", "to_category"} {
+				if err := tx.Model(&models.EnvelopeTransfer{}).Where("user_id = ? AND "+col+" = ?", userID, name).Update(col, *newName).Error; err != nil { return err }
+			}
+			if err := tx.Model(&models.Category{}).Where("user_id = ? AND parent = ?", userID, name).Update("parent", *newName).Error; err != nil { return err }
+			if err := tx.Model(&models.Category{}).Where("user_id = ? AND name = ?", userID, name).Update("name", *newName).Error; err != nil { return err }
+			cat.Name = *newName
+		} else if newName != nil && *newName != name {
+			var n int64
+			if err := tx.Model(&models.Category{}).Where("user_id = ? AND name = ?", userID, *newName).Count(&n).Error; err != nil { return err }
+			if n > 0 { return ErrCategoryExists }
+			renamed := *cat
+			renamed.Name = *newName
+			if err := tx.Create(&renamed).Error; err != nil { return err }
+			if err := mergeCategory(tx, userID, name, *newName); err != nil { return err }
+			if err := tx.Delete(&models.Category{}, "user_id = ? AND name = ?", userID, name).Error; err != nil { return err }
+			cat = &renamed
+		}
+		out = *cat
+		return nil
+	})
+	if err != nil { return nil, err }
+	return &out, nil
+}
+
+// MergeCategories moves everything referencing the source categories onto target, then deletes the sources
+func (r *SpendingRepository) MergeCategories(userID string, sources []string, target string) (*models.Category, error) {
+	var out models.Category
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		t, err := lockCategory(tx, userID, target)
+		if err != nil { return err }
+		for _, src := range sources {
+			if src == target { continue }
+			if _, err := lockCategory(tx, userID, src); err != nil { return err }
+			if err := mergeCategory(tx, userID, src, target); err != nil { return err }
+			if err := tx.Delete(&models.Category{}, "user_id = ? AND name = ?", userID, src).Error; err != nil { return err }
+		}
+		// A source may have been the target's parent
+		if err := tx.Where("user_id = ? AND name = ?", userID, target).First(t).Error; err != nil { return err }
+		out = *t
+		return nil
+	})
+	if err != nil { return nil, err }
+	return &out, nil
+}
+
+// CategoryUsage counts the rows that reference a category by name
+func (r *SpendingRepository) CategoryUsage(userID, name string) (int64, error) {
+	var total int64
+	for _, m := range []interface{}{&models.SpendingEntry{}, &models.Plan{}, &models.RecurringRule{}, &models.CategorizationRule{}, &models.PlanTemplateItem{}} {
+		var n int64
+		if err := r.db.Model(m).Where("user_id = ? AND category = ?", userID, name).Count(&n).Error; err != nil { return 0, err }
+		total += n
+	}
+	var transfers int64
+	if err := r.db.Model(&models.EnvelopeTransfer{}).Where("user_id = ? AND (from_category = ? OR to_category = ?)", userID, name, name).Count(&transfers).Error; err != nil { return 0, err }
+	var children int64
+	if err := r.db.Model(&models.Category{}).Where("user_id = ? AND parent = ?", userID, name).Count(&children).Error; err != nil { return 0, err }
+	return total + transfers + children, nil
+}
+
+// DeleteCategory removes a category after moving its references onto reassignTo. Without a
+// reassignment target, ErrCategoryInUse is returned when anything still references it.
+func (r *SpendingRepository) DeleteCategory(userID, name, reassignTo string) (int64, error) {
+	if reassignTo != "" {
+		if _, err := r.MergeCategories(userID, []string{name}, reassignTo); err != nil { return 0, err }
+		return 1, nil
+	}
+	var rows int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		n, err := (&SpendingRepository{db: tx}).CategoryUsage(userID, name)
+		if err != nil { return err }
+		if n > 0 { return ErrCategoryInUse }
+		res := tx.Delete(&models.Category{}, "user_id = ? AND name = ?", userID, name)
+		rows = res.RowsAffected
+		return res.Error
+	})
+	return rows, err
+}
+
+func lockCategory(tx *gorm.DB, userID, name string) (*models.Category, error) {
+	var cat models.Category
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("user_id = ? AND name = ?", userID, name).First(&cat).Error; err != nil { return nil, err }
+	return &cat, nil
+}
+
+// checkParent verifies that parent exists and is neither name nor one of its descendants
+func checkParent(db *gorm.DB, userID, name, parent string) error {
+	seen := map[string]bool{}
+	for p := parent; p != ""; {
+		if p == name || seen[p] { return ErrInvalidParent }
+		seen[p] = true
+		var cat models.Category
+		if err := db.Where("user_id = ? AND name = ?", userID, p).First(&cat).Error; err != nil {
+			if err == gorm.ErrRecordNotFound { return ErrInvalidParent }
+			return err
+		}
+		p = cat.Parent
+	}
+	return nil
+}
+
+// mergeCategory rewrites references from src to dst. Plans that collide with an existing dst plan in the
+// same month, and template items that collide within a template, are added to it (ErrPlanCurrencyConflict
+// when their currencies differ); children of src move under dst, and dst itself moves up to src's parent
+// if it was a child of src.
+func mergeCategory(tx *gorm.DB, userID, src, dst string) error {
+	var srcPlans []models.Plan
+	if err := tx.Where("user_id = ? AND category = ?", userID, src).Find(&srcPlans).Error; err != nil { return err }
+	for _, p := range srcPlans {
+		var existing models.Plan
+		err := tx.Where("user_id = ? AND month_key = ? AND category = ?", userID, p.MonthKey, dst).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			if err := tx.Model(&p).Update("category", dst).Error; err != nil { return err }
+			continue
+		}
+		if err != nil { return err }
+		if existing.Currency != p.Currency { return fmt.Errorf("%w: %s in %s", ErrPlanCurrencyConflict, dst, p.MonthKey) }
+		if err := tx.Model(&existing).Update("planned_amount", existing.PlannedAmount+p.PlannedAmount).Error; err != nil { return err }
+		if err := tx.Delete(&p).Error; err != nil { return err }
+	}
+	var srcItems []models.PlanTemplateItem
+	if err := tx.Where("user_id = ? AND category = ?", userID, src).Find(&srcItems).Error; err != nil { return err }
+	for _, it := range srcItems {
+		var existing models.PlanTemplateItem
+		err := tx.Where("template_id = ? AND category = ?", it.TemplateID, dst).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			if err := tx.Model(&it).Update("category", dst).Error; err != nil { return err }
+			continue
+		}
+		if err != nil { return err }
+		if existing.Currency != it.Currency { return fmt.Errorf("%w: %s in a plan template", ErrPlanCurrencyConflict, dst) }
+		if err := tx.Model(&existing).Update("planned_amount", existing.PlannedAmount+it.PlannedAmount).Error; err != nil { return err }
+		if err := tx.Delete(&it).Error; err != nil { return err }
+	}
+	for _, m := range []interface{}{&models.SpendingEntry{}, &models.RecurringRule{}, &models.CategorizationRule{}} {
+		if err := tx.Model(m).Where("user_id = ? AND category = ?", userID, src).Update("category", dst).Error; err != nil { return err }
+	}
+	for _, col := range []string{"from_category", "to_category"} {
+		if err := tx.Model(&models.EnvelopeTransfer{}).Where("user_id = ? AND "+col+" = ?", userID, src).Update(col, dst).Error; err != nil { return err }
+	}
+	// Transfers between the merged envelopes no longer move anything
+	if err := tx.Where("user_id = ? AND from_category = ? AND to_category = ?", userID, dst, dst).Delete(&models.EnvelopeTransfer{}).Error; err != nil { return err }
+	var srcCat models.Category
+	if err := tx.Where("user_id = ? AND name = ?", userID, src).First(&srcCat).Error; err != nil { return err }
+	if err := tx.Model(&models.Category{}).Where("user_id = ? AND name = ? AND parent = ?", userID, dst, src).Update("parent", srcCat.Parent).Error; err != nil { return err }
+	return tx.Model(&models.Category{}).Where("user_id = ? AND parent = ? AND name <> ?", userID, src, dst).Update("parent", dst).Error
 }
 
 func (r *SpendingRepository) ListPlans(userID, monthKey string) ([]models.Plan, error) {
@@ -125,19 +567,35 @@ func (r *SpendingRepository) ListPlans(userID, monthKey string) ([]models.Plan,
 	return plans, nil
 }
 
-func (r *SpendingRepository) UpsertPlan(userID, monthKey, category string, plannedAmount float64) (*models.Plan, bool, error) {
+// UpsertPlan creates or updates a plan; an empty currency keeps the existing one (or the user's base currency for new plans)
+func (r *SpendingRepository) UpsertPlan(userID, monthKey, category string, plannedAmount models.Money, currency string) (*models.Plan, bool, error) {
 	_ = r.EnsureMonth(userID, monthKey)
 	var existing models.Plan
 	if err := r.db.Where("user_id = ? AND month_key = ? AND category = ?", userID, monthKey, category).First(&existing).Error; err == nil {
 		existing.PlannedAmount = plannedAmount
+		if currency != "" { existing.Currency = currency }
 		if err2 := r.db.Save(&existing).Error; err2 != nil { return nil, false, err2 }
 		return &existing, true, nil
 	}
-	p := models.Plan{ID: uuid.NewString(), UserID: userID, MonthKey: monthKey, Category: category, PlannedAmount: plannedAmount}
+	p := models.Plan{ID: uuid.NewString(), UserID: userID, MonthKey: monthKey, Category: category, PlannedAmount: plannedAmount, Currency: currencyOr(r.db, userID, currency)}
 	if err := r.db.Create(&p).Error; err != nil { return nil, false, err }
 	return &p, false, nil
 }
 
+// HasMonth reports whether the user has the month
+func (r *SpendingRepository) HasMonth(userID, monthKey string) (bool, error) { return NewEnvelopeRepository(r.db).HasMonth(userID, monthKey) }
+
+// MonthPlans returns a month's plans; gorm.ErrRecordNotFound when the month does not exist
+func (r *SpendingRepository) MonthPlans(userID, monthKey string) ([]models.Plan, error) {
+	var n int64
+	if err := r.db.Model(&models.Month{}).Where("user_id = ? AND month_key = ?", userID, monthKey).Count(&n).Error; err != nil { return nil, err }
+	if n == 0 { return nil, gorm.ErrRecordNotFound }
+	return r.ListPlans(userID, monthKey)
+}
+
+// Template returns the user's plan template with the given ID or name, with its items
+func (r *SpendingRepository) Template(userID, idOrName string) (*models.PlanTemplate, error) { return findTemplate(r.db, userID, idOrName) }
+
 func (r *SpendingRepository) DeletePlan(userID, monthKey, category string) (int64, error) {
 	res := r.db.Delete(&models.Plan{}, "user_id = ? AND month_key = ? AND category = ?", userID, monthKey, category)
 	return res.RowsAffected, res.Error
@@ -149,21 +607,36 @@ func (r *SpendingRepository) ListMonths(userID string) ([]models.Month, error) {
 	return months, nil
 }
 
-func (r *SpendingRepository) CreateMonthWithSeeds(userID, monthKey string) (*models.Month, error) {
+// CreateMonthWithSeeds creates the month with a plan for every non-archived category: the amount and
+// currency of the category's entry in seeds, or 0 in the base currency. Seeds for other categories are ignored.
+func (r *SpendingRepository) CreateMonthWithSeeds(userID, monthKey string, seeds []models.Plan) (*models.Month, error) {
 	// Transaction: create month and seed plans for all categories for this user
 	tx := r.db.Begin()
 	if tx.Error != nil { return nil, tx.Error }
 	m := models.Month{UserID: userID, MonthKey: monthKey}
 	if err := tx.Create(&m).Error; err != nil { tx.Rollback(); return nil, err }
+	bySeed := map[string]models.Plan{}
+	for _, p := range seeds { bySeed[p.Category] = p }
 	var cats []models.Category
-	if err := tx.Where("user_id = ?", userID).Order("name asc").Find(&cats).Error; err == nil {
+	cur := baseCurrencyOf(tx, userID)
+	if err := tx.Where("user_id = ? AND archived = ?", userID, false).Order("name asc").Find(&cats).Error; err == nil {
 		for _, cat := range cats {
 			var existing models.Plan
 			if err := tx.Where("user_id = ? AND month_key = ? AND category = ?", userID, monthKey, cat.Name).First(&existing).Error; err == gorm.ErrRecordNotFound {
-				_ = tx.Create(&models.Plan{ID: uuid.NewString(), UserID: userID, MonthKey: monthKey, Category: cat.Name, PlannedAmount: 0}).Error
+				plan := models.Plan{ID: uuid.NewString(), UserID: userID, MonthKey: monthKey, Category: cat.Name, PlannedAmount: 0, Currency: cur}
+				if seed, ok := bySeed[cat.Name]; ok {
+					plan.PlannedAmount = seed.PlannedAmount
+					plan.Currency = currencyOr(tx, userID, seed.Currency)
+				}
+				if err := tx.Create(&plan).Error; err != nil { tx.Rollback(); return nil, err }
 			}
 		}
 	}
+	// Starting a month wraps up the previous one: queue its summary with the month itself
+	if start, err := time.Parse("2006-01", monthKey); err == nil {
+		prev := models.MonthJobPayload{UserID: userID, MonthKey: start.AddDate(0, -1, 0).Format("2006-01")}
+		if _, err := EnqueueJob(tx, models.JobMonthSummary, prev, time.Now()); err != nil { tx.Rollback(); return nil, err }
+	}
 	if err := tx.Commit().Error; err != nil { return nil, err }
 	return &m, nil
 }
@@ -171,15 +644,65 @@ func (r *SpendingRepository) CreateMonthWithSeeds(userID, monthKey string) (*mod
 func (r *SpendingRepository) MonthSummary(userID, monthKey string) ([]models.SpendingEntry, []models.EarningEntry, []models.BorrowEntry, []models.Plan) {
 	var spending []models.SpendingEntry
 	var earnings []models.EarningEntry
-	var borrows []models.BorrowEntry
 	var plans []models.Plan
 	_ = r.db.Where("user_id = ? AND month_key = ?", userID, monthKey).Order("date desc").Find(&spending).Error
 	_ = r.db.Where("user_id = ? AND month_key = ?", userID, monthKey).Order("date desc").Find(&earnings).Error
-	_ = r.db.Where("user_id = ? AND month_key = ?", userID, monthKey).Order("date desc").Find(&borrows).Error
+	borrows, _ := r.ListBorrows(userID, monthKey, "", "")
 	_ = r.db.Where("user_id = ? AND month_key = ?", userID, monthKey).Order("category asc").Find(&plans).Error
 	return spending, earnings, borrows, plans
 }
 
+// CategoryTotals is the planned and actual spending of one category in a month, in one currency.
+// Day is the spending date (YYYY-MM-DD) for actual amounts and empty for planned amounts.
+type CategoryTotals struct {
+	Category string
+	Currency string
+	Day      string
+	Planned  models.Money
+	Actual   models.Money
+}
+
+// MonthKindAmount is a month-level sum of one kind (earnings, borrowed, lent, outstanding_borrowed,
+// outstanding_lent) in one currency and day
+type MonthKindAmount struct {
+	Kind     string
+	Currency string
+	Day      string
+	Amount   models.Money
+}
+
+// MonthCategoryTotals aggregates plans and spending per category, currency and day in SQL
+// so callers can convert each bucket at the rate of its date.
+func (r *SpendingRepository) MonthCategoryTotals(userID, monthKey string) ([]CategoryTotals, error) {
+	var rows []CategoryTotals
+	err := r.db.Raw(`SELECT category, currency, '' AS day, SUM(planned_amount) AS planned, 0 AS actual
+			FROM plans WHERE user_id = @user AND month_key = @month GROUP BY category, currency
+		UNION ALL
+		SELECT category, currency, DATE_FORMAT(date, '%Y-%m-%d') AS day, 0 AS planned, SUM(amount) AS actual
+			FROM spending_entries WHERE user_id = @user AND month_key = @month GROUP BY category, currency, day
+		ORDER BY category ASC`, sql.Named("user", userID), sql.Named("month", monthKey)).Scan(&rows).Error
+	if err != nil { return nil, err }
+	return rows, nil
+}
+
+// MonthAmounts sums earnings, debts and outstanding debts per direction of a month per currency and day in SQL
+func (r *SpendingRepository) MonthAmounts(userID, monthKey string) ([]MonthKindAmount, error) {
+	var rows []MonthKindAmount
+	err := r.db.Raw(`SELECT 'earnings' AS kind, currency, DATE_FORMAT(date, '%Y-%m-%d') AS day, SUM(amount) AS amount
+			FROM earning_entries WHERE user_id = @user AND month_key = @month GROUP BY currency, day
+		UNION ALL
+		SELECT direction AS kind, currency, DATE_FORMAT(date, '%Y-%m-%d') AS day, SUM(amount) AS amount
+			FROM borrow_entries WHERE user_id = @user AND month_key = @month GROUP BY direction, currency, day
+		UNION ALL
+		SELECT CONCAT('outstanding_', b.direction) AS kind, b.currency, DATE_FORMAT(b.date, '%Y-%m-%d') AS day, SUM(GREATEST(b.amount - COALESCE(r.repaid, 0), 0)) AS amount
+			FROM borrow_entries b
+			LEFT JOIN (SELECT borrow_id, SUM(amount) AS repaid FROM borrow_repayments WHERE user_id = @user GROUP BY borrow_id) r ON r.borrow_id = b.id
+			WHERE b.user_id = @user AND b.month_key = @month GROUP BY b.direction, b.currency, day`,
+		sql.Named("user", userID), sql.Named("month", monthKey)).Scan(&rows).Error
+	if err != nil { return nil, err }
+	return rows, nil
+}
+
 func (r *SpendingRepository) DeleteMonthCascade(userID, monthKey string) error {
 	tx := r.db.Begin()
 	if tx.Error != nil { return tx.Error }
@@ -187,6 +710,7 @@ func (r *SpendingRepository) DeleteMonthCascade(userID, monthKey string) error {
 	if err := tx.Where("user_id = ? AND month_key = ?", userID, monthKey).Delete(&models.EarningEntry{}).Error; err != nil { tx.Rollback(); return err }
 	if err := tx.Where("user_id = ? AND month_key = ?", userID, monthKey).Delete(&models.BorrowEntry{}).Error; err != nil { tx.Rollback(); return err }
 	if err := tx.Delete(&models.Plan{}, "user_id = ? AND month_key = ?", userID, monthKey).Error; err != nil { tx.Rollback(); return err }
+	if err := tx.Delete(&models.EnvelopeTransfer{}, "user_id = ? AND month_key = ?", userID, monthKey).Error; err != nil { tx.Rollback(); return err }
 	if err := tx.Delete(&models.Month{}, "user_id = ? AND month_key = ?", userID, monthKey).Error; err != nil { tx.Rollback(); return err }
 	return tx.Commit().Error
 }
\ No newline at end of file
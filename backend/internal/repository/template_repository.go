@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"achieving-backend/internal/models"
+)
+
+// ErrTemplateNameTaken is returned when a plan template name is already used by another of the user's templates
+var ErrTemplateNameTaken = errors.New("template name already exists")
+
+type TemplateRepository struct {
+	db *gorm.DB
+}
+
+func NewTemplateRepository(db *gorm.DB) *TemplateRepository {
+	return &TemplateRepository{db: db}
+}
+
+func (r *TemplateRepository) List(userID string) ([]models.PlanTemplate, error) {
+	var items []models.PlanTemplate
+	err := r.db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("category asc") }).
+		Where("user_id = ?", userID).Order("name asc").Find(&items).Error
+	if err != nil { return nil, err }
+	return items, nil
+}
+
+// Find returns the template with the given ID or name
+func (r *TemplateRepository) Find(userID, idOrName string) (*models.PlanTemplate, error) { return findTemplate(r.db, userID, idOrName) }
+
+// Create stores a template with its items; every item's category must exist
+func (r *TemplateRepository) Create(userID, name string, items []models.PlanTemplateItem) (*models.PlanTemplate, error) {
+	t := models.PlanTemplate{ID: uuid.NewString(), UserID: userID, Name: name}
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := checkTemplateName(tx, userID, "", name); err != nil { return err }
+		if err := tx.Omit(clause.Associations).Create(&t).Error; err != nil { return err }
+		return replaceTemplateItems(tx, &t, items)
+	})
+	if err != nil { return nil, err }
+	return r.Find(userID, t.ID)
+}
+
+// Update renames the template and/or replaces its items; nil leaves either unchanged
+func (r *TemplateRepository) Update(userID, id string, name *string, items []models.PlanTemplateItem) (*models.PlanTemplate, error) {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var t models.PlanTemplate
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&t).Error; err != nil { return err }
+		if name != nil && *name != t.Name {
+			if err := checkTemplateName(tx, userID, id, *name); err != nil { return err }
+			if err := tx.Model(&t).Update("name", *name).Error; err != nil { return err }
+		}
+		if items == nil { return nil }
+		if err := tx.Where("template_id = ?", id).Delete(&models.PlanTemplateItem{}).Error; err != nil { return err }
+		return replaceTemplateItems(tx, &t, items)
+	})
+	if err != nil { return nil, err }
+	return r.Find(userID, id)
+}
+
+func (r *TemplateRepository) Delete(userID, id string) (int64, error) {
+	res := r.db.Where("user_id = ?", userID).Delete(&models.PlanTemplate{}, "id = ?", id)
+	return res.RowsAffected, res.Error
+}
+
+func findTemplate(db *gorm.DB, userID, idOrName string) (*models.PlanTemplate, error) {
+	var t models.PlanTemplate
+	err := db.Preload("Items", func(db *gorm.DB) *gorm.DB { return db.Order("category asc") }).
+		Where("user_id = ? AND (id = ? OR name = ?)", userID, idOrName, idOrName).First(&t).Error
+	if err != nil { return nil, err }
+	return &t, nil
+}
+
+func checkTemplateName(db *gorm.DB, userID, id, name string) error {
+	var n int64
+	if err := db.Model(&models.PlanTemplate{}).Where("user_id = ? AND name = ? AND id <> ?", userID, name, id).Count(&n).Error; err != nil { return err }
+	if n > 0 { return ErrTemplateNameTaken }
+	return nil
+}
+
+// replaceTemplateItems inserts items for t; an empty currency means the user's base currency
+func replaceTemplateItems(tx *gorm.DB, t *models.PlanTemplate, items []models.PlanTemplateItem) error {
+	for i := range items {
+		it := &items[i]
+		if err := checkCategory(tx, t.UserID, it.Category); err != nil { return err }
+		it.ID = uuid.NewString()
+		it.TemplateID = t.ID
+		it.UserID = t.UserID
+		it.Currency = currencyOr(tx, t.UserID, it.Currency)
+	}
+	if len(items) == 0 { return nil }
+	return tx.Omit(clause.Associations).Create(&items).Error
+}
@@ -10,10 +10,13 @@ import (
 
 	"achieving-backend/internal/config"
 	"achieving-backend/internal/handlers"
+	"achieving-backend/internal/health"
+	"achieving-backend/internal/middleware"
 )
 
-// SetupRouter constructs the gin Engine with middleware and registered routes
-func SetupRouter(db *gorm.DB) *gin.Engine {
+// SetupRouter constructs the gin Engine with middleware and registered routes; checker backs the
+// health probes
+func SetupRouter(db *gorm.DB, checker *health.Checker) *gin.Engine {
     r := gin.Default()
 	// Trusted proxies
 	r.SetTrustedProxies([]string{"127.0.0.1"})
@@ -29,17 +32,57 @@ func SetupRouter(db *gorm.DB) *gin.Engine {
 	}))
 
 	api := r.Group("/api")
-	// Auth
+	// Auth (public, with AuthRequired on its session routes)
 	handlers.RegisterAuthRoutes(api, db)
+
+	// Every other API route needs a live session; the middleware runs once per request
+	protected := api.Group("", middleware.AuthRequired(db))
 	// Goals
-	handlers.RegisterGoalRoutes(api, db)
+	handlers.RegisterGoalRoutes(protected, db)
 	// Spending
-	handlers.RegisterSpendingRoutes(api, db)
+	handlers.RegisterSpendingRoutes(protected, db)
+	// Plan templates
+	handlers.RegisterTemplateRoutes(protected, db)
+	// Envelope balances and transfers
+	handlers.RegisterEnvelopeRoutes(protected, db)
+	// Debt counterparties
+	handlers.RegisterCounterpartyRoutes(protected, db)
+	// Statement import
+	handlers.RegisterImportRoutes(protected, db)
+	// Account export / restore
+	handlers.RegisterExportRoutes(protected, db)
+	// Recurring rules
+	handlers.RegisterRecurringRoutes(protected, db)
+	// Auto-categorization rules
+	handlers.RegisterCategorizationRoutes(protected, db)
+	// Notifications (budget alerts)
+	handlers.RegisterNotificationRoutes(protected, db)
+	// Reports
+	handlers.RegisterReportRoutes(protected, db)
+	// Exchange rates (admin-maintained)
+	handlers.RegisterFxRoutes(protected, db)
+	// Background jobs queue (admin)
+	handlers.RegisterJobRoutes(protected, db)
 
-    // Health (root and /api alias, support GET and HEAD)
-    r.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
-    r.HEAD("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
-    api.GET("/health", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"ok": true}) })
-    api.HEAD("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
-    return r
-}
\ No newline at end of file
+	// Health probes (root and /api alias), public. Liveness only says the process serves requests;
+	// readiness checks dependencies and fails while draining. /health is kept as an alias of readiness.
+	live := func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": health.StatusOK, "uptimeSeconds": int64(checker.Uptime().Seconds())})
+	}
+	ready := func(c *gin.Context) {
+		rep := checker.Run(c.Request.Context())
+		code := http.StatusOK
+		if rep.Status != health.StatusOK { code = http.StatusServiceUnavailable }
+		if c.Request.Method == http.MethodHead { c.Status(code); return }
+		c.JSON(code, rep)
+	}
+	for _, g := range []gin.IRoutes{r, api} {
+		g.GET("/health/live", live)
+		g.HEAD("/health/live", live)
+		g.GET("/health/ready", ready)
+		g.HEAD("/health/ready", ready)
+		g.GET("/health", ready)
+		g.HEAD("/health", ready)
+	}
+	return r
+}
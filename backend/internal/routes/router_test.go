@@ -0,0 +1,70 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"achieving-backend/internal/health"
+	"achieving-backend/internal/testdb"
+)
+
+func TestRouterAuthBoundaries(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(testdb.Open(t), health.NewChecker(health.NewReadiness(), time.Second))
+	cases := []struct {
+		method, path, body string
+		want               int
+		wantBody           string
+	}{
+		{http.MethodGet, "/api/health/live", "", http.StatusOK, "uptimeSeconds"},
+		{http.MethodGet, "/health/ready", "", http.StatusOK, `"status":"ok"`},
+		{http.MethodPost, "/api/auth/login", `{"email":"nobody@example.com","password":"secret1"}`, http.StatusUnauthorized, "invalid credentials"},
+		{http.MethodPost, "/api/auth/refresh", `{}`, http.StatusBadRequest, "invalid payload"},
+		{http.MethodGet, "/api/auth/me", "", http.StatusUnauthorized, "missing token"},
+		{http.MethodGet, "/api/goals", "", http.StatusUnauthorized, "missing token"},
+		{http.MethodGet, "/api/notifications", "", http.StatusUnauthorized, "missing token"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		req.Header.Set("Content-Type", "application/json")
+		r.ServeHTTP(w, req)
+		if w.Code != tc.want || !strings.Contains(w.Body.String(), tc.wantBody) { t.Errorf("%s %s = %d %s, want %d", tc.method, tc.path, w.Code, w.Body, tc.want) }
+	}
+}
+
+func TestMonthSummaryKeepsRawRowsByDefault(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := SetupRouter(testdb.Open(t), health.NewChecker(health.NewReadiness(), time.Second))
+	do := func(method, path, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" { req.Header.Set("Authorization", "Bearer "+token) }
+		r.ServeHTTP(w, req)
+		return w
+	}
+	if w := do(http.MethodPost, "/api/auth/register", `{"email":"ann@example.com","password":"secret1","name":"Ann"}`, ""); w.Code != http.StatusCreated { t.Fatalf("register = %d %s", w.Code, w.Body) }
+	w := do(http.MethodPost, "/api/auth/login", `{"email":"ann@example.com","password":"secret1"}`, "")
+	var login struct{ Token string `json:"token"` }
+	if err := json.Unmarshal(w.Body.Bytes(), &login); err != nil || login.Token == "" { t.Fatalf("login = %d %s", w.Code, w.Body) }
+
+	for path, want := range map[string][]string{
+		"/api/months/2026-03/summary":              {"monthKey", "categories", "totals", "spending", "earnings", "borrows", "plans"},
+		"/api/months/2026-03/summary?view=summary": {"monthKey", "categories", "totals"},
+	} {
+		w := do(http.MethodGet, path, "", login.Token)
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || w.Code != http.StatusOK { t.Fatalf("GET %s = %d %s", path, w.Code, w.Body) }
+		for _, k := range want {
+			if _, ok := body[k]; !ok { t.Errorf("GET %s: no %q in %s", path, k, w.Body) }
+		}
+		if len(body) != len(want)+1 { t.Errorf("GET %s: keys %d, want %v and currency", path, len(body), want) }
+	}
+}
@@ -1,12 +1,23 @@
 package services
 
 import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/repository"
 )
 
+// ErrInvalidSession is returned when a refresh token is unknown, rotated, revoked or expired
+var ErrInvalidSession = errors.New("invalid session")
+
 // jwtSecret returns the application JWT secret from env with a dev fallback
 func jwtSecret() string {
 	secret := os.Getenv("JWT_SECRET")
@@ -16,17 +27,108 @@ func jwtSecret() string {
 	return secret
 }
 
-// GenerateToken creates a signed JWT for the given user metadata
-func GenerateToken(userID, name, email string) (string, jwt.MapClaims, error) {
+// durationFromEnv parses a Go duration env var, falling back on empty or invalid values
+func durationFromEnv(key string, fallback time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil && d > 0 {
+		return d
+	}
+	return fallback
+}
+
+// AccessTokenTTL is the lifetime of access JWTs (ACCESS_TOKEN_TTL, default 24h)
+func AccessTokenTTL() time.Duration { return durationFromEnv("ACCESS_TOKEN_TTL", 24*time.Hour) }
+
+// RefreshTokenTTL is the sliding lifetime of a session (REFRESH_TOKEN_TTL, default 30 days)
+func RefreshTokenTTL() time.Duration { return durationFromEnv("REFRESH_TOKEN_TTL", 30*24*time.Hour) }
+
+// GenerateToken creates a signed JWT for the given user metadata bound to a session via `jti`
+func GenerateToken(userID, name, email, sessionID string) (string, jwt.MapClaims, error) {
 	claims := jwt.MapClaims{
 		"sub":    userID,
 		"name":   name,
 		"email":  email,
+		"jti":    sessionID,
 		"iat":    time.Now().Unix(),
-		"exp":    time.Now().Add(24 * time.Hour).Unix(),
+		"exp":    time.Now().Add(AccessTokenTTL()).Unix(),
 		"issuer": "achieving-backend",
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	signed, err := token.SignedString([]byte(jwtSecret()))
 	return signed, claims, err
-}
\ No newline at end of file
+}
+
+// newRefreshToken returns a random opaque refresh token and its storage hash
+func newRefreshToken() (string, string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", err
+	}
+	tok := base64.RawURLEncoding.EncodeToString(buf)
+	return tok, hashRefreshToken(tok), nil
+}
+
+func hashRefreshToken(tok string) string {
+	sum := sha256.Sum256([]byte(tok))
+	return hex.EncodeToString(sum[:])
+}
+
+// TokenPair is what login and refresh hand back to clients
+type TokenPair struct {
+	Token        string
+	RefreshToken string
+	Claims       jwt.MapClaims
+}
+
+type SessionService struct {
+	repo *repository.SessionRepository
+}
+
+func NewSessionService(repo *repository.SessionRepository) *SessionService {
+	return &SessionService{repo: repo}
+}
+
+// StartSession persists a new session and issues its first token pair
+func (s *SessionService) StartSession(userID, name, email string) (*TokenPair, error) {
+	refresh, hash, err := newRefreshToken()
+	if err != nil { return nil, err }
+	sess, err := s.repo.CreateSession(userID, hash, time.Now().Add(RefreshTokenTTL()))
+	if err != nil { return nil, err }
+	tok, claims, err := GenerateToken(userID, name, email, sess.ID)
+	if err != nil { return nil, err }
+	return &TokenPair{Token: tok, RefreshToken: refresh, Claims: claims}, nil
+}
+
+// Refresh rotates the refresh token of an active session and issues a new access token.
+// A refresh token can be used only once: replaying the token a session last rotated away from, or
+// losing a race to rotate the same token, revokes the whole session and yields ErrInvalidSession,
+// since one of the two callers holds a stolen token.
+func (s *SessionService) Refresh(refreshToken string) (*TokenPair, error) {
+	oldHash := hashRefreshToken(refreshToken)
+	sess, err := s.repo.FindActiveByRefreshHash(oldHash)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		if _, err := s.repo.RevokeByPreviousRefreshHash(oldHash); err != nil { return nil, err }
+		return nil, ErrInvalidSession
+	}
+	if err != nil { return nil, err }
+	refresh, newHash, err := newRefreshToken()
+	if err != nil { return nil, err }
+	rows, err := s.repo.RotateRefreshToken(sess.ID, oldHash, newHash, time.Now().Add(RefreshTokenTTL()))
+	if err != nil { return nil, err }
+	if rows == 0 {
+		if _, err := s.repo.RevokeSession(sess.UserID, sess.ID); err != nil { return nil, err }
+		return nil, ErrInvalidSession
+	}
+	tok, claims, err := GenerateToken(sess.UserID, sess.User.Name, sess.User.Email, sess.ID)
+	if err != nil { return nil, err }
+	return &TokenPair{Token: tok, RefreshToken: refresh, Claims: claims}, nil
+}
+
+func (s *SessionService) IsActive(sessionID string) (bool, error) { return s.repo.IsActive(sessionID) }
+
+func (s *SessionService) Revoke(userID, sessionID string) (int64, error) {
+	return s.repo.RevokeSession(userID, sessionID)
+}
+
+func (s *SessionService) RevokeOthers(userID, keepSessionID string) (int64, error) {
+	return s.repo.RevokeOtherSessions(userID, keepSessionID)
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func newSessionService(t *testing.T) *SessionService {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	return NewSessionService(repository.NewSessionRepository(db))
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	svc := newSessionService(t)
+	first, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	second, err := svc.Refresh(first.RefreshToken)
+	if err != nil { t.Fatalf("refresh: %v", err) }
+	if second.RefreshToken == first.RefreshToken { t.Fatal("refresh token was not rotated") }
+	if second.Claims["jti"] != first.Claims["jti"] { t.Errorf("jti changed from %v to %v", first.Claims["jti"], second.Claims["jti"]) }
+	if second.Claims["sub"] != "u1" || second.Claims["email"] != "u1@example.com" { t.Errorf("claims = %v", second.Claims) }
+	if _, err := svc.Refresh(second.RefreshToken); err != nil { t.Errorf("rotated token refused: %v", err) }
+}
+
+func TestRefreshReplayIsRejected(t *testing.T) {
+	svc := newSessionService(t)
+	pair, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	if _, err := svc.Refresh(pair.RefreshToken); err != nil { t.Fatal(err) }
+	if _, err := svc.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Errorf("replayed refresh token: err = %v, want ErrInvalidSession", err) }
+	if _, err := svc.Refresh("never-issued"); !errors.Is(err, ErrInvalidSession) { t.Errorf("unknown refresh token: err = %v, want ErrInvalidSession", err) }
+}
+
+func TestRefreshReplayRevokesSession(t *testing.T) {
+	svc := newSessionService(t)
+	stolen, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	other, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	current, err := svc.Refresh(stolen.RefreshToken)
+	if err != nil { t.Fatal(err) }
+
+	// The old token shows up again: the session is revoked, also for the holder of the newer token
+	if _, err := svc.Refresh(stolen.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Fatalf("replay: err = %v, want ErrInvalidSession", err) }
+	if active, _ := svc.IsActive(stolen.Claims["jti"].(string)); active { t.Error("session still active after its refresh token was replayed") }
+	if _, err := svc.Refresh(current.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Errorf("refresh with the newer token after a replay: err = %v, want ErrInvalidSession", err) }
+	if active, _ := svc.IsActive(other.Claims["jti"].(string)); !active { t.Error("another session of the user was revoked") }
+}
+
+func TestRefreshRaceRevokesSession(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	repo := repository.NewSessionRepository(db)
+	svc := NewSessionService(repo)
+	pair, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	// Another refresh with the same token wins between the lookup and the rotation
+	sess, err := repo.FindActiveByRefreshHash(hashRefreshToken(pair.RefreshToken))
+	if err != nil { t.Fatal(err) }
+	if rows, err := repo.RotateRefreshToken(sess.ID, "stale", "next", time.Now().Add(time.Hour)); err != nil || rows != 0 { t.Fatalf("rotation with a stale hash: rows = %d, err = %v", rows, err) }
+	if _, err := repo.RotateRefreshToken(sess.ID, sess.RefreshTokenHash, "winner", time.Now().Add(time.Hour)); err != nil { t.Fatal(err) }
+	if _, err := svc.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Fatalf("losing refresh: err = %v, want ErrInvalidSession", err) }
+	if active, _ := svc.IsActive(sess.ID); active { t.Error("session still active after the same refresh token was used twice") }
+}
+
+func TestLogoutRevokesSession(t *testing.T) {
+	svc := newSessionService(t)
+	pair, err := svc.StartSession("u1", "U", "u1@example.com")
+	if err != nil { t.Fatal(err) }
+	jti := pair.Claims["jti"].(string)
+	if n, err := svc.Revoke("other-user", jti); err != nil || n != 0 { t.Fatalf("revoke by another user: n=%d err=%v", n, err) }
+	if n, err := svc.Revoke("u1", jti); err != nil || n != 1 { t.Fatalf("revoke: n=%d err=%v", n, err) }
+	if active, _ := svc.IsActive(jti); active { t.Error("session still active after logout") }
+	if _, err := svc.Refresh(pair.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Errorf("refresh after logout: err = %v, want ErrInvalidSession", err) }
+}
+
+func TestRevokeOthersKeepsCurrentSession(t *testing.T) {
+	svc := newSessionService(t)
+	var pairs []*TokenPair
+	for i := 0; i < 3; i++ {
+		p, err := svc.StartSession("u1", "U", "u1@example.com")
+		if err != nil { t.Fatal(err) }
+		pairs = append(pairs, p)
+	}
+	current := pairs[0].Claims["jti"].(string)
+	if n, err := svc.RevokeOthers("u1", current); err != nil || n != 2 { t.Fatalf("revoke others: n=%d err=%v", n, err) }
+	if active, _ := svc.IsActive(current); !active { t.Error("current session revoked by password change") }
+	for _, p := range pairs[1:] {
+		if active, _ := svc.IsActive(p.Claims["jti"].(string)); active { t.Error("other session still active after password change") }
+		if _, err := svc.Refresh(p.RefreshToken); !errors.Is(err, ErrInvalidSession) { t.Errorf("refresh of revoked session: err = %v", err) }
+	}
+}
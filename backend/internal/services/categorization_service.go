@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidCategorizationRule is returned for rules without a category or without any criterion
+var ErrInvalidCategorizationRule = errors.New("invalid categorization rule")
+
+type CategorizationService struct {
+	rules   *repository.CategorizationRepository
+	entries *repository.SpendingRepository
+}
+
+func NewCategorizationService(rules *repository.CategorizationRepository, entries *repository.SpendingRepository) *CategorizationService {
+	return &CategorizationService{rules: rules, entries: entries}
+}
+
+func (s *CategorizationService) List(userID string) ([]models.CategorizationRule, error) { return s.rules.List(userID) }
+func (s *CategorizationService) Find(userID, id string) (*models.CategorizationRule, error) { return s.rules.Find(userID, id) }
+func (s *CategorizationService) Delete(userID, id string) (int64, error) { return s.rules.Delete(userID, id) }
+func (s *CategorizationService) Reorder(userID string, ids []string) ([]models.CategorizationRule, error) {
+	return s.rules.Reorder(userID, ids)
+}
+
+// Create validates and stores a rule; a nil priority appends it after the existing rules
+func (s *CategorizationService) Create(rule *models.CategorizationRule, priority *int) error {
+	if err := validateCategorizationRule(rule); err != nil { return err }
+	return s.rules.Create(rule, priority)
+}
+
+func (s *CategorizationService) Update(rule *models.CategorizationRule) error {
+	if err := validateCategorizationRule(rule); err != nil { return err }
+	return s.rules.Save(rule)
+}
+
+func validateCategorizationRule(rule *models.CategorizationRule) error {
+	rule.Category = strings.TrimSpace(rule.Category)
+	rule.NoteContains = strings.TrimSpace(rule.NoteContains)
+	rule.Merchant = strings.TrimSpace(rule.Merchant)
+	rule.Currency = strings.ToUpper(rule.Currency)
+	if rule.Category == "" { return fmt.Errorf("%w: category is required", ErrInvalidCategorizationRule) }
+	if rule.NoteContains == "" && rule.Merchant == "" && rule.MinAmount == nil && rule.MaxAmount == nil {
+		return fmt.Errorf("%w: set noteContains, merchant or an amount range", ErrInvalidCategorizationRule)
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return fmt.Errorf("%w: minAmount exceeds maxAmount", ErrInvalidCategorizationRule)
+	}
+	return nil
+}
+
+// RuleMatches reports whether every criterion set on rule holds for the entry
+func RuleMatches(rule *models.CategorizationRule, note, merchant string, amount models.Money, currency string) bool {
+	if rule.NoteContains != "" && !strings.Contains(strings.ToLower(note), strings.ToLower(rule.NoteContains)) { return false }
+	if rule.Merchant != "" && !strings.Contains(strings.ToLower(merchant), strings.ToLower(rule.Merchant)) { return false }
+	if rule.MinAmount != nil && amount < *rule.MinAmount { return false }
+	if rule.MaxAmount != nil && amount > *rule.MaxAmount { return false }
+	if rule.Currency != "" && !strings.EqualFold(rule.Currency, currency) { return false }
+	return true
+}
+
+// MatchRule returns the first of rules (already in priority order) that matches the entry, or nil
+func MatchRule(rules []models.CategorizationRule, note, merchant string, amount models.Money, currency string) *models.CategorizationRule {
+	for i := range rules {
+		if RuleMatches(&rules[i], note, merchant, amount, currency) { return &rules[i] }
+	}
+	return nil
+}
+
+// CategorizationMatch is the outcome of running the rules over one entry; Rule is nil when none matched
+type CategorizationMatch struct {
+	Category string                     `json:"category"`
+	Rule     *models.CategorizationRule `json:"rule"`
+}
+
+// Preview runs the user's active rules over an entry that is not stored; an empty currency means the base currency
+func (s *CategorizationService) Preview(userID, note, merchant string, amount models.Money, currency string) (*CategorizationMatch, error) {
+	rules, err := s.entries.CategorizationRules(userID)
+	if err != nil { return nil, err }
+	out := &CategorizationMatch{}
+	if rule := MatchRule(rules, note, merchant, amount, s.entries.CurrencyOr(userID, currency)); rule != nil {
+		out.Category = rule.Category
+		out.Rule = rule
+	}
+	return out, nil
+}
+
+// Recategorization is a category change the rules make to a stored spending entry
+type Recategorization struct {
+	EntryID  string       `json:"entryId"`
+	Date     time.Time    `json:"date"`
+	Note     string       `json:"note"`
+	Merchant string       `json:"merchant"`
+	Amount   models.Money `json:"amount"`
+	Currency string       `json:"currency"`
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	RuleID   string       `json:"ruleId"`
+}
+
+type RecategorizeResult struct {
+	MonthKey string             `json:"monthKey"`
+	DryRun   bool               `json:"dryRun"`
+	Changes  []Recategorization `json:"changes"`
+	// Updated counts the entries changed; entries edited while the rules ran are left alone
+	Updated  int64              `json:"updated"`
+}
+
+// Recategorize re-runs the rules over the month's spending entries. Only uncategorized entries are
+// considered unless overwrite is set; entries no rule matches keep their category.
+func (s *CategorizationService) Recategorize(userID, monthKey string, overwrite, dryRun bool) (*RecategorizeResult, error) {
+	rules, err := s.entries.CategorizationRules(userID)
+	if err != nil { return nil, err }
+	entries, err := s.entries.ListSpending(userID, monthKey)
+	if err != nil { return nil, err }
+	res := &RecategorizeResult{MonthKey: monthKey, DryRun: dryRun, Changes: []Recategorization{}}
+	changes, from := map[string]string{}, map[string]string{}
+	for _, e := range entries {
+		if e.Category != "" && !overwrite { continue }
+		rule := MatchRule(rules, e.Note, e.Merchant, e.Amount, e.Currency)
+		if rule == nil || rule.Category == e.Category { continue }
+		res.Changes = append(res.Changes, Recategorization{EntryID: e.ID, Date: e.Date, Note: e.Note, Merchant: e.Merchant, Amount: e.Amount, Currency: e.Currency, From: e.Category, To: rule.Category, RuleID: rule.ID})
+		changes[e.ID] = rule.Category
+		from[e.ID] = e.Category
+	}
+	if dryRun || len(changes) == 0 { return res, nil }
+	res.Updated, err = s.entries.Recategorize(userID, changes, from)
+	if err != nil { return nil, err }
+	return res, nil
+}
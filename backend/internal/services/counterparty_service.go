@@ -0,0 +1,118 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+type CounterpartyService struct {
+	repo *repository.CounterpartyRepository
+}
+
+func NewCounterpartyService(repo *repository.CounterpartyRepository) *CounterpartyService {
+	return &CounterpartyService{repo: repo}
+}
+
+func (s *CounterpartyService) List(userID string) ([]models.Counterparty, error) { return s.repo.List(userID) }
+func (s *CounterpartyService) Find(userID, id string) (*models.Counterparty, error) { return s.repo.Find(userID, id) }
+func (s *CounterpartyService) Create(userID, name, note string) (*models.Counterparty, error) {
+	return s.repo.Create(userID, name, note)
+}
+func (s *CounterpartyService) Update(userID, id string, updates map[string]interface{}) (*models.Counterparty, error) {
+	return s.repo.Update(userID, id, updates)
+}
+func (s *CounterpartyService) Delete(userID, id string) (int64, error) { return s.repo.Delete(userID, id) }
+
+// CounterpartyBalance is the position with a counterparty in one currency.
+// Net is positive when the counterparty owes the user and negative when the user owes them.
+type CounterpartyBalance struct {
+	Currency string       `json:"currency"`
+	Borrowed models.Money `json:"borrowed"`
+	Repaid   models.Money `json:"repaid"`
+	Lent     models.Money `json:"lent"`
+	Received models.Money `json:"received"`
+	OwedToMe models.Money `json:"owedToMe"`
+	IOwe     models.Money `json:"iOwe"`
+	Net      models.Money `json:"net"`
+}
+
+// CounterpartyEvent is one debt or repayment in a counterparty's history. Kind is "borrowed" or "lent"
+// for debts, "repaid" when the user paid back and "received" when the counterparty did.
+// Balance is the running net position in the event's currency after it.
+type CounterpartyEvent struct {
+	Kind     string       `json:"kind"`
+	Date     time.Time    `json:"date"`
+	MonthKey string       `json:"monthKey"`
+	BorrowID string       `json:"borrowId"`
+	Amount   models.Money `json:"amount"`
+	Currency string       `json:"currency"`
+	Note     string       `json:"note,omitempty"`
+	Balance  models.Money `json:"balance"`
+}
+
+type CounterpartyStatement struct {
+	Counterparty models.Counterparty   `json:"counterparty"`
+	Balances     []CounterpartyBalance `json:"balances"`
+	Debts        []models.BorrowEntry  `json:"debts"`
+	History      []CounterpartyEvent   `json:"history"`
+}
+
+// Statement returns the net position with a counterparty per currency and its full history across months
+func (s *CounterpartyService) Statement(userID, id string) (*CounterpartyStatement, error) {
+	cp, err := s.repo.Find(userID, id)
+	if err != nil { return nil, err }
+	debts, err := s.repo.Debts(userID, id)
+	if err != nil { return nil, err }
+	repayments, err := s.repo.Repayments(userID, id)
+	if err != nil { return nil, err }
+
+	byID := map[string]models.BorrowEntry{}
+	var events []CounterpartyEvent
+	for _, d := range debts {
+		byID[d.ID] = d
+		events = append(events, CounterpartyEvent{Kind: d.Direction, Date: d.Date, MonthKey: d.MonthKey, BorrowID: d.ID, Amount: d.Amount, Currency: d.Currency})
+	}
+	for _, rp := range repayments {
+		d := byID[rp.BorrowID]
+		kind := "repaid"
+		if d.Direction == models.DirectionLent { kind = "received" }
+		events = append(events, CounterpartyEvent{Kind: kind, Date: rp.Date, MonthKey: rp.Date.Format("2006-01"), BorrowID: rp.BorrowID, Amount: rp.Amount, Currency: d.Currency, Note: rp.Note})
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Date.Before(events[j].Date) })
+
+	var order []string
+	balances := map[string]*CounterpartyBalance{}
+	for i := range events {
+		e := &events[i]
+		b := balances[e.Currency]
+		if b == nil {
+			b = &CounterpartyBalance{Currency: e.Currency}
+			balances[e.Currency] = b
+			order = append(order, e.Currency)
+		}
+		switch e.Kind {
+		case models.DirectionBorrowed:
+			b.Borrowed += e.Amount
+		case models.DirectionLent:
+			b.Lent += e.Amount
+		case "repaid":
+			b.Repaid += e.Amount
+		case "received":
+			b.Received += e.Amount
+		}
+		b.Net = b.Lent - b.Received - (b.Borrowed - b.Repaid)
+		e.Balance = b.Net
+	}
+	for _, d := range debts {
+		if d.Direction == models.DirectionLent { balances[d.Currency].OwedToMe += d.Outstanding } else { balances[d.Currency].IOwe += d.Outstanding }
+	}
+
+	out := &CounterpartyStatement{Counterparty: *cp, Balances: []CounterpartyBalance{}, Debts: debts, History: events}
+	if out.Debts == nil { out.Debts = []models.BorrowEntry{} }
+	if out.History == nil { out.History = []CounterpartyEvent{} }
+	for _, c := range order { out.Balances = append(out.Balances, *balances[c]) }
+	return out, nil
+}
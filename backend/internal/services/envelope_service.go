@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidTransfer is returned for transfers within one envelope or of a non-positive amount
+var ErrInvalidTransfer = errors.New("transfer needs two different categories and a positive amount")
+
+type EnvelopeService struct {
+	repo *repository.EnvelopeRepository
+}
+
+func NewEnvelopeService(repo *repository.EnvelopeRepository) *EnvelopeService {
+	return &EnvelopeService{repo: repo}
+}
+
+func (s *EnvelopeService) HasMonth(userID, monthKey string) (bool, error) { return s.repo.HasMonth(userID, monthKey) }
+func (s *EnvelopeService) ListTransfers(userID, monthKey string) ([]models.EnvelopeTransfer, error) {
+	return s.repo.ListTransfers(userID, monthKey)
+}
+func (s *EnvelopeService) DeleteTransfer(userID, id string) (int64, error) { return s.repo.DeleteTransfer(userID, id) }
+
+func (s *EnvelopeService) CreateTransfer(t *models.EnvelopeTransfer) error {
+	if t.Amount <= 0 || strings.EqualFold(t.FromCategory, t.ToCategory) { return ErrInvalidTransfer }
+	return s.repo.CreateTransfer(t)
+}
+
+// Envelope is a category's balance in a month. Available is Carried (the previous month's Available)
+// plus Planned, minus Spent, plus net transfers; it goes negative when the envelope is overspent.
+type Envelope struct {
+	Category     string       `json:"category"`
+	Carried      models.Money `json:"carried"`
+	Planned      models.Money `json:"planned"`
+	Spent        models.Money `json:"spent"`
+	TransfersIn  models.Money `json:"transfersIn"`
+	TransfersOut models.Money `json:"transfersOut"`
+	Available    models.Money `json:"available"`
+}
+
+type EnvelopeReport struct {
+	MonthKey  string       `json:"monthKey"`
+	Currency  string       `json:"currency"`
+	Envelopes []Envelope   `json:"envelopes"`
+	Available models.Money `json:"available"` // sum over all envelopes
+}
+
+// Envelopes computes every category's envelope for monthKey by carrying balances forward through all
+// earlier months. Amounts are converted to the base currency at the rate of their date (plans and
+// transfers use the first of their month); a *MissingRatesError is returned when some rate is unavailable.
+func (s *EnvelopeService) Envelopes(conv *Converter, userID, monthKey string) (*EnvelopeReport, error) {
+	rows, err := s.repo.Rows(userID, monthKey)
+	if err != nil { return nil, err }
+	balance := map[string]models.Money{}
+	current := map[string]*Envelope{}
+	for _, r := range rows {
+		at := rowDate(r.MonthKey, r.Day)
+		planned, spent := conv.Convert(r.Planned, r.Currency, at), conv.Convert(r.Spent, r.Currency, at)
+		inflow, outflow := conv.Convert(r.TransferIn, r.Currency, at), conv.Convert(r.TransferOut, r.Currency, at)
+		if r.MonthKey != monthKey {
+			balance[r.Category] += planned - spent + inflow - outflow
+			continue
+		}
+		e := current[r.Category]
+		if e == nil {
+			e = &Envelope{Category: r.Category}
+			current[r.Category] = e
+		}
+		e.Planned += planned
+		e.Spent += spent
+		e.TransfersIn += inflow
+		e.TransfersOut += outflow
+	}
+	if err := conv.Err(); err != nil { return nil, err }
+
+	// Envelopes untouched this month still carry their balance
+	for cat, b := range balance {
+		if current[cat] == nil && b != 0 { current[cat] = &Envelope{Category: cat} }
+	}
+	out := &EnvelopeReport{MonthKey: monthKey, Currency: conv.Base, Envelopes: make([]Envelope, 0, len(current))}
+	for cat, e := range current {
+		e.Carried = balance[cat]
+		e.Available = e.Carried + e.Planned - e.Spent + e.TransfersIn - e.TransfersOut
+		out.Available += e.Available
+		out.Envelopes = append(out.Envelopes, *e)
+	}
+	sort.Slice(out.Envelopes, func(i, j int) bool { return out.Envelopes[i].Category < out.Envelopes[j].Category })
+	return out, nil
+}
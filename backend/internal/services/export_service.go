@@ -0,0 +1,169 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrAccountNotEmpty is returned when a restore would mix bundle data into unrelated existing data
+var ErrAccountNotEmpty = errors.New("account is not empty")
+
+// ErrInvalidBundle is returned when the uploaded archive is not an export bundle
+var ErrInvalidBundle = errors.New("invalid export bundle")
+
+// maxBundleJSON caps the uncompressed data.json of an uploaded bundle, so a small zip cannot
+// expand into an unbounded amount of memory
+const maxBundleJSON = 256 << 20
+
+// exportVersion is bumped when the bundle layout changes incompatibly
+const exportVersion = 1
+
+// ExportFile is the data.json document inside the export zip
+type ExportFile struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+	repository.Bundle
+}
+
+type ExportService struct {
+	repo  *repository.ExportRepository
+	goals *repository.GoalRepository
+}
+
+func NewExportService(repo *repository.ExportRepository, goals *repository.GoalRepository) *ExportService {
+	return &ExportService{repo: repo, goals: goals}
+}
+
+// WriteZip streams data.json plus one CSV per table into w
+func (s *ExportService) WriteZip(userID string, w io.Writer) error {
+	b, err := s.repo.LoadBundle(userID)
+	if err != nil { return err }
+	zw := zip.NewWriter(w)
+	jw, err := zw.Create("data.json")
+	if err != nil { return err }
+	enc := json.NewEncoder(jw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(ExportFile{Version: exportVersion, ExportedAt: time.Now().UTC(), Bundle: *b}); err != nil { return err }
+	var templateItems []models.PlanTemplateItem
+	for _, t := range b.PlanTemplates { templateItems = append(templateItems, t.Items...) }
+	tables := []struct {
+		name string
+		rows interface{}
+	}{
+		{"months.csv", b.Months},
+		{"categories.csv", b.Categories},
+		{"plans.csv", b.Plans},
+		{"envelope_transfers.csv", b.EnvelopeTransfers},
+		{"spending_entries.csv", b.Spending},
+		{"earning_entries.csv", b.Earnings},
+		{"counterparties.csv", b.Counterparties},
+		{"borrow_entries.csv", b.Borrows},
+		{"borrow_repayments.csv", b.BorrowRepayments},
+		{"goals.csv", b.Goals},
+		{"goal_contributions.csv", b.GoalContributions},
+		{"recurring_rules.csv", b.RecurringRules},
+		{"categorization_rules.csv", b.CategorizationRules},
+		{"plan_templates.csv", b.PlanTemplates},
+		{"plan_template_items.csv", templateItems},
+		{"notification_channels.csv", b.NotificationChannels},
+	}
+	for _, t := range tables {
+		fw, err := zw.Create(t.name)
+		if err != nil { return err }
+		if err := writeCSV(fw, t.rows); err != nil { return err }
+	}
+	return zw.Close()
+}
+
+// ReadZip extracts the bundle from an export zip
+func ReadZip(r io.ReaderAt, size int64) (*ExportFile, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil { return nil, ErrInvalidBundle }
+	for _, f := range zr.File {
+		if f.Name != "data.json" { continue }
+		if f.UncompressedSize64 > maxBundleJSON { return nil, fmt.Errorf("%w: data.json is too large", ErrInvalidBundle) }
+		rc, err := f.Open()
+		if err != nil { return nil, ErrInvalidBundle }
+		defer rc.Close()
+		// The header size can lie; the limit holds whatever the entry actually inflates to
+		var ef ExportFile
+		if err := json.NewDecoder(io.LimitReader(rc, maxBundleJSON)).Decode(&ef); err != nil { return nil, ErrInvalidBundle }
+		if ef.Version != exportVersion { return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidBundle, ef.Version) }
+		return &ef, nil
+	}
+	return nil, ErrInvalidBundle
+}
+
+// Restore imports a bundle into the user's account. The account must be empty or
+// contain only data from this same bundle, which makes re-running a restore a no-op.
+func (s *ExportService) Restore(userID string, ef *ExportFile) error {
+	months, cats, goals, err := s.repo.ExistingKeys(userID)
+	if err != nil { return err }
+	inBundle := map[string]bool{}
+	for _, m := range ef.Months { inBundle["m:"+m.MonthKey] = true }
+	for _, c := range ef.Categories { inBundle["c:"+c.Name] = true }
+	for _, g := range ef.Goals { inBundle["g:"+repository.RestoreID(userID, g.ID)] = true }
+	for _, mk := range months { if !inBundle["m:"+mk] { return ErrAccountNotEmpty } }
+	for _, name := range cats { if !inBundle["c:"+name] { return ErrAccountNotEmpty } }
+	for _, id := range goals { if !inBundle["g:"+id] { return ErrAccountNotEmpty } }
+
+	if err := s.repo.RestoreBundle(userID, &ef.Bundle); err != nil { return err }
+	// Schedules are derived data; rebuild them for the restored goals (none when the target is too small)
+	for i := range ef.Goals {
+		items, err := BuildSchedule(&ef.Goals[i])
+		if err != nil && !errors.Is(err, ErrScheduleTooFine) { return err }
+		if _, err := s.goals.ReplaceSchedule(userID, ef.Goals[i].ID, items); err != nil { return err }
+	}
+	return nil
+}
+
+// writeCSV writes a slice of structs using their JSON field names as the header.
+// Fields tagged json:"-" (associations, secrets) and nested rows such as template items are skipped.
+func writeCSV(w io.Writer, rows interface{}) error {
+	cw := csv.NewWriter(w)
+	v := reflect.ValueOf(rows)
+	t := v.Type().Elem()
+	var cols []int
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		name := strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || t.Field(i).Type.Kind() == reflect.Slice { continue }
+		cols = append(cols, i)
+		header = append(header, name)
+	}
+	if err := cw.Write(header); err != nil { return err }
+	for i := 0; i < v.Len(); i++ {
+		row := v.Index(i)
+		rec := make([]string, len(cols))
+		for j, c := range cols { rec[j] = csvValue(row.Field(c)) }
+		if err := cw.Write(rec); err != nil { return err }
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func csvValue(f reflect.Value) string {
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() { return "" }
+		f = f.Elem()
+	}
+	if tm, ok := f.Interface().(time.Time); ok { return tm.Format(time.RFC3339) }
+	if s, ok := f.Interface().(fmt.Stringer); ok { return s.String() }
+	switch f.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(f.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(f.Interface())
+	}
+}
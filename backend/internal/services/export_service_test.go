@@ -0,0 +1,83 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func TestExportRestoreRoundTrip(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	testdb.User(t, db, "u2")
+	target := models.MoneyFromInt(600)
+	start, end := day(2026, 1, 1), day(2026, 6, 30)
+	seed := []interface{}{
+		&models.Month{UserID: "u1", MonthKey: "2026-03"},
+		&models.Category{UserID: "u1", Name: "food", Archived: true},
+		&models.Plan{ID: "p1", UserID: "u1", MonthKey: "2026-03", Category: "food", PlannedAmount: models.MoneyFromInt(300), Currency: "USD"},
+		&models.SpendingEntry{ID: "s1", UserID: "u1", MonthKey: "2026-03", Category: "food", Amount: money(t, "12.34"), Currency: "USD", Date: day(2026, 3, 2), Note: "lunch"},
+		&models.Goal{ID: "g1", UserID: "u1", Title: "Trip", SaveFrequency: "monthly", StartDate: &start, EndDate: &end, TargetAmount: &target, Currency: "USD"},
+		&models.GoalContribution{ID: "gc1", UserID: "u1", GoalID: "g1", Amount: models.MoneyFromInt(100), Currency: "USD", Date: day(2026, 1, 5)},
+		&models.RecurringRule{ID: "r1", UserID: "u1", Kind: "spending", Amount: models.MoneyFromInt(900), Currency: "USD", Category: "rent", RRule: "FREQ=MONTHLY;BYMONTHDAY=1", StartDate: start, Active: true},
+		&models.CategorizationRule{ID: "c1", UserID: "u1", Priority: 1, Category: "food", Merchant: "cafe", Active: true},
+		&models.PlanTemplate{ID: "t1", UserID: "u1", Name: "Lean", Items: []models.PlanTemplateItem{{ID: "ti1", UserID: "u1", Category: "food", PlannedAmount: models.MoneyFromInt(250), Currency: "USD"}}},
+		&models.NotificationChannel{ID: "n1", UserID: "u1", Channel: "telegram", Address: "12345", Enabled: true},
+	}
+	for _, row := range seed {
+		if err := db.Create(row).Error; err != nil { t.Fatalf("seeding %T: %v", row, err) }
+	}
+	// Inactive rows must survive the boolean column defaults
+	db.Model(&models.RecurringRule{}).Where("id = ?", "r1").Update("active", false)
+	db.Model(&models.NotificationChannel{}).Where("id = ?", "n1").Update("enabled", false)
+
+	exports := repository.NewExportRepository(db)
+	svc := NewExportService(exports, repository.NewGoalRepository(db))
+	var buf bytes.Buffer
+	if err := svc.WriteZip("u1", &buf); err != nil { t.Fatal(err) }
+	for i := 0; i < 2; i++ {
+		ef, err := ReadZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil { t.Fatal(err) }
+		if err := svc.Restore("u2", ef); err != nil { t.Fatalf("restore %d: %v", i+1, err) }
+	}
+
+	src, err := exports.LoadBundle("u1")
+	if err != nil { t.Fatal(err) }
+	got, err := exports.LoadBundle("u2")
+	if err != nil { t.Fatal(err) }
+	counts := []struct {
+		name      string
+		src, got  int
+	}{
+		{"months", len(src.Months), len(got.Months)},
+		{"categories", len(src.Categories), len(got.Categories)},
+		{"plans", len(src.Plans), len(got.Plans)},
+		{"spending", len(src.Spending), len(got.Spending)},
+		{"goals", len(src.Goals), len(got.Goals)},
+		{"goalContributions", len(src.GoalContributions), len(got.GoalContributions)},
+		{"recurringRules", len(src.RecurringRules), len(got.RecurringRules)},
+		{"categorizationRules", len(src.CategorizationRules), len(got.CategorizationRules)},
+		{"planTemplates", len(src.PlanTemplates), len(got.PlanTemplates)},
+		{"notificationChannels", len(src.NotificationChannels), len(got.NotificationChannels)},
+	}
+	for _, c := range counts {
+		if c.src != 1 || c.got != c.src { t.Errorf("%s: exported %d, restored %d; want 1 each", c.name, c.src, c.got) }
+	}
+	if t.Failed() { return }
+
+	if s := got.Spending[0]; s.ID == "s1" || s.UserID != "u2" || s.Amount != money(t, "12.34") || s.Note != "lunch" { t.Errorf("spending = %+v", s) }
+	if !got.Categories[0].Archived { t.Error("category lost archived") }
+	if gc := got.GoalContributions[0]; gc.GoalID != got.Goals[0].ID { t.Errorf("contribution points at %s, goal is %s", gc.GoalID, got.Goals[0].ID) }
+	if r := got.RecurringRules[0]; r.Active || r.RRule != "FREQ=MONTHLY;BYMONTHDAY=1" || r.Amount != models.MoneyFromInt(900) { t.Errorf("recurring rule = %+v", r) }
+	if r := got.CategorizationRules[0]; !r.Active || r.Merchant != "cafe" || r.Priority != 1 { t.Errorf("categorization rule = %+v", r) }
+	if ch := got.NotificationChannels[0]; ch.Enabled || ch.Address != "12345" { t.Errorf("channel = %+v", ch) }
+	tpl := got.PlanTemplates[0]
+	if tpl.Name != "Lean" || len(tpl.Items) != 1 || tpl.Items[0].TemplateID != tpl.ID || tpl.Items[0].PlannedAmount != models.MoneyFromInt(250) { t.Errorf("template = %+v", tpl) }
+
+	items, err := repository.NewGoalRepository(db).ListInstallments("u2", got.Goals[0].ID)
+	if err != nil { t.Fatal(err) }
+	if len(items) != 6 { t.Errorf("restored goal has %d installments, want 6", len(items)) }
+}
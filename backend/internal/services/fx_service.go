@@ -0,0 +1,134 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrNoRate is returned when an amount cannot be converted for lack of an exchange rate
+var ErrNoRate = errors.New("no exchange rate")
+
+// MissingRatesError lists the currency pairs and dates that had no usable rate
+type MissingRatesError struct {
+	Missing []string `json:"missing"` // "KHR/USD@2024-05-01"
+}
+
+func (e *MissingRatesError) Error() string {
+	return fmt.Sprintf("%v: %s", ErrNoRate, strings.Join(e.Missing, ", "))
+}
+
+func (e *MissingRatesError) Unwrap() error { return ErrNoRate }
+
+type FxService struct {
+	repo *repository.FxRepository
+}
+
+func NewFxService(repo *repository.FxRepository) *FxService {
+	return &FxService{repo: repo}
+}
+
+func (s *FxService) ListRates(base, quote string, from, to *time.Time) ([]models.FxRate, error) {
+	return s.repo.ListRates(base, quote, from, to)
+}
+
+// SaveRates validates and upserts rates; dates are truncated to the day
+func (s *FxService) SaveRates(rates []models.FxRate) error {
+	for i := range rates {
+		r := &rates[i]
+		r.Base, r.Quote = strings.ToUpper(r.Base), strings.ToUpper(r.Quote)
+		if len(r.Base) != 3 || len(r.Quote) != 3 || r.Base == r.Quote { return fmt.Errorf("rate %d: invalid currency pair", i+1) }
+		if r.Rate <= 0 { return fmt.Errorf("rate %d: rate must be positive", i+1) }
+		r.Date = dateOnly(r.Date)
+	}
+	return s.repo.UpsertRates(rates)
+}
+
+// ParseFxCSV reads rates from CSV rows "date,base,quote,rate[,source]"; a header row is skipped
+func ParseFxCSV(r io.Reader, source string) ([]models.FxRate, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	records, err := cr.ReadAll()
+	if err != nil { return nil, err }
+	var rates []models.FxRate
+	for i, rec := range records {
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" { continue }
+		if i == 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "date") { continue }
+		if len(rec) < 4 { return nil, fmt.Errorf("line %d: expected date,base,quote,rate", i+1) }
+		d, err := time.Parse("2006-01-02", strings.TrimSpace(rec[0]))
+		if err != nil { return nil, fmt.Errorf("line %d: invalid date", i+1) }
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+		if err != nil { return nil, fmt.Errorf("line %d: invalid rate", i+1) }
+		src := source
+		if len(rec) > 4 && strings.TrimSpace(rec[4]) != "" { src = strings.TrimSpace(rec[4]) }
+		rates = append(rates, models.FxRate{Date: d, Base: strings.TrimSpace(rec[1]), Quote: strings.TrimSpace(rec[2]), Rate: rate, Source: src})
+	}
+	return rates, nil
+}
+
+// Converter converts amounts into a user's base currency, caching rates for the life of one request
+func (s *FxService) Converter(userID string) *Converter {
+	return &Converter{repo: s.repo, Base: s.repo.BaseCurrency(userID), rates: map[string]float64{}, missing: map[string]bool{}}
+}
+
+type Converter struct {
+	repo    *repository.FxRepository
+	Base    string
+	rates   map[string]float64
+	missing map[string]bool
+}
+
+// Convert returns amount (in currency) expressed in the base currency using the latest rate on or before date.
+// Unconvertible amounts count as 0 and are reported by Err.
+func (c *Converter) Convert(amount models.Money, currency string, date time.Time) models.Money {
+	if amount == 0 || currency == "" || currency == c.Base { return amount }
+	rate, ok := c.Rate(currency, c.Base, date)
+	if !ok { return 0 }
+	return amount.MulRate(rate)
+}
+
+// Rate finds the from->to rate for date, falling back to the inverse of to->from
+func (c *Converter) Rate(from, to string, date time.Time) (float64, bool) {
+	if from == to { return 1, true }
+	day := dateOnly(date)
+	key := from + "/" + to + "@" + day.Format("2006-01-02")
+	if r, ok := c.rates[key]; ok { return r, true }
+	if c.missing[key] { return 0, false }
+	rate := 0.0
+	if fx, err := c.repo.LatestRate(from, to, day); err == nil {
+		rate = fx.Rate
+	} else if fx, err := c.repo.LatestRate(to, from, day); err == nil && fx.Rate > 0 {
+		rate = 1 / fx.Rate
+	}
+	if rate == 0 {
+		c.missing[key] = true
+		return 0, false
+	}
+	c.rates[key] = rate
+	return rate, true
+}
+
+// Err reports every pair that could not be converted so far, or nil
+func (c *Converter) Err() error {
+	if len(c.missing) == 0 { return nil }
+	keys := make([]string, 0, len(c.missing))
+	for k := range c.missing { keys = append(keys, k) }
+	sort.Strings(keys)
+	return &MissingRatesError{Missing: keys}
+}
+
+// rowDate is the conversion date of an aggregate row: its day, or the first of its month for plans
+func rowDate(monthKey, day string) time.Time {
+	if t, err := time.Parse("2006-01-02", day); err == nil { return t }
+	t, _ := time.Parse("2006-01", monthKey)
+	return t
+}
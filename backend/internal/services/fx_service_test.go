@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func TestParseFxCSV(t *testing.T) {
+	rates, err := ParseFxCSV(strings.NewReader("date,base,quote,rate,source\n2024-05-01, USD, KHR, 4100\n\n2024-05-02,EUR,USD,1.0725,ecb\n"), "manual")
+	if err != nil { t.Fatal(err) }
+	want := []models.FxRate{
+		{Date: day(2024, time.May, 1), Base: "USD", Quote: "KHR", Rate: 4100, Source: "manual"},
+		{Date: day(2024, time.May, 2), Base: "EUR", Quote: "USD", Rate: 1.0725, Source: "ecb"},
+	}
+	if len(rates) != len(want) { t.Fatalf("rates = %+v, want %+v", rates, want) }
+	for i, w := range want {
+		if g := rates[i]; !g.Date.Equal(w.Date) || g.Base != w.Base || g.Quote != w.Quote || g.Rate != w.Rate || g.Source != w.Source { t.Errorf("rate %d = %+v, want %+v", i, g, w) }
+	}
+
+	for in, msg := range map[string]string{
+		"2024-05-01,USD,KHR\n":                             "line 1: expected date,base,quote,rate",
+		"date,base,quote,rate\n01/05/2024,USD,KHR,4100\n": "line 2: invalid date",
+		"2024-05-01,USD,KHR,lots\n":                        "line 1: invalid rate",
+		"2024-05-01,USD,\"KHR,4100\n":                      "extraneous or missing",
+	} {
+		if _, err := ParseFxCSV(strings.NewReader(in), ""); err == nil || !strings.Contains(err.Error(), msg) { t.Errorf("ParseFxCSV(%q) err = %v, want %q", in, err, msg) }
+	}
+}
+
+func TestConverterRate(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	fx := NewFxService(repository.NewFxRepository(db))
+	err := fx.SaveRates([]models.FxRate{
+		{Date: day(2024, time.May, 1), Base: "usd", Quote: "khr", Rate: 4000},
+		{Date: day(2024, time.May, 10), Base: "USD", Quote: "KHR", Rate: 4100},
+		{Date: day(2024, time.May, 1), Base: "EUR", Quote: "USD", Rate: 1.08},
+		{Date: day(2024, time.May, 1), Base: "USD", Quote: "EUR", Rate: 0.9},
+	})
+	if err != nil { t.Fatal(err) }
+
+	conv := fx.Converter("u1")
+	tests := []struct {
+		name     string
+		from, to string
+		date     time.Time
+		want     float64
+		ok       bool
+	}{
+		{"same currency", "KHR", "KHR", day(2020, time.January, 1), 1, true},
+		{"direct", "USD", "KHR", day(2024, time.May, 5), 4000, true},
+		{"latest on or before", "USD", "KHR", day(2024, time.June, 1), 4100, true},
+		{"inverse fallback", "KHR", "USD", day(2024, time.May, 12), 1.0 / 4100, true},
+		{"direct over inverse", "EUR", "USD", day(2024, time.May, 2), 1.08, true},
+		{"before the first rate", "KHR", "USD", day(2024, time.April, 30), 0, false},
+		{"unknown pair", "GBP", "USD", day(2024, time.May, 2), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := conv.Rate(tt.from, tt.to, tt.date)
+		if ok != tt.ok || got != tt.want { t.Errorf("%s: Rate(%s, %s) = %v, %v; want %v, %v", tt.name, tt.from, tt.to, got, ok, tt.want, tt.ok) }
+	}
+
+	if got := conv.Convert(models.MoneyFromInt(8200), "KHR", day(2024, time.May, 12)); got != models.MoneyFromInt(2) { t.Errorf("Convert = %s, want 2", got) }
+	if got := conv.Convert(models.MoneyFromInt(5), "GBP", day(2024, time.May, 2)); got != 0 { t.Errorf("unconvertible amount = %s, want 0", got) }
+	var missing *MissingRatesError
+	if err := conv.Err(); !errors.As(err, &missing) || !errors.Is(err, ErrNoRate) || strings.Join(missing.Missing, " ") != "GBP/USD@2024-05-02 KHR/USD@2024-04-30" {
+		t.Errorf("Err = %v", err)
+	}
+	if err := fx.Converter("u1").Err(); err != nil { t.Errorf("a new converter reports %v", err) }
+}
@@ -1,11 +1,19 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"time"
 	"achieving-backend/internal/models"
 	"achieving-backend/internal/repository"
 )
 
+// ErrDerivedStatus is returned when a client sets a goal status other than the derived one
+var ErrDerivedStatus = errors.New("goal status is derived from contributions")
+
+// ErrScheduleTooFine is returned when the target cannot give every installment at least one cent
+var ErrScheduleTooFine = errors.New("target amount is smaller than one cent per installment")
+
 type GoalService struct {
 	repo *repository.GoalRepository
 }
@@ -18,12 +26,23 @@ func (s *GoalService) ListGoals(userID string) ([]models.Goal, error) {
 	return s.repo.ListGoals(userID)
 }
 
-func (s *GoalService) CreateGoal(userID, title, description, category, saveFrequency string, duration *int, startDate, endDate, targetDate *time.Time, targetAmount *float64) (*models.Goal, error) {
-	return s.repo.CreateGoal(userID, title, description, category, saveFrequency, duration, startDate, endDate, targetDate, targetAmount)
+// CreateGoal saves a goal and its schedule in one transaction; a non-zero opening amount becomes its
+// first contribution
+func (s *GoalService) CreateGoal(userID, title, description, category, saveFrequency string, duration *int, startDate, endDate, targetDate *time.Time, targetAmount *models.Money, currency string, opening models.Money) (*models.Goal, error) {
+	return s.repo.CreateGoal(userID, title, description, category, saveFrequency, duration, startDate, endDate, targetDate, targetAmount, currency, opening, BuildSchedule)
 }
 
+// scheduleFields are the goal columns that define its savings schedule
+var scheduleFields = []string{"save_frequency", "duration", "start_date", "end_date", "target_date", "target_amount"}
+
+// UpdateGoal saves the updates and, when a schedule field changed, rebuilds the installments from the
+// updated goal in the same transaction, so an invalid schedule rejects the whole update
 func (s *GoalService) UpdateGoal(userID, id string, updates map[string]interface{}) (int64, error) {
-	return s.repo.UpdateGoal(userID, id, updates)
+	var schedule repository.ScheduleFunc
+	for _, f := range scheduleFields {
+		if _, ok := updates[f]; ok { schedule = BuildSchedule; break }
+	}
+	return s.repo.UpdateGoal(userID, id, updates, schedule)
 }
 
 func (s *GoalService) FindGoal(userID, id string) (*models.Goal, error) {
@@ -32,4 +51,167 @@ func (s *GoalService) FindGoal(userID, id string) (*models.Goal, error) {
 
 func (s *GoalService) DeleteGoal(userID, id string) (int64, error) {
 	return s.repo.DeleteGoal(userID, id)
-}
\ No newline at end of file
+}
+
+func (s *GoalService) ListContributions(userID, goalID string) ([]models.GoalContribution, error) {
+	return s.repo.ListContributions(userID, goalID)
+}
+
+func (s *GoalService) AddContribution(userID, goalID string, amount models.Money, date time.Time, note string) (*models.GoalContribution, error) {
+	return s.repo.CreateContribution(userID, goalID, amount, date, note)
+}
+
+// AddContributionIn records a contribution given in another currency, converting it into the goal's
+// currency at the rate of the contribution date. The original amount is kept in the note.
+func (s *GoalService) AddContributionIn(conv *Converter, userID, goalID string, amount models.Money, currency string, date time.Time, note string) (*models.GoalContribution, error) {
+	g, err := s.repo.FindGoal(userID, goalID)
+	if err != nil { return nil, err }
+	if currency == "" || currency == g.Currency { return s.repo.CreateContribution(userID, goalID, amount, date, note) }
+	rate, ok := conv.Rate(currency, g.Currency, date)
+	if !ok { return nil, conv.Err() }
+	original := fmt.Sprintf("%s %s", amount, currency)
+	if note == "" { note = original } else { note = note + " (" + original + ")" }
+	return s.repo.CreateContribution(userID, goalID, amount.MulRate(rate).RoundTo(2), date, note)
+}
+
+// WithBase fills the goals' base-currency figures: the target at today's rate and the saved amount
+// as the sum of contributions at the rate of each contribution date. Goals whose rates are missing are left unconverted.
+func (s *GoalService) WithBase(conv *Converter, userID string, goals []models.Goal) error {
+	byDay := map[string][]repository.GoalDayAmount{}
+	for _, g := range goals {
+		if g.Currency != conv.Base {
+			rows, err := s.repo.ContributionsByDay(userID)
+			if err != nil { return err }
+			for _, r := range rows { byDay[r.GoalID] = append(byDay[r.GoalID], r) }
+			break
+		}
+	}
+	today := time.Now()
+	for i := range goals {
+		g := &goals[i]
+		g.BaseCurrency = conv.Base
+		if g.Currency == conv.Base {
+			g.TargetAmountBase, g.CurrentAmountBase = g.TargetAmount, g.CurrentAmount
+			continue
+		}
+		ok := true
+		if g.TargetAmount != nil {
+			rate, found := conv.Rate(g.Currency, conv.Base, today)
+			if found {
+				v := g.TargetAmount.MulRate(rate)
+				g.TargetAmountBase = &v
+			}
+			ok = found
+		}
+		var current models.Money
+		for _, r := range byDay[g.ID] {
+			rate, found := conv.Rate(g.Currency, conv.Base, rowDate("", r.Day))
+			if !found { ok = false; break }
+			current += r.Amount.MulRate(rate)
+		}
+		if ok { g.CurrentAmountBase = &current }
+	}
+	return nil
+}
+
+func (s *GoalService) DeleteContribution(userID, goalID, id string) (int64, error) {
+	return s.repo.DeleteContribution(userID, goalID, id)
+}
+
+// SetCurrentAmount keeps the legacy "set saved amount" API working by recording
+// the difference as a balance adjustment in the ledger
+func (s *GoalService) SetCurrentAmount(userID, goalID string, amount models.Money) error {
+	_, err := s.repo.AdjustToAmount(userID, goalID, amount, time.Now(), "Balance adjustment")
+	return err
+}
+
+// SetStatus accepts only the status derived from the contributions (after re-deriving it), since
+// any other value would be overwritten by the next contribution
+func (s *GoalService) SetStatus(userID, goalID, status string) error {
+	g, err := s.repo.RecomputeProgress(userID, goalID)
+	if err != nil { return err }
+	if g.Status != status { return fmt.Errorf("%w: the goal is %s", ErrDerivedStatus, g.Status) }
+	return nil
+}
+
+// Schedule returns the goal's installments, generating them on first access for goals created before
+// schedules were persisted. Such goals whose target is too small to schedule have no installments.
+func (s *GoalService) Schedule(userID, goalID string) ([]models.GoalInstallment, error) {
+	items, err := s.repo.ListInstallments(userID, goalID)
+	if err != nil || len(items) > 0 { return items, err }
+	items, err = s.RegenerateSchedule(userID, goalID)
+	if errors.Is(err, ErrScheduleTooFine) { return []models.GoalInstallment{}, nil }
+	return items, err
+}
+
+func (s *GoalService) RegenerateSchedule(userID, goalID string) ([]models.GoalInstallment, error) {
+	g, err := s.repo.FindGoal(userID, goalID)
+	if err != nil { return nil, err }
+	items, err := BuildSchedule(g)
+	if err != nil { return nil, err }
+	return s.repo.ReplaceSchedule(userID, goalID, items)
+}
+
+// maxSchedulePeriods bounds schedules built from far-apart dates (about ten years of daily saving)
+const maxSchedulePeriods = 3660
+
+// BuildSchedule splits TargetAmount into equal installments due every SaveFrequency period
+// from StartDate through EndDate. Without an end date, the schedule spans Duration months.
+// Installments are whole cents: the leftover cents go one each to the first installments and any
+// fraction of a cent to the last, so they sum to the target exactly. It returns ErrScheduleTooFine
+// when the target has fewer cents than there are installments.
+func BuildSchedule(g *models.Goal) ([]models.GoalInstallment, error) {
+	if g.StartDate == nil || g.TargetAmount == nil || *g.TargetAmount <= 0 { return nil, nil }
+	start := *g.StartDate
+	var end time.Time
+	switch {
+	case g.EndDate != nil:
+		end = *g.EndDate
+	case g.TargetDate != nil:
+		end = *g.TargetDate
+	case g.Duration != nil && *g.Duration > 0:
+		end = addMonths(start, *g.Duration).AddDate(0, 0, -1)
+	default:
+		return nil, nil
+	}
+	var dues []time.Time
+	for i := 0; len(dues) < maxSchedulePeriods; i++ {
+		due := nextDue(start, g.SaveFrequency, i)
+		if due.After(end) { break }
+		dues = append(dues, due)
+	}
+	if len(dues) == 0 { return nil, nil }
+	n := models.Money(len(dues))
+	cent := models.MoneyFromInt(1) / 100
+	cents := *g.TargetAmount / cent
+	if cents < n { return nil, ErrScheduleTooFine }
+	items := make([]models.GoalInstallment, len(dues))
+	for i, due := range dues {
+		per := cents / n * cent
+		if models.Money(i) < cents%n { per += cent }
+		items[i] = models.GoalInstallment{Sequence: i + 1, DueDate: due, PlannedAmount: per}
+	}
+	items[len(items)-1].PlannedAmount += *g.TargetAmount % cent
+	return items, nil
+}
+
+// nextDue returns the i-th due date from start; unknown frequencies default to monthly
+func nextDue(start time.Time, frequency string, i int) time.Time {
+	switch frequency {
+	case "daily":
+		return start.AddDate(0, 0, i)
+	case "weekly":
+		return start.AddDate(0, 0, 7*i)
+	default:
+		return addMonths(start, i)
+	}
+}
+
+// addMonths adds n calendar months, clamping to the last day of the target month (Jan 31 + 1 = Feb 28/29)
+func addMonths(t time.Time, n int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, n, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay { day = lastDay }
+	return first.AddDate(0, 0, day-1)
+}
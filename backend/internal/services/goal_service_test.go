@@ -0,0 +1,139 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func newGoalService(t *testing.T) *GoalService {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	return NewGoalService(repository.NewGoalRepository(db))
+}
+
+func TestGoalStatusFollowsTargetChanges(t *testing.T) {
+	svc := newGoalService(t)
+	target := models.MoneyFromInt(100)
+	g, err := svc.CreateGoal("u1", "Bike", "", "", "monthly", nil, nil, nil, nil, &target, "USD", 0)
+	if err != nil { t.Fatal(err) }
+	if err := svc.SetCurrentAmount("u1", g.ID, models.MoneyFromInt(100)); err != nil { t.Fatal(err) }
+	if g, _ = svc.FindGoal("u1", g.ID); g.Status != "completed" { t.Fatalf("status = %s, want completed", g.Status) }
+
+	if _, err := svc.UpdateGoal("u1", g.ID, map[string]interface{}{"target_amount": models.MoneyFromInt(150)}); err != nil { t.Fatal(err) }
+	if g, _ = svc.FindGoal("u1", g.ID); g.Status != "in_progress" { t.Errorf("after raising the target: status = %s, want in_progress", g.Status) }
+
+	if err := svc.SetStatus("u1", g.ID, "completed"); !errors.Is(err, ErrDerivedStatus) { t.Errorf("setting a non-derived status: err = %v", err) }
+	if err := svc.SetStatus("u1", g.ID, "in_progress"); err != nil { t.Errorf("setting the derived status: %v", err) }
+}
+
+func TestSetCurrentAmountRecordsDeltaOnce(t *testing.T) {
+	svc := newGoalService(t)
+	target := models.MoneyFromInt(100)
+	g, err := svc.CreateGoal("u1", "Bike", "", "", "monthly", nil, nil, nil, nil, &target, "USD", 0)
+	if err != nil { t.Fatal(err) }
+	if _, err := svc.AddContribution("u1", g.ID, models.MoneyFromInt(30), time.Now(), ""); err != nil { t.Fatal(err) }
+	for i := 0; i < 2; i++ {
+		if err := svc.SetCurrentAmount("u1", g.ID, models.MoneyFromInt(50)); err != nil { t.Fatal(err) }
+	}
+	items, _ := svc.ListContributions("u1", g.ID)
+	if len(items) != 2 { t.Fatalf("%d contributions, want the deposit and one adjustment", len(items)) }
+	if g, _ = svc.FindGoal("u1", g.ID); g.CurrentAmount == nil || *g.CurrentAmount != models.MoneyFromInt(50) { t.Errorf("current amount = %v, want 50", g.CurrentAmount) }
+}
+
+func TestBuildSchedule(t *testing.T) {
+	day := func(s string) *time.Time { d, _ := time.Parse("2006-01-02", s); return &d }
+	money := func(s string) *models.Money { m, err := models.ParseMoney(s); if err != nil { t.Fatal(err) }; return &m }
+	months := func(n int) *int { return &n }
+	tests := []struct {
+		name      string
+		goal      models.Goal
+		count     int
+		first     string // amount of the first installment
+		last      string // amount of the last installment
+		lastDue   string
+		err       error
+	}{
+		{"monthly even", models.Goal{SaveFrequency: "monthly", StartDate: day("2026-01-31"), Duration: months(12), TargetAmount: money("1200")}, 12, "100", "100", "2026-12-31", nil},
+		{"monthly remainder", models.Goal{SaveFrequency: "monthly", StartDate: day("2026-01-01"), EndDate: day("2026-03-31"), TargetAmount: money("100")}, 3, "33.34", "33.33", "2026-03-01", nil},
+		{"weekly", models.Goal{SaveFrequency: "weekly", StartDate: day("2026-01-01"), EndDate: day("2026-01-29"), TargetAmount: money("10")}, 5, "2", "2", "2026-01-29", nil},
+		{"daily leftover cents", models.Goal{SaveFrequency: "daily", StartDate: day("2026-01-01"), EndDate: day("2028-09-26"), TargetAmount: money("15")}, 1000, "0.02", "0.01", "2028-09-26", nil},
+		{"sub-cent target", models.Goal{SaveFrequency: "weekly", StartDate: day("2026-01-01"), EndDate: day("2026-01-15"), TargetAmount: money("1.0005")}, 3, "0.34", "0.3305", "2026-01-15", nil},
+		{"target below period count", models.Goal{SaveFrequency: "daily", StartDate: day("2026-01-01"), EndDate: day("2026-01-10"), TargetAmount: money("0.09")}, 0, "", "", "", ErrScheduleTooFine},
+		{"no end", models.Goal{SaveFrequency: "daily", StartDate: day("2026-01-01"), TargetAmount: money("10")}, 0, "", "", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items, err := BuildSchedule(&tt.goal)
+			if !errors.Is(err, tt.err) { t.Fatalf("err = %v, want %v", err, tt.err) }
+			if len(items) != tt.count { t.Fatalf("%d installments, want %d", len(items), tt.count) }
+			if tt.count == 0 { return }
+			var sum models.Money
+			for i, it := range items {
+				if it.PlannedAmount <= 0 { t.Errorf("installment %d is %s", it.Sequence, it.PlannedAmount) }
+				if it.Sequence != i+1 { t.Errorf("installment %d has sequence %d", i, it.Sequence) }
+				sum += it.PlannedAmount
+			}
+			if sum != *tt.goal.TargetAmount { t.Errorf("sum = %s, want %s", sum, tt.goal.TargetAmount) }
+			if got := items[0].PlannedAmount; got != *money(tt.first) { t.Errorf("first = %s, want %s", got, tt.first) }
+			last := items[len(items)-1]
+			if got := last.PlannedAmount; got != *money(tt.last) { t.Errorf("last = %s, want %s", got, tt.last) }
+			if got := last.DueDate.Format("2006-01-02"); got != tt.lastDue { t.Errorf("last due = %s, want %s", got, tt.lastDue) }
+		})
+	}
+}
+
+func TestCreateGoalRejectsTooFineSchedule(t *testing.T) {
+	svc := newGoalService(t)
+	start, end := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2028, 9, 26, 0, 0, 0, 0, time.UTC)
+	target := models.MoneyFromInt(5)
+	if _, err := svc.CreateGoal("u1", "Coins", "", "", "daily", nil, &start, &end, nil, &target, "USD", 0); !errors.Is(err, ErrScheduleTooFine) { t.Fatalf("err = %v, want ErrScheduleTooFine", err) }
+	if goals, _ := svc.ListGoals("u1"); len(goals) != 0 { t.Errorf("%d goals saved", len(goals)) }
+
+	target = models.MoneyFromInt(15)
+	g, err := svc.CreateGoal("u1", "Coins", "", "", "daily", nil, &start, &end, nil, &target, "USD", 0)
+	if err != nil { t.Fatal(err) }
+	if _, err := svc.UpdateGoal("u1", g.ID, map[string]interface{}{"target_amount": models.MoneyFromInt(1)}); !errors.Is(err, ErrScheduleTooFine) { t.Fatalf("update err = %v, want ErrScheduleTooFine", err) }
+	if g, _ = svc.FindGoal("u1", g.ID); *g.TargetAmount != target { t.Errorf("target changed to %s", g.TargetAmount) }
+}
+
+func TestCreateGoalStartsInDerivedStatus(t *testing.T) {
+	svc := newGoalService(t)
+	target := models.MoneyFromInt(100)
+	g, err := svc.CreateGoal("u1", "Bike", "", "", "monthly", nil, nil, nil, nil, &target, "USD", 0)
+	if err != nil { t.Fatal(err) }
+	if g.Status != "not_started" { t.Errorf("new goal status = %s, want not_started", g.Status) }
+	if err := svc.SetStatus("u1", g.ID, g.Status); err != nil { t.Errorf("setting the new goal's own status: %v", err) }
+
+	g, err = svc.CreateGoal("u1", "Car", "", "", "monthly", nil, nil, nil, nil, &target, "USD", models.MoneyFromInt(40))
+	if err != nil { t.Fatal(err) }
+	if g.Status != "in_progress" || g.CurrentAmount == nil || *g.CurrentAmount != models.MoneyFromInt(40) { t.Errorf("goal with an opening balance = %s, %v", g.Status, g.CurrentAmount) }
+	items, _ := svc.ListContributions("u1", g.ID)
+	if len(items) != 1 || items[0].Note != "Opening balance" || items[0].Amount != models.MoneyFromInt(40) { t.Errorf("contributions = %+v, want the opening balance", items) }
+}
+
+func TestGoalWriteRollsBackWithItsSchedule(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	repo := repository.NewGoalRepository(db)
+	svc := NewGoalService(repo)
+	failing := func(*models.Goal) ([]models.GoalInstallment, error) { return nil, errors.New("disk full") }
+	start, end := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)
+	target := models.MoneyFromInt(300)
+
+	if _, err := repo.CreateGoal("u1", "Trip", "", "", "monthly", nil, &start, &end, nil, &target, "USD", models.MoneyFromInt(10), failing); err == nil { t.Fatal("create succeeded without its schedule") }
+	if goals, _ := svc.ListGoals("u1"); len(goals) != 0 { t.Fatalf("%d goals saved without a schedule", len(goals)) }
+
+	g, err := svc.CreateGoal("u1", "Trip", "", "", "monthly", nil, &start, &end, nil, &target, "USD", 0)
+	if err != nil { t.Fatal(err) }
+	if _, err := repo.UpdateGoal("u1", g.ID, map[string]interface{}{"target_amount": models.MoneyFromInt(600)}, failing); err == nil { t.Fatal("update succeeded without its schedule") }
+	if g, _ = svc.FindGoal("u1", g.ID); *g.TargetAmount != target { t.Errorf("target changed to %s with a stale schedule", g.TargetAmount) }
+
+	if _, err := svc.UpdateGoal("u1", g.ID, map[string]interface{}{"target_amount": models.MoneyFromInt(600)}); err != nil { t.Fatal(err) }
+	items, _ := repo.ListInstallments("u1", g.ID)
+	if len(items) != 3 || items[0].PlannedAmount != models.MoneyFromInt(200) { t.Errorf("installments after the update = %+v, want 3 of 200", items) }
+}
@@ -0,0 +1,355 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrImportRows is returned when one or more statement rows could not be parsed
+var ErrImportRows = errors.New("statement contains invalid rows")
+
+// CSVMapping tells the importer which CSV columns hold which fields.
+// Columns are referenced by header name or by 0-based index ("0", "1", ...).
+type CSVMapping struct {
+	Date            string `json:"date"`
+	Amount          string `json:"amount"`
+	Description     string `json:"description"`
+	Merchant        string `json:"merchant"`
+	Category        string `json:"category"`
+	Currency        string `json:"currency"`
+	DateFormat      string `json:"dateFormat"` // Go layout; common formats are tried when empty
+	Delimiter       string `json:"delimiter"`  // defaults to ","
+	HasHeader       *bool  `json:"hasHeader"`  // defaults to true
+	DefaultCategory string `json:"defaultCategory"` // used when no categorization rule matches
+	DefaultCurrency string `json:"defaultCurrency"` // empty means the user's base currency
+	// DecimalSeparator is "." (default) or ","; the other one may only group thousands
+	DecimalSeparator string `json:"decimalSeparator"`
+}
+
+// ErrAmbiguousAmount is returned for an amount whose grouping separator is not followed by groups of
+// three digits, e.g. "12,50" read with a "." decimal separator, rather than guessing a value 100x off
+var ErrAmbiguousAmount = errors.New("ambiguous amount separators")
+
+// ImportOptions control how statement rows become entries
+type ImportOptions struct {
+	// Kind forces every row to "spending" or "earning"; empty means negative
+	// amounts are spending and positive amounts are earnings (bank convention)
+	Kind            string
+	DryRun          bool
+	// DefaultCategory is given to spending rows without a category that no categorization rule matches
+	DefaultCategory string
+}
+
+// ImportRow is one parsed statement transaction
+type ImportRow struct {
+	Line        int          `json:"line"`
+	Kind        string       `json:"kind"`
+	Date        time.Time    `json:"date"`
+	Amount      models.Money `json:"amount"`
+	Description string       `json:"description"`
+	Merchant    string       `json:"merchant,omitempty"`
+	Category    string       `json:"category,omitempty"`
+	Currency    string       `json:"currency,omitempty"`
+}
+
+// ImportRowError reports a row that could not be parsed
+type ImportRowError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// ImportResult is returned for both dry runs and committed imports
+type ImportResult struct {
+	DryRun     bool                   `json:"dryRun"`
+	Spending   []models.SpendingEntry `json:"spending"`
+	Earnings   []models.EarningEntry  `json:"earnings"`
+	Duplicates []ImportRow            `json:"duplicates"`
+	Errors     []ImportRowError       `json:"errors"`
+}
+
+type ImportService struct {
+	repo     *repository.SpendingRepository
+	spending *SpendingService
+}
+
+func NewImportService(repo *repository.SpendingRepository) *ImportService {
+	return &ImportService{repo: repo, spending: NewSpendingService(repo)}
+}
+
+// Import creates entries for every non-duplicate row in a single transaction, then checks each month
+// and category it added spending to against the budget alert thresholds (conv converts to the base currency).
+// Rows with parse errors abort the import with ErrImportRows unless it is a dry run.
+func (s *ImportService) Import(conv *Converter, userID string, rows []ImportRow, rowErrs []ImportRowError, opts ImportOptions) (*ImportResult, error) {
+	res := &ImportResult{DryRun: opts.DryRun, Spending: []models.SpendingEntry{}, Earnings: []models.EarningEntry{}, Duplicates: []ImportRow{}, Errors: rowErrs}
+	if res.Errors == nil { res.Errors = []ImportRowError{} }
+	if len(rowErrs) > 0 && !opts.DryRun { return res, ErrImportRows }
+
+	for i := range rows {
+		switch opts.Kind {
+		case "spending", "earning":
+			rows[i].Kind = opts.Kind
+		}
+		rows[i].Amount = rows[i].Amount.Abs()
+	}
+	if err := s.categorize(userID, rows, opts.DefaultCategory); err != nil { return nil, err }
+
+	monthSet := map[string]bool{}
+	for _, row := range rows { monthSet[row.Date.Format("2006-01")] = true }
+	months := make([]string, 0, len(monthSet))
+	for mk := range monthSet { months = append(months, mk) }
+	sort.Strings(months)
+
+	// Each existing entry matches one row, so identical rows within a statement (two coffees on
+	// the same day) are all imported the first time and all reported as duplicates on re-import
+	existing, err := s.repo.CountDuplicateKeys(userID, months)
+	if err != nil { return nil, err }
+	var fresh []ImportRow
+	for _, row := range rows {
+		key := repository.EntryKey(row.Kind, row.Date, row.Amount, row.Description)
+		if existing[key] > 0 {
+			existing[key]--
+			res.Duplicates = append(res.Duplicates, row)
+			continue
+		}
+		fresh = append(fresh, row)
+	}
+
+	if opts.DryRun {
+		for _, row := range fresh {
+			mk := row.Date.Format("2006-01")
+			if row.Kind == "earning" {
+				res.Earnings = append(res.Earnings, models.EarningEntry{UserID: userID, Source: row.Description, Amount: row.Amount, Currency: row.Currency, Date: row.Date, MonthKey: mk})
+			} else {
+				res.Spending = append(res.Spending, models.SpendingEntry{UserID: userID, Amount: row.Amount, Currency: row.Currency, Category: row.Category, Date: row.Date, MonthKey: mk, Note: row.Description, Merchant: row.Merchant})
+			}
+		}
+		return res, nil
+	}
+
+	err = s.repo.Transaction(func(tx *repository.SpendingRepository) error {
+		for _, mk := range months {
+			if err := tx.EnsureMonth(userID, mk); err != nil { return err }
+		}
+		for _, row := range fresh {
+			if row.Kind == "earning" {
+				item, err := tx.CreateEarning(userID, row.Description, row.Amount, row.Date, row.Currency)
+				if err != nil { return err }
+				res.Earnings = append(res.Earnings, *item)
+			} else {
+				entry, err := tx.CreateSpending(userID, row.Amount, row.Category, row.Date, row.Description, row.Merchant, row.Currency)
+				if err != nil { return err }
+				res.Spending = append(res.Spending, *entry)
+			}
+		}
+		return nil
+	})
+	if err != nil { return nil, err }
+	checked := map[string]bool{}
+	for _, e := range res.Spending {
+		if checked[e.MonthKey+"|"+e.Category] { continue }
+		checked[e.MonthKey+"|"+e.Category] = true
+		s.spending.alertBudget(conv, userID, e.MonthKey, e.Category)
+	}
+	return res, nil
+}
+
+// ParseCSVStatement parses CSV rows according to the mapping
+func ParseCSVStatement(r io.Reader, m CSVMapping) ([]ImportRow, []ImportRowError, error) {
+	if m.Date == "" || m.Amount == "" { return nil, nil, errors.New("mapping requires date and amount columns") }
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	if m.Delimiter != "" {
+		d := []rune(m.Delimiter)
+		if m.Delimiter == `\t` { d = []rune{'\t'} }
+		cr.Comma = d[0]
+	}
+	decimal := '.'
+	switch m.DecimalSeparator {
+	case "", ".":
+	case ",":
+		decimal = ','
+	default:
+		return nil, nil, fmt.Errorf("decimal separator must be \".\" or \",\", not %q", m.DecimalSeparator)
+	}
+	records, err := cr.ReadAll()
+	if err != nil { return nil, nil, err }
+	if len(records) == 0 { return nil, nil, nil }
+
+	hasHeader := m.HasHeader == nil || *m.HasHeader
+	var header []string
+	start := 0
+	if hasHeader {
+		header = records[0]
+		start = 1
+	}
+	resolve := func(col string) (int, error) {
+		if col == "" { return -1, nil }
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), strings.TrimSpace(col)) { return i, nil }
+		}
+		if idx, err := strconv.Atoi(col); err == nil && idx >= 0 { return idx, nil }
+		return -1, fmt.Errorf("unknown column %q", col)
+	}
+	dateCol, err := resolve(m.Date)
+	if err != nil { return nil, nil, err }
+	amountCol, err := resolve(m.Amount)
+	if err != nil { return nil, nil, err }
+	descCol, err := resolve(m.Description)
+	if err != nil { return nil, nil, err }
+	merchantCol, err := resolve(m.Merchant)
+	if err != nil { return nil, nil, err }
+	catCol, err := resolve(m.Category)
+	if err != nil { return nil, nil, err }
+	curCol, err := resolve(m.Currency)
+	if err != nil { return nil, nil, err }
+
+	field := func(rec []string, idx int) string {
+		if idx < 0 || idx >= len(rec) { return "" }
+		return strings.TrimSpace(rec[idx])
+	}
+	var rows []ImportRow
+	var rowErrs []ImportRowError
+	for i := start; i < len(records); i++ {
+		rec := records[i]
+		line := i + 1
+		if len(rec) == 1 && strings.TrimSpace(rec[0]) == "" { continue }
+		d, err := parseStatementDate(field(rec, dateCol), m.DateFormat)
+		if err != nil { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid date"}); continue }
+		amt, err := parseStatementAmount(field(rec, amountCol), decimal)
+		if errors.Is(err, ErrAmbiguousAmount) { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "ambiguous amount; set the mapping's decimalSeparator"}); continue }
+		if err != nil { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid amount"}); continue }
+		if amt == 0 { continue }
+		cur := strings.ToUpper(field(rec, curCol))
+		if cur == "" { cur = strings.ToUpper(m.DefaultCurrency) }
+		if cur != "" && !currencyRe.MatchString(cur) { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid currency"}); continue }
+		rows = append(rows, ImportRow{Line: line, Kind: kindForAmount(amt), Date: d, Amount: amt, Description: field(rec, descCol), Merchant: field(rec, merchantCol), Category: field(rec, catCol), Currency: cur})
+	}
+	return rows, rowErrs, nil
+}
+
+var (
+	ofxTxnRe    = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+	ofxTagRe    = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+	ofxCurDefRe = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Z]{3})`)
+	currencyRe  = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+// ParseOFXStatement parses the STMTTRN records of an OFX/QFX file (SGML 1.x or XML 2.x).
+// Line numbers in the result refer to the transaction's position in the file; rows take the statement's CURDEF currency
+// and the payee NAME as their merchant.
+func ParseOFXStatement(r io.Reader) ([]ImportRow, []ImportRowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil { return nil, nil, err }
+	if !bytes.Contains(bytes.ToUpper(data), []byte("<OFX>")) { return nil, nil, errors.New("not an OFX document") }
+	currency := ""
+	if m := ofxCurDefRe.FindSubmatch(data); m != nil { currency = strings.ToUpper(string(m[1])) }
+	var rows []ImportRow
+	var rowErrs []ImportRowError
+	for i, match := range ofxTxnRe.FindAllSubmatch(data, -1) {
+		line := i + 1
+		tags := map[string]string{}
+		for _, tm := range ofxTagRe.FindAllSubmatch(match[1], -1) {
+			tags[strings.ToUpper(string(tm[1]))] = strings.TrimSpace(string(tm[2]))
+		}
+		posted := tags["DTPOSTED"]
+		if len(posted) < 8 { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid date"}); continue }
+		d, err := time.Parse("20060102", posted[:8])
+		if err != nil { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid date"}); continue }
+		amt, err := parseStatementAmount(tags["TRNAMT"], '.')
+		if err != nil { rowErrs = append(rowErrs, ImportRowError{Line: line, Error: "invalid amount"}); continue }
+		if amt == 0 { continue }
+		desc := tags["NAME"]
+		if memo := tags["MEMO"]; memo != "" {
+			if desc == "" { desc = memo } else if !strings.EqualFold(desc, memo) { desc = desc + " - " + memo }
+		}
+		rows = append(rows, ImportRow{Line: line, Kind: kindForAmount(amt), Date: d, Amount: amt, Description: desc, Merchant: tags["NAME"], Currency: currency})
+	}
+	return rows, rowErrs, nil
+}
+
+// categorize fills in the category of spending rows that have none from the user's categorization rules,
+// falling back to defaultCategory
+func (s *ImportService) categorize(userID string, rows []ImportRow, defaultCategory string) error {
+	var rules []models.CategorizationRule
+	loaded := false
+	for i := range rows {
+		row := &rows[i]
+		if row.Kind == "earning" || row.Category != "" { continue }
+		if !loaded {
+			var err error
+			if rules, err = s.repo.CategorizationRules(userID); err != nil { return err }
+			loaded = true
+		}
+		row.Category = defaultCategory
+		if rule := MatchRule(rules, row.Description, row.Merchant, row.Amount, s.repo.CurrencyOr(userID, row.Currency)); rule != nil { row.Category = rule.Category }
+	}
+	return nil
+}
+
+func kindForAmount(amt models.Money) string {
+	if amt > 0 { return "earning" }
+	return "spending"
+}
+
+func keepDigit(r rune) rune {
+	if r >= '0' && r <= '9' { return r }
+	return -1
+}
+
+func keepNumber(r rune) rune {
+	if r == '-' || r == '+' { return r }
+	return keepDigit(r)
+}
+
+var statementDateLayouts = []string{"2006-01-02", time.RFC3339, "2006/01/02", "01/02/2006", "1/2/2006", "02.01.2006", "20060102"}
+
+func parseStatementDate(s, layout string) (time.Time, error) {
+	if layout != "" { return time.Parse(layout, s) }
+	for _, l := range statementDateLayouts {
+		if t, err := time.Parse(l, s); err == nil { return t, nil }
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseStatementAmount accepts "1,234.56", "-12.00", "(12.00)", "$12" and trailing-minus forms, or
+// "1.234,56" with a "," decimal separator. The other separator must group thousands in threes.
+func parseStatementAmount(s string, decimal rune) (models.Money, error) {
+	s = strings.TrimSpace(s)
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = s[1 : len(s)-1]
+	}
+	if strings.HasSuffix(s, "-") {
+		neg = true
+		s = strings.TrimSuffix(s, "-")
+	}
+	group := ","
+	if decimal == ',' { group = "." }
+	intPart, frac, hasFrac := strings.Cut(s, string(decimal))
+	if strings.ContainsAny(frac, group+string(decimal)) { return 0, fmt.Errorf("%w: %q", ErrAmbiguousAmount, s) }
+	if groups := strings.Split(intPart, group); len(groups) > 1 {
+		for i, g := range groups {
+			digits := len(strings.Map(keepDigit, g))
+			if (i == 0 && (digits == 0 || digits > 3)) || (i > 0 && digits != 3) { return 0, fmt.Errorf("%w: %q", ErrAmbiguousAmount, s) }
+		}
+	}
+	num := strings.Map(keepNumber, intPart)
+	if hasFrac { num += "." + strings.Map(keepDigit, frac) }
+	v, err := models.ParseMoney(num)
+	if err != nil { return 0, err }
+	if neg { v = -v }
+	return v, nil
+}
@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func TestParseCSVStatementMapping(t *testing.T) {
+	noHeader := false
+	tests := []struct {
+		name    string
+		input   string
+		mapping CSVMapping
+		want    []ImportRow
+		errs    []int // lines reported as invalid
+	}{
+		{
+			name:    "header names",
+			input:   "Booked,Details,Payee,Value,Cur\n2026-03-01,Coffee,Cafe Nero,-3.50,eur\n2026-03-02,Salary,ACME,\"1,200.00\",\n",
+			mapping: CSVMapping{Date: "booked", Amount: "Value", Description: "Details", Merchant: "Payee", Currency: "Cur", DefaultCurrency: "usd"},
+			want: []ImportRow{
+				{Line: 2, Kind: "spending", Date: day(2026, 3, 1), Amount: money(t, "-3.50"), Description: "Coffee", Merchant: "Cafe Nero", Currency: "EUR"},
+				{Line: 3, Kind: "earning", Date: day(2026, 3, 2), Amount: money(t, "1200"), Description: "Salary", Merchant: "ACME", Currency: "USD"},
+			},
+		},
+		{
+			name:    "indexes without header",
+			input:   "01.03.2026;(12.00);Groceries;food\n02.03.2026;5.00-;Refund;\n",
+			mapping: CSVMapping{Date: "0", Amount: "1", Description: "2", Category: "3", Delimiter: ";", HasHeader: &noHeader},
+			want: []ImportRow{
+				{Line: 1, Kind: "spending", Date: day(2026, 3, 1), Amount: money(t, "-12"), Description: "Groceries", Category: "food"},
+				{Line: 2, Kind: "spending", Date: day(2026, 3, 2), Amount: money(t, "-5"), Description: "Refund"},
+			},
+		},
+		{
+			name:    "explicit date format and bad rows",
+			input:   "d,a\n03/01/26,-1\nyesterday,-1\n03/02/26,lots\n03/03/26,0\n",
+			mapping: CSVMapping{Date: "d", Amount: "a", DateFormat: "01/02/06"},
+			want:    []ImportRow{{Line: 2, Kind: "spending", Date: day(2026, 3, 1), Amount: money(t, "-1")}},
+			errs:    []int{3, 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrs, err := ParseCSVStatement(strings.NewReader(tt.input), tt.mapping)
+			if err != nil { t.Fatal(err) }
+			assertRows(t, rows, tt.want)
+			if len(rowErrs) != len(tt.errs) { t.Fatalf("errors = %+v, want lines %v", rowErrs, tt.errs) }
+			for i, e := range rowErrs {
+				if e.Line != tt.errs[i] { t.Errorf("error %d on line %d, want %d", i, e.Line, tt.errs[i]) }
+			}
+		})
+	}
+
+	if _, _, err := ParseCSVStatement(strings.NewReader("a,b\n"), CSVMapping{Date: "a", Amount: "missing"}); err == nil { t.Error("unknown column: want an error") }
+	if _, _, err := ParseCSVStatement(strings.NewReader("a,b\n"), CSVMapping{Date: "a"}); err == nil { t.Error("no amount column: want an error") }
+}
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>GBP
+<BANKTRANLIST>
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20260305120000[0:GMT]
+<TRNAMT>-42.10
+<NAME>TESCO
+<MEMO>Weekly shop
+</STMTTRN>
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20260306
+<TRNAMT>100.00
+<NAME>Refund
+<MEMO>refund
+</STMTTRN>
+<STMTTRN>
+<DTPOSTED>2026
+<TRNAMT>-1.00
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <BANKMSGSRSV1><STMTTRNRS><STMTRS>
+    <CURDEF>EUR</CURDEF>
+    <BANKTRANLIST>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20260310</DTPOSTED>
+        <TRNAMT>-9.99</TRNAMT>
+        <NAME>Streaming</NAME>
+      </STMTTRN>
+      <STMTTRN>
+        <TRNTYPE>DEBIT</TRNTYPE>
+        <DTPOSTED>20260311</DTPOSTED>
+        <TRNAMT>abc</TRNAMT>
+      </STMTTRN>
+    </BANKTRANLIST>
+  </STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+func TestParseOFXStatement(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []ImportRow
+		errs  []int
+	}{
+		{"sgml", ofxSGML, []ImportRow{
+			{Line: 1, Kind: "spending", Date: day(2026, 3, 5), Amount: money(t, "-42.10"), Description: "TESCO - Weekly shop", Merchant: "TESCO", Currency: "GBP"},
+			{Line: 2, Kind: "earning", Date: day(2026, 3, 6), Amount: money(t, "100"), Description: "Refund", Merchant: "Refund", Currency: "GBP"},
+		}, []int{3}},
+		{"xml", ofxXML, []ImportRow{
+			{Line: 1, Kind: "spending", Date: day(2026, 3, 10), Amount: money(t, "-9.99"), Description: "Streaming", Merchant: "Streaming", Currency: "EUR"},
+		}, []int{2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, rowErrs, err := ParseOFXStatement(strings.NewReader(tt.input))
+			if err != nil { t.Fatal(err) }
+			assertRows(t, rows, tt.want)
+			if len(rowErrs) != len(tt.errs) { t.Fatalf("errors = %+v, want lines %v", rowErrs, tt.errs) }
+			for i, e := range rowErrs {
+				if e.Line != tt.errs[i] { t.Errorf("error %d on line %d, want %d", i, e.Line, tt.errs[i]) }
+			}
+		})
+	}
+	if _, _, err := ParseOFXStatement(strings.NewReader("date,amount\n")); err == nil { t.Error("CSV input: want an error") }
+}
+
+func TestImportKeepsIdenticalRowsAndSkipsExisting(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	svc := NewImportService(repository.NewSpendingRepository(db))
+	conv := NewFxService(repository.NewFxRepository(db)).Converter("u1")
+	coffee := ImportRow{Kind: "spending", Date: day(2026, 3, 1), Amount: money(t, "-3.50"), Description: "Coffee", Category: "food"}
+	statement := func() []ImportRow { return []ImportRow{coffee, coffee} }
+
+	res, err := svc.Import(conv, "u1", statement(), nil, ImportOptions{})
+	if err != nil { t.Fatal(err) }
+	if len(res.Spending) != 2 || len(res.Duplicates) != 0 { t.Fatalf("first import: %d created, %d duplicates; want 2, 0", len(res.Spending), len(res.Duplicates)) }
+
+	res, err = svc.Import(conv, "u1", append(statement(), coffee), nil, ImportOptions{})
+	if err != nil { t.Fatal(err) }
+	if len(res.Spending) != 1 || len(res.Duplicates) != 2 { t.Fatalf("re-import with one more: %d created, %d duplicates; want 1, 2", len(res.Spending), len(res.Duplicates)) }
+
+	var n int64
+	db.Model(&models.SpendingEntry{}).Where("user_id = ?", "u1").Count(&n)
+	if n != 3 { t.Errorf("%d entries stored, want 3", n) }
+}
+
+func assertRows(t *testing.T, got, want []ImportRow) {
+	t.Helper()
+	if len(got) != len(want) { t.Fatalf("rows = %+v, want %+v", got, want) }
+	for i := range want {
+		g, w := got[i], want[i]
+		if !g.Date.Equal(w.Date) { t.Errorf("row %d date = %s, want %s", i, g.Date, w.Date) }
+		g.Date = w.Date
+		if g != w { t.Errorf("row %d = %+v, want %+v", i, g, w) }
+	}
+}
+
+func day(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+
+func money(t *testing.T, s string) models.Money {
+	t.Helper()
+	m, err := models.ParseMoney(s)
+	if err != nil { t.Fatal(err) }
+	return m
+}
+
+func TestParseStatementAmountSeparators(t *testing.T) {
+	tests := []struct {
+		in      string
+		decimal rune
+		want    string
+		err     error
+	}{
+		{"1,234.56", '.', "1234.56", nil},
+		{"$1,234,567", '.', "1234567", nil},
+		{"(12.50)", '.', "-12.50", nil},
+		{"12,50", '.', "", ErrAmbiguousAmount},
+		{"1,2345.00", '.', "", ErrAmbiguousAmount},
+		{"1.234,56", '.', "", ErrAmbiguousAmount},
+		{"1.2.3", '.', "", ErrAmbiguousAmount},
+		{"12,50", ',', "12.50", nil},
+		{"-1.234,56 €", ',', "-1234.56", nil},
+		{"1.234", ',', "1234", nil},
+		{"1,234.56", ',', "", ErrAmbiguousAmount},
+		{"12.5", ',', "", ErrAmbiguousAmount},
+	}
+	for _, tt := range tests {
+		got, err := parseStatementAmount(tt.in, tt.decimal)
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) { t.Errorf("parseStatementAmount(%q, %q) = %s, %v; want %v", tt.in, tt.decimal, got, err, tt.err) }
+			continue
+		}
+		if err != nil || got != money(t, tt.want) { t.Errorf("parseStatementAmount(%q, %q) = %s, %v; want %s", tt.in, tt.decimal, got, err, tt.want) }
+	}
+
+	rows, rowErrs, err := ParseCSVStatement(strings.NewReader("d;a\n2026-03-01;-12,50\n"), CSVMapping{Date: "d", Amount: "a", Delimiter: ";"})
+	if err != nil { t.Fatal(err) }
+	if len(rows) != 0 || len(rowErrs) != 1 || !strings.Contains(rowErrs[0].Error, "decimalSeparator") { t.Errorf("European amount without a separator: rows = %+v, errors = %+v", rows, rowErrs) }
+	rows, _, err = ParseCSVStatement(strings.NewReader("d;a\n2026-03-01;-12,50\n"), CSVMapping{Date: "d", Amount: "a", Delimiter: ";", DecimalSeparator: ","})
+	if err != nil { t.Fatal(err) }
+	if len(rows) != 1 || rows[0].Amount != money(t, "-12.50") { t.Errorf("rows = %+v, want -12.50", rows) }
+	if _, _, err := ParseCSVStatement(strings.NewReader("d,a\n"), CSVMapping{Date: "d", Amount: "a", DecimalSeparator: "'"}); err == nil { t.Error("unknown decimal separator: want an error") }
+}
@@ -0,0 +1,90 @@
+package services
+
+import (
+	"sort"
+	"time"
+
+	"achieving-backend/internal/models"
+)
+
+// Accrual is a debt's position as of a date, with interest accrued per day on a 365-day year.
+// Repayments settle unpaid interest first, then principal.
+type Accrual struct {
+	AsOf      time.Time    `json:"asOf"`
+	Principal models.Money `json:"principal"`       // principal still owed, including capitalized interest
+	Interest  models.Money `json:"accruedInterest"` // interest accrued and not yet paid
+	Owed      models.Money `json:"amountOwed"`      // Principal + Interest
+	Total     models.Money `json:"totalInterest"`   // all interest accrued since the debt started, paid or not
+}
+
+// AccrueDebt computes what is owed on b as of asOf (exclusive: interest runs through the previous day),
+// given its repayments. Compounding periods count from the debt date; monthly and yearly periods end on
+// the same day of the month, clamped to the month's last day.
+func AccrueDebt(b *models.BorrowEntry, repayments []models.BorrowRepayment, asOf time.Time) Accrual {
+	start, end := dateOnly(b.Date), dateOnly(asOf)
+	a := Accrual{AsOf: end}
+	if end.Before(start) { return a }
+	a.Principal = b.Amount
+	rate := b.InterestRate / 100
+
+	pays := append([]models.BorrowRepayment(nil), repayments...)
+	sort.SliceStable(pays, func(i, j int) bool { return pays[i].Date.Before(pays[j].Date) })
+	period := 0
+	next := compoundBoundary(start, b.Compounding, 1)
+	t := start
+	for i := 0; ; {
+		for ; i < len(pays) && !dateOnly(pays[i].Date).After(t); i++ { a.pay(pays[i].Amount) }
+		if !t.Before(end) { break }
+		stop := end
+		if i < len(pays) && dateOnly(pays[i].Date).Before(stop) { stop = dateOnly(pays[i].Date) }
+		if !next.IsZero() && next.Before(stop) { stop = next }
+		if rate > 0 && a.Principal > 0 {
+			days := stop.Sub(t).Hours() / 24
+			interest := a.Principal.MulRate(rate * days / 365)
+			a.Interest += interest
+			a.Total += interest
+		}
+		t = stop
+		if t.Equal(next) {
+			a.Principal += a.Interest
+			a.Interest = 0
+			period++
+			next = compoundBoundary(start, b.Compounding, period+1)
+		}
+	}
+	a.Owed = a.Principal + a.Interest
+	return a
+}
+
+// InterestBetween is the interest b accrued from from (inclusive) to to (exclusive)
+func InterestBetween(b *models.BorrowEntry, repayments []models.BorrowRepayment, from, to time.Time) models.Money {
+	if b.InterestRate == 0 || !to.After(from) { return 0 }
+	return AccrueDebt(b, repayments, to).Total - AccrueDebt(b, repayments, from).Total
+}
+
+func (a *Accrual) pay(amount models.Money) {
+	// Negative balance adjustments add to the principal
+	if amount < 0 {
+		a.Principal -= amount
+		return
+	}
+	if amount <= a.Interest {
+		a.Interest -= amount
+		return
+	}
+	a.Principal -= amount - a.Interest
+	a.Interest = 0
+}
+
+// compoundBoundary returns the end of the n-th compounding period after start; zero for simple interest
+func compoundBoundary(start time.Time, mode string, n int) time.Time {
+	switch mode {
+	case models.CompoundDaily:
+		return start.AddDate(0, 0, n)
+	case models.CompoundMonthly:
+		return addMonths(start, n)
+	case models.CompoundYearly:
+		return addMonths(start, 12*n)
+	}
+	return time.Time{}
+}
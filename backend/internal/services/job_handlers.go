@@ -0,0 +1,46 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"achieving-backend/internal/jobs"
+	"achieving-backend/internal/models"
+)
+
+// RegisterJobHandlers registers the handlers of the job kinds enqueued by the repositories
+func RegisterJobHandlers(r *jobs.Runner, spending *SpendingService, fx *FxService) {
+	r.Handle(models.JobMonthSummary, func(ctx context.Context, job *models.Job) error {
+		var p models.MonthJobPayload
+		if err := jobs.Decode(job, &p); err != nil { return err }
+		// Missing rates fail the attempt; they may be loaded before the next one
+		return spending.NotifyMonthSummary(fx.Converter(p.UserID), p.UserID, p.MonthKey)
+	})
+}
+
+// NotifyMonthSummary notifies the user of a month's spending against plan, once per month; months
+// that do not exist or have no plans nor spending are skipped
+func (s *SpendingService) NotifyMonthSummary(conv *Converter, userID, monthKey string) error {
+	ok, err := s.repo.HasMonth(userID, monthKey)
+	if err != nil || !ok { return err }
+	summary, err := s.BudgetSummary(conv, userID, monthKey)
+	if err != nil { return err }
+	t := summary.Totals
+	if t.Planned == 0 && t.Spending == 0 { return nil }
+	over := 0
+	for _, cb := range summary.Categories {
+		if cb.Planned > 0 && cb.Actual > cb.Planned { over++ }
+	}
+	body := fmt.Sprintf("Spent %s of %s %s planned and earned %s.", t.Spending, t.Planned, summary.Currency, t.Earnings)
+	if over == 1 { body += " 1 category went over plan." }
+	if over > 1 { body += fmt.Sprintf(" %d categories went over plan.", over) }
+	_, err = s.repo.Notify(&models.Notification{
+		UserID:    userID,
+		Kind:      models.NotificationMonthSummary,
+		DedupeKey: "summary:" + monthKey,
+		Title:     fmt.Sprintf("Your %s summary", monthKey),
+		Body:      body,
+		MonthKey:  monthKey,
+	})
+	return err
+}
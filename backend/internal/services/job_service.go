@@ -0,0 +1,49 @@
+package services
+
+import (
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+type JobService struct {
+	repo *repository.JobRepository
+}
+
+func NewJobService(repo *repository.JobRepository) *JobService {
+	return &JobService{repo: repo}
+}
+
+// QueueStatus summarizes the jobs queue. Lag is how long the oldest due job has been waiting, in seconds.
+type QueueStatus struct {
+	Counts         []repository.JobCount `json:"counts"`
+	Queued         int64                 `json:"queued"`
+	Running        int64                 `json:"running"`
+	Dead           int64                 `json:"dead"`
+	OldestQueuedAt *time.Time            `json:"oldestQueuedAt"`
+	Lag            float64               `json:"lagSeconds"`
+}
+
+func (s *JobService) Status(now time.Time) (*QueueStatus, error) {
+	counts, err := s.repo.Counts()
+	if err != nil { return nil, err }
+	oldest, err := s.repo.OldestQueued(now)
+	if err != nil { return nil, err }
+	st := &QueueStatus{Counts: counts, OldestQueuedAt: oldest}
+	for _, c := range counts {
+		switch c.Status {
+		case models.JobQueued:
+			st.Queued += c.Count
+		case models.JobRunning:
+			st.Running += c.Count
+		case models.JobDead:
+			st.Dead += c.Count
+		}
+	}
+	if oldest != nil { st.Lag = round2(now.Sub(*oldest).Seconds()) }
+	return st, nil
+}
+
+func (s *JobService) List(status, kind string, limit int) ([]models.Job, error) { return s.repo.List(status, kind, limit) }
+func (s *JobService) Requeue(id string) (int64, error)                         { return s.repo.Requeue(id, time.Now()) }
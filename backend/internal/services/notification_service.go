@@ -0,0 +1,46 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/notifications"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidChannel is returned for unknown channels and malformed addresses
+var ErrInvalidChannel = errors.New("invalid notification channel")
+
+type NotificationService struct {
+	repo *repository.NotificationRepository
+}
+
+func NewNotificationService(repo *repository.NotificationRepository) *NotificationService {
+	return &NotificationService{repo: repo}
+}
+
+func (s *NotificationService) List(userID string, unreadOnly bool, limit int) ([]models.Notification, error) {
+	return s.repo.List(userID, unreadOnly, limit)
+}
+func (s *NotificationService) UnreadCount(userID string) (int64, error) { return s.repo.UnreadCount(userID) }
+func (s *NotificationService) SetRead(userID, id string, read bool) (*models.Notification, error) {
+	return s.repo.SetRead(userID, id, read)
+}
+func (s *NotificationService) MarkAllRead(userID string) (int64, error) { return s.repo.MarkAllRead(userID) }
+func (s *NotificationService) Deliveries(userID, notificationID string) ([]models.NotificationDelivery, error) {
+	return s.repo.Deliveries(userID, notificationID)
+}
+
+func (s *NotificationService) ListChannels(userID string) ([]models.NotificationChannel, error) { return s.repo.ListChannels(userID) }
+func (s *NotificationService) FindChannel(userID, channel string) (*models.NotificationChannel, error) {
+	return s.repo.FindChannel(userID, channel)
+}
+func (s *NotificationService) DeleteChannel(userID, channel string) (int64, error) { return s.repo.DeleteChannel(userID, channel) }
+
+// SaveChannel sets where the user's notifications are sent on a channel ("signal" or "telegram")
+func (s *NotificationService) SaveChannel(userID, channel, address string, enabled bool) (*models.NotificationChannel, error) {
+	if channel != models.ChannelSignal && channel != models.ChannelTelegram { return nil, fmt.Errorf("%w: unknown channel %q", ErrInvalidChannel, channel) }
+	if !notifications.ValidAddress(channel, address) { return nil, fmt.Errorf("%w: invalid %s address", ErrInvalidChannel, channel) }
+	return s.repo.SaveChannel(userID, channel, address, enabled)
+}
@@ -0,0 +1,214 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidRule is returned for malformed recurring rules
+var ErrInvalidRule = errors.New("invalid recurring rule")
+
+// maxCatchUpPerRun bounds how many missed occurrences one rule materializes per scheduler pass;
+// anything left is picked up on the next pass
+const maxCatchUpPerRun = 400
+
+// RRule is the supported subset of RFC 5545 recurrence rules:
+// FREQ=DAILY|WEEKLY|MONTHLY|YEARLY, INTERVAL=n, BYMONTHDAY=d (monthly/yearly; -1 is the last day).
+type RRule struct {
+	Freq       string
+	Interval   int
+	ByMonthDay int
+}
+
+// ParseRRule parses strings such as "FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=1" (an "RRULE:" prefix is allowed)
+func ParseRRule(s string) (RRule, error) {
+	rr := RRule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	for _, part := range strings.Split(s, ";") {
+		if part == "" { continue }
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 { return rr, fmt.Errorf("%w: %q", ErrInvalidRule, part) }
+		key, val := strings.ToUpper(strings.TrimSpace(kv[0])), strings.ToUpper(strings.TrimSpace(kv[1]))
+		switch key {
+		case "FREQ":
+			switch val {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				rr.Freq = val
+			default:
+				return rr, fmt.Errorf("%w: unsupported FREQ %q", ErrInvalidRule, val)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(val)
+			if err != nil || n < 1 { return rr, fmt.Errorf("%w: bad INTERVAL", ErrInvalidRule) }
+			rr.Interval = n
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(val)
+			if err != nil || n == 0 || n < -1 || n > 31 { return rr, fmt.Errorf("%w: bad BYMONTHDAY", ErrInvalidRule) }
+			rr.ByMonthDay = n
+		default:
+			return rr, fmt.Errorf("%w: unsupported part %q", ErrInvalidRule, key)
+		}
+	}
+	if rr.Freq == "" { return rr, fmt.Errorf("%w: FREQ is required", ErrInvalidRule) }
+	return rr, nil
+}
+
+// nth returns the k-th occurrence counted from start
+func (rr RRule) nth(start time.Time, k int) time.Time {
+	switch rr.Freq {
+	case "DAILY":
+		return start.AddDate(0, 0, k*rr.Interval)
+	case "WEEKLY":
+		return start.AddDate(0, 0, 7*k*rr.Interval)
+	case "YEARLY":
+		return rr.onMonthDay(addMonths(start, 12*k*rr.Interval))
+	default:
+		return rr.onMonthDay(addMonths(start, k*rr.Interval))
+	}
+}
+
+// onMonthDay moves t to BYMONTHDAY within its month, clamping to the month's last day
+func (rr RRule) onMonthDay(t time.Time) time.Time {
+	if rr.ByMonthDay == 0 { return t }
+	lastDay := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	day := rr.ByMonthDay
+	if day == -1 || day > lastDay { day = lastDay }
+	return time.Date(t.Year(), t.Month(), day, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+// NextOccurrence returns the first occurrence strictly after `after` (and not before start),
+// or nil when the schedule has ended
+func NextOccurrence(rule *models.RecurringRule, after time.Time) *time.Time {
+	rr, err := ParseRRule(rule.RRule)
+	if err != nil { return nil }
+	start := dateOnly(rule.StartDate)
+	for k := 0; ; k++ {
+		t := rr.nth(start, k)
+		if rule.EndDate != nil && t.After(dateOnly(*rule.EndDate)) { return nil }
+		if t.Before(start) { continue }
+		if t.After(after) { return &t }
+	}
+}
+
+func dateOnly(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RecurringService materializes entries like SpendingService creates them: spending rules without
+// a category go through the categorization rules and every spending occurrence is checked against
+// the budget alert thresholds
+type RecurringService struct {
+	repo     *repository.RecurringRepository
+	spending *SpendingService
+	fx       *FxService
+}
+
+func NewRecurringService(repo *repository.RecurringRepository, spending *SpendingService, fx *FxService) *RecurringService {
+	return &RecurringService{repo: repo, spending: spending, fx: fx}
+}
+
+func (s *RecurringService) ListRules(userID string) ([]models.RecurringRule, error) { return s.repo.ListRules(userID) }
+func (s *RecurringService) FindRule(userID, id string) (*models.RecurringRule, error) { return s.repo.FindRule(userID, id) }
+func (s *RecurringService) DeleteRule(userID, id string) (int64, error) { return s.repo.DeleteRule(userID, id) }
+
+// validate checks the rule and recomputes its next run from the last materialized occurrence
+func (s *RecurringService) validate(rule *models.RecurringRule) error {
+	switch rule.Kind {
+	case "spending":
+	case "earning":
+		if rule.Source == "" { return fmt.Errorf("%w: source is required for earnings", ErrInvalidRule) }
+	default:
+		return fmt.Errorf("%w: kind must be spending or earning", ErrInvalidRule)
+	}
+	if rule.Amount <= 0 { return fmt.Errorf("%w: amount must be positive", ErrInvalidRule) }
+	if _, err := ParseRRule(rule.RRule); err != nil { return err }
+	rule.StartDate = dateOnly(rule.StartDate)
+	if rule.EndDate != nil {
+		end := dateOnly(*rule.EndDate)
+		if end.Before(rule.StartDate) { return fmt.Errorf("%w: endDate is before startDate", ErrInvalidRule) }
+		rule.EndDate = &end
+	}
+	after := rule.StartDate.Add(-time.Nanosecond)
+	if rule.LastRunDate != nil && !rule.LastRunDate.Before(rule.StartDate) { after = *rule.LastRunDate }
+	rule.NextRunDate = NextOccurrence(rule, after)
+	if rule.NextRunDate == nil { rule.Active = false }
+	return nil
+}
+
+func (s *RecurringService) CreateRule(rule *models.RecurringRule) error {
+	rule.Active = true
+	if err := s.validate(rule); err != nil { return err }
+	return s.repo.CreateRule(rule)
+}
+
+// UpdateRule saves an edited rule; schedule changes take effect from the next unmaterialized occurrence
+func (s *RecurringService) UpdateRule(rule *models.RecurringRule) error {
+	if err := s.validate(rule); err != nil { return err }
+	return s.repo.SaveRule(rule)
+}
+
+// RunDue materializes every occurrence due on or before asOf, catching up missed ones
+func (s *RecurringService) RunDue(asOf time.Time) (int, error) {
+	ids, err := s.repo.ListDueRuleIDs(asOf)
+	if err != nil { return 0, err }
+	created := 0
+	next := func(rule *models.RecurringRule) *time.Time { return NextOccurrence(rule, *rule.NextRunDate) }
+	for _, id := range ids {
+		// Budgets are checked once per rule and month touched, after the entries are committed
+		type budgetKey struct{ userID, monthKey, category string }
+		touched := map[budgetKey]bool{}
+		var order []budgetKey
+		create := func(tx *repository.SpendingRepository, rule *models.RecurringRule, occurrence time.Time) (string, error) {
+			if rule.Kind == "earning" {
+				item, err := tx.CreateEarning(rule.UserID, rule.Source, rule.Amount, occurrence, rule.Currency)
+				if err != nil { return "", err }
+				return item.ID, nil
+			}
+			entry, err := createSpending(tx, rule.UserID, rule.Amount, rule.Category, occurrence, rule.Note, "", rule.Currency)
+			if err != nil { return "", err }
+			k := budgetKey{entry.UserID, entry.MonthKey, entry.Category}
+			if !touched[k] { touched[k] = true; order = append(order, k) }
+			return entry.ID, nil
+		}
+		for i := 0; i < maxCatchUpPerRun; i++ {
+			ok, err := s.repo.Materialize(id, asOf, next, create)
+			if err != nil {
+				log.Printf("recurring: rule %s failed: %v", id, err)
+				break
+			}
+			if !ok { break }
+			created++
+		}
+		for _, k := range order { s.spending.alertBudget(s.fx.Converter(k.userID), k.userID, k.monthKey, k.category) }
+	}
+	return created, nil
+}
+
+// RecurringInterval is how often the scheduler looks for due rules (RECURRING_INTERVAL, default 1h)
+func RecurringInterval() time.Duration { return durationFromEnv("RECURRING_INTERVAL", time.Hour) }
+
+// RunScheduler runs RunDue immediately and then every interval until ctx is cancelled
+func (s *RecurringService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RunDue(time.Now()); err != nil {
+			log.Printf("recurring: scheduler pass failed: %v", err)
+		} else if n > 0 {
+			log.Printf("recurring: materialized %d occurrence(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func TestParseRRule(t *testing.T) {
+	tests := []struct {
+		in   string
+		want RRule
+		err  bool
+	}{
+		{"FREQ=MONTHLY;INTERVAL=1;BYMONTHDAY=1", RRule{Freq: "MONTHLY", Interval: 1, ByMonthDay: 1}, false},
+		{"RRULE:freq=weekly;interval=2", RRule{Freq: "WEEKLY", Interval: 2}, false},
+		{"FREQ=MONTHLY;BYMONTHDAY=-1", RRule{Freq: "MONTHLY", Interval: 1, ByMonthDay: -1}, false},
+		{"FREQ=YEARLY;", RRule{Freq: "YEARLY", Interval: 1}, false},
+		{"INTERVAL=1", RRule{}, true},
+		{"FREQ=HOURLY", RRule{}, true},
+		{"FREQ=DAILY;INTERVAL=0", RRule{}, true},
+		{"FREQ=MONTHLY;BYMONTHDAY=-2", RRule{}, true},
+		{"FREQ=MONTHLY;BYMONTHDAY=32", RRule{}, true},
+		{"FREQ=MONTHLY;BYDAY=MO", RRule{}, true},
+		{"FREQ", RRule{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRRule(tt.in)
+		if tt.err {
+			if !errors.Is(err, ErrInvalidRule) { t.Errorf("ParseRRule(%q) err = %v, want ErrInvalidRule", tt.in, err) }
+			continue
+		}
+		if err != nil || got != tt.want { t.Errorf("ParseRRule(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want) }
+	}
+}
+
+func TestNextOccurrence(t *testing.T) {
+	end := day(2026, 5, 31)
+	tests := []struct {
+		name  string
+		rule  models.RecurringRule
+		after time.Time
+		want  []time.Time // successive occurrences; a zero time means the schedule ended
+	}{
+		{"last day of month", models.RecurringRule{RRule: "FREQ=MONTHLY;BYMONTHDAY=-1", StartDate: day(2026, 1, 1)}, day(2025, 12, 31),
+			[]time.Time{day(2026, 1, 31), day(2026, 2, 28), day(2026, 3, 31), day(2026, 4, 30)}},
+		{"31st clamped in short months", models.RecurringRule{RRule: "FREQ=MONTHLY;BYMONTHDAY=31", StartDate: day(2028, 1, 31)}, day(2028, 1, 30),
+			[]time.Time{day(2028, 1, 31), day(2028, 2, 29), day(2028, 3, 31), day(2028, 4, 30)}},
+		{"start day kept after a short month", models.RecurringRule{RRule: "FREQ=MONTHLY", StartDate: day(2026, 1, 31)}, day(2026, 1, 31),
+			[]time.Time{day(2026, 2, 28), day(2026, 3, 31)}},
+		{"day before start is skipped", models.RecurringRule{RRule: "FREQ=MONTHLY;BYMONTHDAY=1", StartDate: day(2026, 1, 15)}, day(2026, 1, 14),
+			[]time.Time{day(2026, 2, 1), day(2026, 3, 1)}},
+		{"biweekly until end", models.RecurringRule{RRule: "FREQ=WEEKLY;INTERVAL=2", StartDate: day(2026, 5, 1), EndDate: &end}, day(2026, 5, 1),
+			[]time.Time{day(2026, 5, 15), day(2026, 5, 29), {}}},
+		{"yearly leap day", models.RecurringRule{RRule: "FREQ=YEARLY;BYMONTHDAY=-1", StartDate: day(2028, 2, 1)}, day(2028, 1, 1),
+			[]time.Time{day(2028, 2, 29), day(2029, 2, 28)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			after := tt.after
+			for i, want := range tt.want {
+				got := NextOccurrence(&tt.rule, after)
+				if want.IsZero() {
+					if got != nil { t.Errorf("occurrence %d = %s, want none", i+1, got.Format("2006-01-02")) }
+					return
+				}
+				if got == nil || !got.Equal(want) { t.Fatalf("occurrence %d = %v, want %s", i+1, got, want.Format("2006-01-02")) }
+				after = *got
+			}
+		})
+	}
+}
+
+func newRecurringService(t *testing.T) (*RecurringService, *gorm.DB) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	return NewRecurringService(repository.NewRecurringRepository(db), NewSpendingService(repository.NewSpendingRepository(db)), NewFxService(repository.NewFxRepository(db))), db
+}
+
+func TestRunDueCatchesUpAfterDowntime(t *testing.T) {
+	svc, db := newRecurringService(t)
+	spending := repository.NewSpendingRepository(db)
+	rule := models.RecurringRule{UserID: "u1", Kind: "spending", Amount: models.MoneyFromInt(900), Currency: "USD", Category: "rent", RRule: "FREQ=MONTHLY;BYMONTHDAY=-1", StartDate: day(2026, 1, 1)}
+	if err := svc.CreateRule(&rule); err != nil { t.Fatal(err) }
+
+	// The scheduler was down from January to mid-April
+	n, err := svc.RunDue(day(2026, 4, 15))
+	if err != nil || n != 3 { t.Fatalf("RunDue = %d, %v; want 3 occurrences", n, err) }
+	if n, _ := svc.RunDue(day(2026, 4, 15)); n != 0 { t.Errorf("second pass created %d, want 0", n) }
+	for _, mk := range []string{"2026-01", "2026-02", "2026-03"} {
+		entries, err := spending.ListSpending("u1", mk)
+		if err != nil { t.Fatal(err) }
+		if len(entries) != 1 || entries[0].Category != "rent" { t.Errorf("%s: %+v", mk, entries) }
+	}
+	got, _ := repository.NewRecurringRepository(db).FindRule("u1", rule.ID)
+	if got.NextRunDate == nil || !got.NextRunDate.Equal(day(2026, 4, 30)) { t.Errorf("next run = %v, want 2026-04-30", got.NextRunDate) }
+}
+
+func TestRunDueCategorizesSpending(t *testing.T) {
+	svc, db := newRecurringService(t)
+	spending := repository.NewSpendingRepository(db)
+	if err := db.Create(&models.CategorizationRule{ID: "c1", UserID: "u1", Category: "subscriptions", NoteContains: "netflix", Active: true}).Error; err != nil { t.Fatal(err) }
+	rule := models.RecurringRule{UserID: "u1", Kind: "spending", Amount: money(t, "15.99"), Currency: "USD", Note: "Netflix", RRule: "FREQ=MONTHLY", StartDate: day(2026, 3, 3)}
+	if err := svc.CreateRule(&rule); err != nil { t.Fatal(err) }
+	if _, err := svc.RunDue(day(2026, 3, 3)); err != nil { t.Fatal(err) }
+	entries, _ := spending.ListSpending("u1", "2026-03")
+	if len(entries) != 1 || entries[0].Category != "subscriptions" { t.Fatalf("entries = %+v, want one in subscriptions", entries) }
+}
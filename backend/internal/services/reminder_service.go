@@ -0,0 +1,100 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ReminderService notifies users of debts coming due and overdue
+type ReminderService struct {
+	repo *repository.SpendingRepository
+}
+
+func NewReminderService(repo *repository.SpendingRepository) *ReminderService {
+	return &ReminderService{repo: repo}
+}
+
+// DebtReminderDays is how many days ahead of its due date a debt is reminded of (DEBT_REMINDER_DAYS, default 3)
+func DebtReminderDays() int {
+	if n, err := strconv.Atoi(os.Getenv("DEBT_REMINDER_DAYS")); err == nil && n >= 0 { return n }
+	return 3
+}
+
+// ReminderInterval is how often the reminder scheduler runs (REMINDER_INTERVAL, default 1h)
+func ReminderInterval() time.Duration { return durationFromEnv("REMINDER_INTERVAL", time.Hour) }
+
+// RunDue notifies every debt still owed as of asOf that is due within DebtReminderDays, once per due
+// date, and every overdue one, once per due date. It returns how many notifications were created.
+func (s *ReminderService) RunDue(asOf time.Time) (int, error) {
+	today := dateOnly(asOf)
+	debts, err := s.repo.ListDueBy(today.AddDate(0, 0, DebtReminderDays()))
+	if err != nil { return 0, err }
+	created := 0
+	for start := 0; start < len(debts); {
+		// Debts come ordered by user; load each user's repayments in one query
+		end := start
+		for end < len(debts) && debts[end].UserID == debts[start].UserID { end++ }
+		batch := debts[start:end]
+		start = end
+		ids := make([]string, len(batch))
+		for i := range batch { ids[i] = batch[i].ID }
+		pays, err := s.repo.RepaymentsFor(batch[0].UserID, ids)
+		if err != nil { return created, err }
+		byBorrow := map[string][]models.BorrowRepayment{}
+		for _, p := range pays { byBorrow[p.BorrowID] = append(byBorrow[p.BorrowID], p) }
+		for i := range batch {
+			b := &batch[i]
+			owed := AccrueDebt(b, byBorrow[b.ID], today).Owed
+			if owed <= 0 { continue }
+			ok, err := s.repo.Notify(debtReminder(b, owed, today))
+			if err != nil { return created, err }
+			if ok { created++ }
+		}
+	}
+	return created, nil
+}
+
+// debtReminder words the reminder for a debt from the user's side
+func debtReminder(b *models.BorrowEntry, owed models.Money, today time.Time) *models.Notification {
+	due := dateOnly(*b.DueDate)
+	day := due.Format("2006-01-02")
+	n := &models.Notification{UserID: b.UserID, MonthKey: b.MonthKey}
+	whose := fmt.Sprintf("Debt to %s", b.From)
+	if b.Direction == "lent" { whose = fmt.Sprintf("%s's debt to you", b.From) }
+	if due.Before(today) {
+		n.Kind = models.NotificationBorrowOverdue
+		n.DedupeKey = fmt.Sprintf("borrow_overdue:%s:%s", b.ID, day)
+		n.Title = fmt.Sprintf("%s is overdue", whose)
+	} else {
+		n.Kind = models.NotificationBorrowDue
+		n.DedupeKey = fmt.Sprintf("borrow_due:%s:%s", b.ID, day)
+		n.Title = fmt.Sprintf("%s is due on %s", whose, day)
+	}
+	n.Body = fmt.Sprintf("%s %s still owed, due %s.", owed, b.Currency, day)
+	return n
+}
+
+// RunScheduler runs RunDue immediately and then every interval until ctx is cancelled
+func (s *ReminderService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.RunDue(time.Now()); err != nil {
+			log.Printf("reminders: scheduler pass failed: %v", err)
+		} else if n > 0 {
+			log.Printf("reminders: created %d notification(s)", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidRange is returned for malformed or oversized report ranges
+var ErrInvalidRange = errors.New("invalid month range")
+
+// maxTrendMonths caps the number of months in one trends report
+const maxTrendMonths = 60
+
+// TrendPoint is one month of a series. MovingAvg is the trailing Window-month average;
+// YoY is the percent change against the same month a year earlier (nil when that month is 0).
+type TrendPoint struct {
+	MonthKey  string   `json:"monthKey"`
+	Amount    models.Money `json:"amount"`
+	MovingAvg models.Money `json:"movingAvg"`
+	YoY       *float64     `json:"yoy"`
+}
+
+// MonthTrend holds month-level figures. SavingsRate is (earnings − spending) / earnings in percent;
+// PlanAdherence is the percent of planned categories whose spending stayed within plan.
+type MonthTrend struct {
+	MonthKey      string     `json:"monthKey"`
+	Spending      TrendPoint `json:"spending"`
+	Earnings      TrendPoint `json:"earnings"`
+	SavingsRate   *float64   `json:"savingsRate"`
+	PlanAdherence *float64   `json:"planAdherence"`
+}
+
+type GroupTrend struct {
+	Key    string       `json:"key"`
+	Series []TrendPoint `json:"series"`
+}
+
+type TrendsReport struct {
+	From     string       `json:"from"`
+	To       string       `json:"to"`
+	Currency string       `json:"currency"`
+	GroupBy  string       `json:"groupBy,omitempty"`
+	Window   int          `json:"window"`
+	Months   []MonthTrend `json:"months"`
+	Groups   []GroupTrend `json:"groups"`
+}
+
+type ReportService struct {
+	repo *repository.ReportRepository
+}
+
+func NewReportService(repo *repository.ReportRepository) *ReportService {
+	return &ReportService{repo: repo}
+}
+
+// monthKeys lists YYYY-MM keys from `from` through `to` inclusive
+func monthKeys(from, to string) ([]string, error) {
+	f, err := time.Parse("2006-01", from)
+	if err != nil { return nil, ErrInvalidRange }
+	t, err := time.Parse("2006-01", to)
+	if err != nil || t.Before(f) { return nil, ErrInvalidRange }
+	var keys []string
+	for m := f; !m.After(t); m = m.AddDate(0, 1, 0) {
+		keys = append(keys, m.Format("2006-01"))
+		if len(keys) > maxTrendMonths+12 { return nil, ErrInvalidRange }
+	}
+	return keys, nil
+}
+
+func shiftMonth(key string, n int) string {
+	t, _ := time.Parse("2006-01", key)
+	return t.AddDate(0, n, 0).Format("2006-01")
+}
+
+// Trends builds per-month series over [from, to]. groupBy is "", "category" (spending) or "source" (earnings).
+// Data from the 12 months before `from` is loaded so moving averages and YoY are complete from the first month.
+// Amounts are converted to the user's base currency at the rate of their date.
+func (s *ReportService) Trends(conv *Converter, userID, from, to, groupBy string, window int) (*TrendsReport, error) {
+	keys, err := monthKeys(from, to)
+	if err != nil { return nil, err }
+	if len(keys) > maxTrendMonths { return nil, ErrInvalidRange }
+	if window < 1 || window > 12 { window = 3 }
+	start := shiftMonth(from, -12)
+	all, _ := monthKeys(start, to)
+
+	spending, err := s.repo.SpendingByMonth(userID, start, to, groupBy == "category")
+	if err != nil { return nil, err }
+	earnings, err := s.repo.EarningsByMonth(userID, start, to, groupBy == "source")
+	if err != nil { return nil, err }
+	usage, err := s.repo.PlanUsageByMonth(userID, from, to)
+	if err != nil { return nil, err }
+
+	spendTotal := map[string]models.Money{}
+	earnTotal := map[string]models.Money{}
+	groups := map[string]map[string]models.Money{}
+	for i := range spending {
+		r := &spending[i]
+		r.Amount = conv.Convert(r.Amount, r.Currency, rowDate(r.MonthKey, r.Day))
+		spendTotal[r.MonthKey] += r.Amount
+		if groupBy == "category" { addGroup(groups, *r) }
+	}
+	for i := range earnings {
+		r := &earnings[i]
+		r.Amount = conv.Convert(r.Amount, r.Currency, rowDate(r.MonthKey, r.Day))
+		earnTotal[r.MonthKey] += r.Amount
+		if groupBy == "source" { addGroup(groups, *r) }
+	}
+	type planKey struct{ month, category string }
+	plan := map[planKey]models.Money{}
+	actual := map[planKey]models.Money{}
+	for _, u := range usage {
+		k := planKey{u.MonthKey, u.Category}
+		at := rowDate(u.MonthKey, u.Day)
+		plan[k] += conv.Convert(u.Planned, u.Currency, at)
+		actual[k] += conv.Convert(u.Actual, u.Currency, at)
+	}
+	if err := conv.Err(); err != nil { return nil, err }
+	planned := map[string]int{}
+	within := map[string]int{}
+	for k, p := range plan {
+		planned[k.month]++
+		if actual[k] <= p { within[k.month]++ }
+	}
+
+	spendSeries := buildSeries(all, keys, spendTotal, window)
+	earnSeries := buildSeries(all, keys, earnTotal, window)
+	report := &TrendsReport{From: from, Currency: conv.Base, To: to, GroupBy: groupBy, Window: window, Months: make([]MonthTrend, len(keys)), Groups: []GroupTrend{}}
+	for i, mk := range keys {
+		mt := MonthTrend{MonthKey: mk, Spending: spendSeries[i], Earnings: earnSeries[i]}
+		if e := earnTotal[mk]; e > 0 {
+			rate := round2((e - spendTotal[mk]).Ratio(e) * 100)
+			mt.SavingsRate = &rate
+		}
+		if n := planned[mk]; n > 0 {
+			adh := round2(float64(within[mk]) / float64(n) * 100)
+			mt.PlanAdherence = &adh
+		}
+		report.Months[i] = mt
+	}
+	names := make([]string, 0, len(groups))
+	for k := range groups { names = append(names, k) }
+	sort.Strings(names)
+	for _, k := range names {
+		report.Groups = append(report.Groups, GroupTrend{Key: k, Series: buildSeries(all, keys, groups[k], window)})
+	}
+	return report, nil
+}
+
+func addGroup(groups map[string]map[string]models.Money, r repository.MonthAmount) {
+	if groups[r.GroupKey] == nil { groups[r.GroupKey] = map[string]models.Money{} }
+	groups[r.GroupKey][r.MonthKey] += r.Amount
+}
+
+// buildSeries computes points for `keys`, using `all` (which starts 12 months earlier) as history
+func buildSeries(all, keys []string, values map[string]models.Money, window int) []TrendPoint {
+	offset := len(all) - len(keys)
+	out := make([]TrendPoint, len(keys))
+	for i, mk := range keys {
+		idx := offset + i
+		var sum models.Money
+		for j := idx - window + 1; j <= idx; j++ { sum += values[all[j]] }
+		p := TrendPoint{MonthKey: mk, Amount: values[mk], MovingAvg: sum.Quo(int64(window))}
+		if prev := values[all[idx-12]]; prev != 0 {
+			yoy := round2((values[mk] - prev).Ratio(prev.Abs()) * 100)
+			p.YoY = &yoy
+		}
+		out[i] = p
+	}
+	return out
+}
@@ -1,7 +1,14 @@
 package services
 
 import (
+	"fmt"
+	"log"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+	"gorm.io/gorm"
 	"achieving-backend/internal/models"
 	"achieving-backend/internal/repository"
 )
@@ -17,39 +24,431 @@ func NewSpendingService(repo *repository.SpendingRepository) *SpendingService {
 func (s *SpendingService) EnsureMonth(userID, monthKey string) error { return s.repo.EnsureMonth(userID, monthKey) }
 
 func (s *SpendingService) ListSpending(userID, monthKey string) ([]models.SpendingEntry, error) { return s.repo.ListSpending(userID, monthKey) }
-func (s *SpendingService) CreateSpending(userID string, amount float64, category string, date time.Time, note string) (*models.SpendingEntry, error) {
-	return s.repo.CreateSpending(userID, amount, category, date, note)
+// CreateSpending stores a spending entry; without a category, the user's categorization rules pick one
+// and the entry stays uncategorized when none matches. Afterwards the category's month total is checked
+// against the user's budget alert thresholds (see checkBudget); conv converts it to the base currency.
+func (s *SpendingService) CreateSpending(conv *Converter, userID string, amount models.Money, category string, date time.Time, note, merchant, currency string) (*models.SpendingEntry, error) {
+	entry, err := createSpending(s.repo, userID, amount, category, date, note, merchant, currency)
+	if err != nil { return nil, err }
+	s.alertBudget(conv, userID, entry.MonthKey, entry.Category)
+	return entry, nil
+}
+
+// createSpending stores a spending entry through repo, categorizing it by the user's rules when it has no category
+func createSpending(repo *repository.SpendingRepository, userID string, amount models.Money, category string, date time.Time, note, merchant, currency string) (*models.SpendingEntry, error) {
+	if strings.TrimSpace(category) == "" {
+		rules, err := repo.CategorizationRules(userID)
+		if err != nil { return nil, err }
+		if rule := MatchRule(rules, note, merchant, amount, repo.CurrencyOr(userID, currency)); rule != nil { category = rule.Category } else { category = "" }
+	}
+	return repo.CreateSpending(userID, amount, category, date, note, merchant, currency)
+}
+
+// alertBudget runs checkBudget for an entry that is already stored; a failed check only costs the alert
+func (s *SpendingService) alertBudget(conv *Converter, userID, monthKey, category string) {
+	if err := s.checkBudget(conv, userID, monthKey, category); err != nil {
+		log.Printf("budget alert check failed for %s %s: %v", monthKey, category, err)
+	}
+}
+// UpdateSpending saves an edit; when it changes the amount, category, date or currency, the entry's
+// month and category are checked against the budget alert thresholds like a new entry
+func (s *SpendingService) UpdateSpending(conv *Converter, userID, id string, version int64, updates map[string]interface{}) (*models.SpendingEntry, error) {
+	entry, err := s.repo.UpdateSpending(userID, id, version, updates)
+	if err != nil { return nil, err }
+	for _, f := range []string{"amount", "category", "date", "currency"} {
+		if _, ok := updates[f]; ok {
+			s.alertBudget(conv, userID, entry.MonthKey, entry.Category)
+			break
+		}
+	}
+	return entry, nil
 }
 func (s *SpendingService) DeleteSpending(userID, id string) (int64, error) { return s.repo.DeleteSpending(userID, id) }
 
 func (s *SpendingService) ListEarnings(userID, monthKey string) ([]models.EarningEntry, error) { return s.repo.ListEarnings(userID, monthKey) }
-func (s *SpendingService) CreateEarning(userID, source string, amount float64, date time.Time) (*models.EarningEntry, error) {
-	return s.repo.CreateEarning(userID, source, amount, date)
+func (s *SpendingService) CreateEarning(userID, source string, amount models.Money, date time.Time, currency string) (*models.EarningEntry, error) {
+	return s.repo.CreateEarning(userID, source, amount, date, currency)
+}
+func (s *SpendingService) UpdateEarning(userID, id string, version int64, updates map[string]interface{}) (*models.EarningEntry, error) {
+	return s.repo.UpdateEarning(userID, id, version, updates)
 }
 func (s *SpendingService) DeleteEarning(userID, id string) (int64, error) { return s.repo.DeleteEarning(userID, id) }
 
-func (s *SpendingService) ListBorrows(userID, monthKey string) ([]models.BorrowEntry, error) { return s.repo.ListBorrows(userID, monthKey) }
-func (s *SpendingService) CreateBorrow(userID, from string, amount float64, date time.Time) (*models.BorrowEntry, error) {
-	return s.repo.CreateBorrow(userID, from, amount, date)
+func (s *SpendingService) ListBorrows(userID, monthKey, direction, status string) ([]models.BorrowEntry, error) {
+	return s.repo.ListBorrows(userID, monthKey, direction, status)
+}
+func (s *SpendingService) FindBorrow(userID, id string) (*models.BorrowEntry, error) { return s.repo.FindBorrow(userID, id) }
+func (s *SpendingService) CreateBorrow(userID, counterpartyID, from string, item models.BorrowEntry) (*models.BorrowEntry, error) {
+	return s.repo.CreateBorrow(userID, counterpartyID, from, item)
 }
-func (s *SpendingService) UpdateBorrowRepayment(userID, id string, repaidAmount float64, repaidDate time.Time) (int64, error) {
+func (s *SpendingService) UpdateBorrow(userID, id string, version int64, updates map[string]interface{}) (*models.BorrowEntry, error) {
+	return s.repo.UpdateBorrow(userID, id, version, updates)
+}
+// UpdateBorrowRepayment sets the total repaid amount; like CreateRepayment, the added amount may not
+// exceed what an interest-bearing debt owes on its date
+func (s *SpendingService) UpdateBorrowRepayment(userID, id string, repaidAmount models.Money, repaidDate time.Time) (int64, error) {
+	b, err := s.repo.FindBorrow(userID, id)
+	if err == gorm.ErrRecordNotFound { return 0, nil }
+	if err != nil { return 0, err }
+	if b.InterestRate > 0 && repaidAmount > b.RepaidAmount {
+		st, err := s.accrue(userID, b, repaidDate)
+		if err != nil { return 0, err }
+		if repaidAmount-b.RepaidAmount > st.Owed { return 0, repository.ErrRepaymentExceedsBalance }
+	}
 	return s.repo.UpdateBorrowRepayment(userID, id, repaidAmount, repaidDate)
 }
+func (s *SpendingService) ListRepayments(userID, borrowID string) ([]models.BorrowRepayment, error) { return s.repo.ListRepayments(userID, borrowID) }
+// CreateRepayment records a repayment; on interest-bearing debts it may not exceed the amount owed on its date
+func (s *SpendingService) CreateRepayment(userID, borrowID string, amount models.Money, date time.Time, note string) (*models.BorrowRepayment, error) {
+	b, err := s.repo.FindBorrow(userID, borrowID)
+	if err != nil { return nil, err }
+	if b.InterestRate > 0 {
+		st, err := s.accrue(userID, b, date)
+		if err != nil { return nil, err }
+		if amount > st.Owed { return nil, repository.ErrRepaymentExceedsBalance }
+	}
+	return s.repo.CreateRepayment(userID, borrowID, amount, date, note)
+}
+func (s *SpendingService) DeleteRepayment(userID, borrowID, id string) (int64, error) { return s.repo.DeleteRepayment(userID, borrowID, id) }
 func (s *SpendingService) DeleteBorrow(userID, id string) (int64, error) { return s.repo.DeleteBorrow(userID, id) }
 
-func (s *SpendingService) ListCategories(userID string) ([]models.Category, error) { return s.repo.ListCategories(userID) }
-func (s *SpendingService) CreateCategory(userID, name string) (*models.Category, error) { return s.repo.CreateCategory(userID, name) }
-func (s *SpendingService) DeleteCategory(userID, name string) (int64, error) { return s.repo.DeleteCategory(userID, name) }
+// DebtStatus is a debt with its accrued position as of a date
+type DebtStatus struct {
+	models.BorrowEntry
+	Accrual
+	DaysOverdue int `json:"daysOverdue"`
+}
+
+func (s *SpendingService) accrue(userID string, b *models.BorrowEntry, asOf time.Time) (*DebtStatus, error) {
+	pays, err := s.repo.RepaymentsFor(userID, []string{b.ID})
+	if err != nil { return nil, err }
+	st := &DebtStatus{BorrowEntry: *b, Accrual: AccrueDebt(b, pays, asOf)}
+	if b.DueDate != nil && st.Owed > 0 {
+		if days := int(st.AsOf.Sub(dateOnly(*b.DueDate)).Hours() / 24); days > 0 { st.DaysOverdue = days }
+	}
+	return st, nil
+}
+
+// DebtAccrual returns what is owed on a debt as of asOf, including accrued interest
+func (s *SpendingService) DebtAccrual(userID, id string, asOf time.Time) (*DebtStatus, error) {
+	b, err := s.repo.FindBorrow(userID, id)
+	if err != nil { return nil, err }
+	return s.accrue(userID, b, asOf)
+}
+
+// Overdue lists debts past their due date that still have an amount owed as of asOf, most overdue first
+func (s *SpendingService) Overdue(userID string, asOf time.Time) ([]DebtStatus, error) {
+	debts, err := s.repo.ListOverdue(userID, asOf)
+	if err != nil { return nil, err }
+	ids := make([]string, len(debts))
+	for i := range debts { ids[i] = debts[i].ID }
+	pays, err := s.repo.RepaymentsFor(userID, ids)
+	if err != nil { return nil, err }
+	byBorrow := map[string][]models.BorrowRepayment{}
+	for _, p := range pays { byBorrow[p.BorrowID] = append(byBorrow[p.BorrowID], p) }
+	out := []DebtStatus{}
+	for i := range debts {
+		b := &debts[i]
+		st := DebtStatus{BorrowEntry: *b, Accrual: AccrueDebt(b, byBorrow[b.ID], asOf)}
+		if st.Owed <= 0 { continue }
+		st.DaysOverdue = int(st.AsOf.Sub(dateOnly(*b.DueDate)).Hours() / 24)
+		out = append(out, st)
+	}
+	return out, nil
+}
+
+// monthInterest sums the interest accrued in the month on interest-bearing debts per direction and
+// currency, up to asOf for the current month
+func (s *SpendingService) monthInterest(userID, monthKey string, asOf time.Time) ([]MonthInterest, error) {
+	from, err := time.Parse("2006-01", monthKey)
+	if err != nil { return nil, err }
+	to := from.AddDate(0, 1, 0)
+	if today := dateOnly(asOf).AddDate(0, 0, 1); today.Before(to) { to = today }
+	debts, err := s.repo.ListInterestBearing(userID, to)
+	if err != nil { return nil, err }
+	ids := make([]string, len(debts))
+	for i := range debts { ids[i] = debts[i].ID }
+	pays, err := s.repo.RepaymentsFor(userID, ids)
+	if err != nil { return nil, err }
+	byBorrow := map[string][]models.BorrowRepayment{}
+	for _, p := range pays { byBorrow[p.BorrowID] = append(byBorrow[p.BorrowID], p) }
+	var out []MonthInterest
+	for i := range debts {
+		b := &debts[i]
+		if v := InterestBetween(b, byBorrow[b.ID], from, to); v != 0 {
+			out = append(out, MonthInterest{Direction: b.Direction, Currency: b.Currency, Amount: v})
+		}
+	}
+	return out, nil
+}
+
+// MonthInterest is interest accrued on one debt within a month
+type MonthInterest struct {
+	Direction string
+	Currency  string
+	Amount    models.Money
+}
+
+func (s *SpendingService) ListCategories(userID string, archived *bool) ([]models.Category, error) {
+	return s.repo.ListCategories(userID, archived)
+}
+func (s *SpendingService) CreateCategory(userID string, cat models.Category) (*models.Category, error) {
+	return s.repo.CreateCategory(userID, cat)
+}
+func (s *SpendingService) UpdateCategory(userID, name string, newName, parent *string, attrs map[string]interface{}) (*models.Category, error) {
+	return s.repo.UpdateCategory(userID, name, newName, parent, attrs)
+}
+func (s *SpendingService) MergeCategories(userID string, sources []string, target string) (*models.Category, error) {
+	return s.repo.MergeCategories(userID, sources, target)
+}
+func (s *SpendingService) DeleteCategory(userID, name, reassignTo string) (int64, error) {
+	return s.repo.DeleteCategory(userID, name, reassignTo)
+}
 
 func (s *SpendingService) ListPlans(userID, monthKey string) ([]models.Plan, error) { return s.repo.ListPlans(userID, monthKey) }
-func (s *SpendingService) UpsertPlan(userID, monthKey, category string, plannedAmount float64) (*models.Plan, bool, error) {
-	return s.repo.UpsertPlan(userID, monthKey, category, plannedAmount)
+func (s *SpendingService) UpsertPlan(userID, monthKey, category string, plannedAmount models.Money, currency string) (*models.Plan, bool, error) {
+	return s.repo.UpsertPlan(userID, monthKey, category, plannedAmount, currency)
 }
 func (s *SpendingService) DeletePlan(userID, monthKey, category string) (int64, error) { return s.repo.DeletePlan(userID, monthKey, category) }
 
 func (s *SpendingService) ListMonths(userID string) ([]models.Month, error) { return s.repo.ListMonths(userID) }
-func (s *SpendingService) CreateMonthWithSeeds(userID, monthKey string) (*models.Month, error) { return s.repo.CreateMonthWithSeeds(userID, monthKey) }
 func (s *SpendingService) MonthSummary(userID, monthKey string) ([]models.SpendingEntry, []models.EarningEntry, []models.BorrowEntry, []models.Plan) {
 	return s.repo.MonthSummary(userID, monthKey)
 }
-func (s *SpendingService) DeleteMonthCascade(userID, monthKey string) error { return s.repo.DeleteMonthCascade(userID, monthKey) }
\ No newline at end of file
+func (s *SpendingService) DeleteMonthCascade(userID, monthKey string) error { return s.repo.DeleteMonthCascade(userID, monthKey) }
+
+// MonthSeed chooses the planned amounts of a new month. Template (an ID or name) or CopyFrom (a month key)
+// clones amounts; Rollover adds each category's remainder (planned minus spent) in CopyFrom, or in the
+// previous month when CopyFrom is empty. Categories without an amount are planned at 0.
+type MonthSeed struct {
+	Template string
+	CopyFrom string
+	Rollover bool
+}
+
+// CreateMonth creates a month and seeds its plans; gorm.ErrRecordNotFound when the template or the
+// month to copy from does not exist. conv converts remainders spent in another currency than the plan.
+func (s *SpendingService) CreateMonth(conv *Converter, userID, monthKey string, seed MonthSeed) (*models.Month, error) {
+	var seeds []models.Plan
+	switch {
+	case seed.Template != "":
+		t, err := s.repo.Template(userID, seed.Template)
+		if err != nil { return nil, err }
+		for _, it := range t.Items { seeds = append(seeds, models.Plan{Category: it.Category, PlannedAmount: it.PlannedAmount, Currency: it.Currency}) }
+	case seed.CopyFrom != "":
+		plans, err := s.repo.MonthPlans(userID, seed.CopyFrom)
+		if err != nil { return nil, err }
+		seeds = plans
+	}
+	if seed.Rollover {
+		src := seed.CopyFrom
+		if src == "" { src = addMonths(rowDate(monthKey, ""), -1).Format("2006-01") }
+		var err error
+		if seeds, err = s.rollover(conv, userID, src, monthKey, seeds); err != nil { return nil, err }
+	}
+	return s.repo.CreateMonthWithSeeds(userID, monthKey, seeds)
+}
+
+// rollover adds each category's remainder in month src to its seed, converted into the seed's currency
+// (a category without a seed gets one in its src plan's currency). Overspending lowers the seed, floored at 0.
+func (s *SpendingService) rollover(conv *Converter, userID, src, monthKey string, seeds []models.Plan) ([]models.Plan, error) {
+	rows, err := s.repo.MonthCategoryTotals(userID, src)
+	if err != nil { return nil, err }
+	planCur := map[string]string{}
+	for _, r := range rows {
+		if r.Day == "" { planCur[r.Category] = r.Currency }
+	}
+	var order []string
+	remainder := map[string]models.Money{}
+	for _, r := range rows {
+		cur := planCur[r.Category]
+		if cur == "" {
+			cur = conv.Base
+			planCur[r.Category] = cur
+		}
+		if _, ok := remainder[r.Category]; !ok { order = append(order, r.Category) }
+		rate, ok := conv.Rate(r.Currency, cur, rowDate(src, r.Day))
+		if !ok { continue }
+		remainder[r.Category] += (r.Planned - r.Actual).MulRate(rate)
+	}
+	index := map[string]int{}
+	for i, p := range seeds { index[p.Category] = i }
+	start := rowDate(monthKey, "")
+	for _, cat := range order {
+		i, ok := index[cat]
+		if !ok {
+			seeds = append(seeds, models.Plan{Category: cat, Currency: planCur[cat]})
+			i = len(seeds) - 1
+		}
+		p := &seeds[i]
+		rate, ok := conv.Rate(planCur[cat], p.Currency, start)
+		if !ok { continue }
+		p.PlannedAmount += remainder[cat].MulRate(rate)
+		if p.PlannedAmount < 0 { p.PlannedAmount = 0 }
+	}
+	if err := conv.Err(); err != nil { return nil, err }
+	return seeds, nil
+}
+
+// ParseThresholds parses a budget alert thresholds setting such as "80,100" into ascending, distinct
+// percentages between 1 and 1000; an empty setting yields none
+func ParseThresholds(s string) ([]int, error) {
+	var out []int
+	seen := map[int]bool{}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" { continue }
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 1 || n > 1000 { return nil, fmt.Errorf("invalid budget alert threshold %q", part) }
+		if !seen[n] { out = append(out, n) }
+		seen[n] = true
+	}
+	sort.Ints(out)
+	return out, nil
+}
+
+// FormatThresholds is the inverse of ParseThresholds
+func FormatThresholds(ts []int) string {
+	parts := make([]string, len(ts))
+	for i, t := range ts { parts[i] = strconv.Itoa(t) }
+	return strings.Join(parts, ",")
+}
+
+// checkBudget notifies the user of every budget alert threshold the category's spending in the month
+// has reached, as a percentage of its plan in the base currency. Each threshold is notified at most
+// once per month and category. Categories without a plan, and months with missing rates, are skipped.
+func (s *SpendingService) checkBudget(conv *Converter, userID, monthKey, category string) error {
+	if category == "" { return nil }
+	setting, err := s.repo.BudgetAlertThresholds(userID)
+	if err != nil { return err }
+	thresholds, err := ParseThresholds(setting)
+	if err != nil || len(thresholds) == 0 { return err }
+	rows, err := s.repo.MonthCategoryTotals(userID, monthKey)
+	if err != nil { return err }
+	var planned, spent models.Money
+	for _, r := range rows {
+		if r.Category != category { continue }
+		at := rowDate(monthKey, r.Day)
+		planned += conv.Convert(r.Planned, r.Currency, at)
+		spent += conv.Convert(r.Actual, r.Currency, at)
+	}
+	if conv.Err() != nil || planned <= 0 { return nil }
+	used := spent.Ratio(planned) * 100
+	for _, t := range thresholds {
+		if used < float64(t) { break }
+		title := fmt.Sprintf("%s reached %d%% of its %s budget", category, t, monthKey)
+		if t == 100 { title = fmt.Sprintf("%s is over its %s budget", category, monthKey) }
+		n := models.Notification{
+			UserID:    userID,
+			Kind:      models.NotificationBudgetThreshold,
+			DedupeKey: fmt.Sprintf("budget:%s:%s:%d", monthKey, category, t),
+			Title:     title,
+			Body:      fmt.Sprintf("Spent %s of %s %s planned (%.0f%%).", spent.String(), planned.String(), conv.Base, used),
+			MonthKey:  monthKey,
+			Category:  category,
+		}
+		if _, err := s.repo.Notify(&n); err != nil { return err }
+	}
+	return nil
+}
+
+// CategoryBudget compares a category's plan with its actual spending.
+// Variance is planned minus actual (negative when overspent); PercentUsed is nil without a plan.
+type CategoryBudget struct {
+	Category    string   `json:"category"`
+	Planned     models.Money `json:"planned"`
+	Actual      models.Money `json:"actual"`
+	Variance    models.Money `json:"variance"`
+	PercentUsed *float64     `json:"percentUsed"`
+}
+
+// BudgetTotals are month-level figures; NetCashFlow is earnings minus spending.
+// OutstandingBorrows is what the user still owes, OutstandingLent what is still owed to them.
+type BudgetTotals struct {
+	Planned            models.Money `json:"planned"`
+	Spending           models.Money `json:"spending"`
+	Earnings           models.Money `json:"earnings"`
+	Borrowed           models.Money `json:"borrowed"`
+	OutstandingBorrows models.Money `json:"outstandingBorrows"`
+	Lent               models.Money `json:"lent"`
+	OutstandingLent    models.Money `json:"outstandingLent"`
+	InterestAccrued    models.Money `json:"interestAccrued"` // on money borrowed, within the month
+	InterestEarned     models.Money `json:"interestEarned"`  // on money lent, within the month
+	Variance           models.Money `json:"variance"`
+	NetCashFlow        models.Money `json:"netCashFlow"`
+}
+
+type BudgetSummary struct {
+	MonthKey   string           `json:"monthKey"`
+	Currency   string           `json:"currency"`
+	Categories []CategoryBudget `json:"categories"`
+	Totals     BudgetTotals     `json:"totals"`
+}
+
+// BudgetSummary computes budget vs actual per category and month totals from exact SQL aggregates.
+// Interest accrued in the month is computed from each interest-bearing debt's repayment history.
+// Every amount is converted to the user's base currency at the rate of its date (plans and interest
+// use the first of the month); a *MissingRatesError is returned when some rate is unavailable.
+func (s *SpendingService) BudgetSummary(conv *Converter, userID, monthKey string) (*BudgetSummary, error) {
+	rows, err := s.repo.MonthCategoryTotals(userID, monthKey)
+	if err != nil { return nil, err }
+	amounts, err := s.repo.MonthAmounts(userID, monthKey)
+	if err != nil { return nil, err }
+	interest, err := s.monthInterest(userID, monthKey, time.Now())
+	if err != nil { return nil, err }
+
+	var order []string
+	byCat := map[string]*CategoryBudget{}
+	var t BudgetTotals
+	for _, r := range rows {
+		cb := byCat[r.Category]
+		if cb == nil {
+			cb = &CategoryBudget{Category: r.Category}
+			byCat[r.Category] = cb
+			order = append(order, r.Category)
+		}
+		at := rowDate(monthKey, r.Day)
+		planned, actual := conv.Convert(r.Planned, r.Currency, at), conv.Convert(r.Actual, r.Currency, at)
+		cb.Planned += planned
+		cb.Actual += actual
+		t.Planned += planned
+		t.Spending += actual
+	}
+	for _, a := range amounts {
+		v := conv.Convert(a.Amount, a.Currency, rowDate(monthKey, a.Day))
+		switch a.Kind {
+		case "earnings":
+			t.Earnings += v
+		case "borrowed":
+			t.Borrowed += v
+		case "outstanding_borrowed":
+			t.OutstandingBorrows += v
+		case "lent":
+			t.Lent += v
+		case "outstanding_lent":
+			t.OutstandingLent += v
+		}
+	}
+	for _, in := range interest {
+		v := conv.Convert(in.Amount, in.Currency, rowDate(monthKey, ""))
+		if in.Direction == models.DirectionLent { t.InterestEarned += v } else { t.InterestAccrued += v }
+	}
+	if err := conv.Err(); err != nil { return nil, err }
+
+	out := &BudgetSummary{MonthKey: monthKey, Currency: conv.Base, Categories: make([]CategoryBudget, 0, len(order))}
+	for _, name := range order {
+		cb := byCat[name]
+		cb.Variance = cb.Planned - cb.Actual
+		if cb.Planned > 0 {
+			pct := round2(cb.Actual.Ratio(cb.Planned) * 100)
+			cb.PercentUsed = &pct
+		}
+		out.Categories = append(out.Categories, *cb)
+	}
+	t.Variance = t.Planned - t.Spending
+	t.NetCashFlow = t.Earnings - t.Spending
+	out.Totals = t
+	return out, nil
+}
+
+func round2(v float64) float64 { return math.Round(v*100) / 100 }
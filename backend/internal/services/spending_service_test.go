@@ -0,0 +1,127 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+	"achieving-backend/internal/testdb"
+)
+
+func TestUpdateBorrowRepaymentChecksBalance(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	repo := repository.NewSpendingRepository(db)
+	b, err := repo.CreateBorrow("u1", "", "Sam", models.BorrowEntry{Amount: models.MoneyFromInt(100), Date: day(2026, 3, 1)})
+	if err != nil { t.Fatal(err) }
+
+	steps := []struct {
+		total models.Money
+		err   error
+		want  models.Money // repaid afterwards
+	}{
+		{models.MoneyFromInt(60), nil, models.MoneyFromInt(60)},
+		{models.MoneyFromInt(120), repository.ErrRepaymentExceedsBalance, models.MoneyFromInt(60)},
+		{models.MoneyFromInt(100), nil, models.MoneyFromInt(100)},
+		{models.MoneyFromInt(40), nil, models.MoneyFromInt(40)},
+	}
+	for _, st := range steps {
+		rows, err := repo.UpdateBorrowRepayment("u1", b.ID, st.total, day(2026, 3, 10))
+		if err != st.err { t.Fatalf("repaid total %s: err = %v, want %v", st.total, err, st.err) }
+		if err == nil && rows != 1 { t.Errorf("repaid total %s: rows = %d", st.total, rows) }
+		items, err := repo.ListRepayments("u1", b.ID)
+		if err != nil { t.Fatal(err) }
+		var repaid models.Money
+		for _, it := range items { repaid += it.Amount }
+		if repaid != st.want { t.Errorf("after setting %s: repaid = %s, want %s", st.total, repaid, st.want) }
+	}
+	if rows, err := repo.UpdateBorrowRepayment("u1", "missing", models.MoneyFromInt(1), day(2026, 3, 10)); rows != 0 || err != nil { t.Errorf("unknown borrow: %d, %v", rows, err) }
+}
+
+func TestBudgetAlertsCrossThresholdsOnce(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	repo := repository.NewSpendingRepository(db)
+	svc := NewSpendingService(repo)
+	conv := NewFxService(repository.NewFxRepository(db)).Converter("u1")
+	if err := repo.EnsureMonth("u1", "2026-03"); err != nil { t.Fatal(err) }
+	if err := db.Create(&models.Plan{ID: "p1", UserID: "u1", MonthKey: "2026-03", Category: "food", PlannedAmount: models.MoneyFromInt(100), Currency: "USD"}).Error; err != nil { t.Fatal(err) }
+	alerts := func() []string {
+		var keys []string
+		db.Model(&models.Notification{}).Where("user_id = ? AND kind = ?", "u1", models.NotificationBudgetThreshold).Order("dedupe_key").Pluck("dedupe_key", &keys)
+		return keys
+	}
+	want := func(step string, keys ...string) {
+		t.Helper()
+		got := alerts()
+		if len(got) != len(keys) { t.Fatalf("%s: alerts = %v, want %v", step, got, keys) }
+		for i := range keys {
+			if got[i] != keys[i] { t.Fatalf("%s: alerts = %v, want %v", step, got, keys) }
+		}
+	}
+
+	if _, err := svc.CreateSpending(conv, "u1", models.MoneyFromInt(50), "food", day(2026, 3, 2), "", "", "USD"); err != nil { t.Fatal(err) }
+	want("50%")
+	lunch, err := svc.CreateSpending(conv, "u1", models.MoneyFromInt(20), "food", day(2026, 3, 3), "", "", "USD")
+	if err != nil { t.Fatal(err) }
+	want("70%")
+
+	// Editing the amount up to 85% crosses the first threshold
+	lunch, err = svc.UpdateSpending(conv, "u1", lunch.ID, lunch.Version, map[string]interface{}{"amount": models.MoneyFromInt(35)})
+	if err != nil { t.Fatal(err) }
+	want("85% after edit", "budget:2026-03:food:80")
+
+	// Dipping below and crossing 80% again does not repeat the alert
+	lunch, err = svc.UpdateSpending(conv, "u1", lunch.ID, lunch.Version, map[string]interface{}{"amount": models.MoneyFromInt(10)})
+	if err != nil { t.Fatal(err) }
+	lunch, err = svc.UpdateSpending(conv, "u1", lunch.ID, lunch.Version, map[string]interface{}{"amount": models.MoneyFromInt(40)})
+	if err != nil { t.Fatal(err) }
+	want("90% again", "budget:2026-03:food:80")
+
+	// An import over the plan reaches 100%
+	imports := NewImportService(repo)
+	row := ImportRow{Kind: "spending", Date: day(2026, 3, 4), Amount: models.MoneyFromInt(-15), Description: "Groceries", Category: "food"}
+	if _, err := imports.Import(conv, "u1", []ImportRow{row}, nil, ImportOptions{}); err != nil { t.Fatal(err) }
+	want("105% after import", "budget:2026-03:food:100", "budget:2026-03:food:80")
+
+	if _, err := svc.CreateSpending(conv, "u1", models.MoneyFromInt(30), "food", day(2026, 3, 5), "", "", "USD"); err != nil { t.Fatal(err) }
+	want("135%", "budget:2026-03:food:100", "budget:2026-03:food:80")
+
+	// Other categories and months have their own alerts
+	if _, err := svc.CreateSpending(conv, "u1", models.MoneyFromInt(500), "travel", day(2026, 3, 5), "", "", "USD"); err != nil { t.Fatal(err) }
+	want("unplanned category", "budget:2026-03:food:100", "budget:2026-03:food:80")
+}
+
+func TestUpdateBorrowAmountChecksRepayments(t *testing.T) {
+	db := testdb.Open(t)
+	testdb.User(t, db, "u1")
+	repo := repository.NewSpendingRepository(db)
+	b, err := repo.CreateBorrow("u1", "", "Sam", models.BorrowEntry{Amount: models.MoneyFromInt(100), Date: day(2026, 3, 1), InterestRate: 5})
+	if err != nil { t.Fatal(err) }
+	if _, err := repo.CreateRepayment("u1", b.ID, models.MoneyFromInt(60), day(2026, 3, 10), ""); err != nil { t.Fatal(err) }
+	amount := func() (models.BorrowEntry, models.Money) {
+		var got models.BorrowEntry
+		if err := db.Table("borrow_entries").Where("id = ?", b.ID).Select("amount, interest_rate, version").Scan(&got).Error; err != nil { t.Fatal(err) }
+		return got, got.Amount
+	}
+
+	steps := []struct {
+		updates map[string]interface{}
+		err     error
+		amount  models.Money // stored afterwards
+	}{
+		{map[string]interface{}{"amount": models.MoneyFromInt(50)}, nil, models.MoneyFromInt(50)}, // interest accrues on top
+		{map[string]interface{}{"interest_rate": 0.0}, repository.ErrRepaymentExceedsBalance, models.MoneyFromInt(50)},
+		{map[string]interface{}{"amount": models.MoneyFromInt(60), "interest_rate": 0.0}, nil, models.MoneyFromInt(60)},
+		{map[string]interface{}{"amount": models.MoneyFromInt(59)}, repository.ErrRepaymentExceedsBalance, models.MoneyFromInt(60)},
+	}
+	for i, st := range steps {
+		cur, _ := amount()
+		_, err := repo.UpdateBorrow("u1", b.ID, cur.Version, st.updates)
+		// The reload after a successful update can fail on SQLite, which returns MAX(date) as text, so
+		// accepted updates are checked on the stored row
+		if (st.err != nil && !errors.Is(err, st.err)) || (st.err == nil && errors.Is(err, repository.ErrRepaymentExceedsBalance)) { t.Fatalf("step %d: err = %v, want %v", i, err, st.err) }
+		if _, got := amount(); got != st.amount { t.Errorf("step %d: amount = %s, want %s", i, got, st.amount) }
+	}
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"achieving-backend/internal/models"
+	"achieving-backend/internal/repository"
+)
+
+// ErrInvalidTemplate is returned for plan templates without a name or with repeated or negative items
+var ErrInvalidTemplate = errors.New("invalid plan template")
+
+type TemplateService struct {
+	repo    *repository.TemplateRepository
+	entries *repository.SpendingRepository
+}
+
+func NewTemplateService(repo *repository.TemplateRepository, entries *repository.SpendingRepository) *TemplateService {
+	return &TemplateService{repo: repo, entries: entries}
+}
+
+func (s *TemplateService) List(userID string) ([]models.PlanTemplate, error) { return s.repo.List(userID) }
+func (s *TemplateService) Find(userID, idOrName string) (*models.PlanTemplate, error) { return s.repo.Find(userID, idOrName) }
+func (s *TemplateService) Delete(userID, id string) (int64, error) { return s.repo.Delete(userID, id) }
+
+// Create stores a template with items, or with the plans of fromMonth when it is given
+// (gorm.ErrRecordNotFound when that month does not exist)
+func (s *TemplateService) Create(userID, name string, items []models.PlanTemplateItem, fromMonth string) (*models.PlanTemplate, error) {
+	if fromMonth != "" {
+		plans, err := s.entries.MonthPlans(userID, fromMonth)
+		if err != nil { return nil, err }
+		items = make([]models.PlanTemplateItem, 0, len(plans))
+		for _, p := range plans { items = append(items, models.PlanTemplateItem{Category: p.Category, PlannedAmount: p.PlannedAmount, Currency: p.Currency}) }
+	}
+	name = strings.TrimSpace(name)
+	if err := validateTemplate(&name, items); err != nil { return nil, err }
+	if items == nil { items = []models.PlanTemplateItem{} }
+	return s.repo.Create(userID, name, items)
+}
+
+// Update renames the template and/or replaces its items; nil leaves either unchanged
+func (s *TemplateService) Update(userID, id string, name *string, items []models.PlanTemplateItem) (*models.PlanTemplate, error) {
+	if name != nil {
+		trimmed := strings.TrimSpace(*name)
+		name = &trimmed
+	}
+	if err := validateTemplate(name, items); err != nil { return nil, err }
+	return s.repo.Update(userID, id, name, items)
+}
+
+func validateTemplate(name *string, items []models.PlanTemplateItem) error {
+	if name != nil && *name == "" { return fmt.Errorf("%w: name is required", ErrInvalidTemplate) }
+	seen := map[string]bool{}
+	for _, it := range items {
+		key := strings.ToLower(it.Category)
+		if it.Category == "" { return fmt.Errorf("%w: item category is required", ErrInvalidTemplate) }
+		if seen[key] { return fmt.Errorf("%w: %s appears more than once", ErrInvalidTemplate, it.Category) }
+		if it.PlannedAmount < 0 { return fmt.Errorf("%w: %s has a negative amount", ErrInvalidTemplate, it.Category) }
+		seen[key] = true
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Package testdb opens throwaway in-memory SQLite databases with the application's tables, for
+// tests of repositories and services whose queries are portable
+package testdb
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"achieving-backend/internal/models"
+)
+
+// driverName is SQLite with the MySQL functions the repositories use and SQLite lacks
+const driverName = "sqlite3_testdb"
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+		if err := conn.RegisterFunc("greatest", greatest, true); err != nil { return err }
+		return conn.RegisterFunc("date_format", dateFormat, true)
+	}})
+}
+
+// dateFormat is MySQL's DATE_FORMAT for the %Y, %m and %d specifiers, over SQLite's text timestamps
+func dateFormat(value, format string) interface{} {
+	if len(value) < 10 { return nil }
+	return strings.NewReplacer("%Y", value[0:4], "%m", value[5:7], "%d", value[8:10]).Replace(format)
+}
+
+// greatest is MySQL's GREATEST for numbers; any NULL argument makes the result NULL
+func greatest(args ...interface{}) interface{} {
+	var best interface{}
+	bestValue := 0.0
+	for _, a := range args {
+		var v float64
+		switch n := a.(type) {
+		case int64:
+			v = float64(n)
+		case float64:
+			v = n
+		case nil:
+			return nil
+		default:
+			return a
+		}
+		if best == nil || v > bestValue { best, bestValue = a, v }
+	}
+	return best
+}
+
+// Open returns an empty database with every model's table, closed when the test ends
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+	// A named shared-cache database lives as long as one of its connections; keep a single one so
+	// that every query sees the same data
+	db, err := gorm.Open(sqlite.New(sqlite.Config{DriverName: driverName, DSN: "file:" + uuid.NewString() + "?mode=memory&cache=shared"}), &gorm.Config{Logger: logger.Discard, DisableForeignKeyConstraintWhenMigrating: true})
+	if err != nil { t.Fatalf("open test db: %v", err) }
+	sqlDB, err := db.DB()
+	if err != nil { t.Fatalf("open test db: %v", err) }
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	err = db.AutoMigrate(&models.User{}, &models.Session{}, &models.Month{}, &models.Category{}, &models.Plan{},
+		&models.SpendingEntry{}, &models.EarningEntry{}, &models.Counterparty{}, &models.BorrowEntry{}, &models.BorrowRepayment{},
+		&models.Goal{}, &models.GoalContribution{}, &models.GoalInstallment{}, &models.RecurringRule{}, &models.RecurringOccurrence{},
+		&models.FxRate{}, &models.PlanTemplate{}, &models.PlanTemplateItem{}, &models.EnvelopeTransfer{}, &models.CategorizationRule{},
+		&models.Notification{}, &models.NotificationChannel{}, &models.NotificationDelivery{}, &models.Job{})
+	if err != nil { t.Fatalf("migrate test db: %v", err) }
+	return db
+}
+
+// User inserts a user with the given ID and USD as base currency
+func User(t testing.TB, db *gorm.DB, id string) models.User {
+	t.Helper()
+	u := models.User{ID: id, Email: id + "@example.com", Name: id, BaseCurrency: "USD", BudgetAlertThresholds: "80,100"}
+	if err := db.Create(&u).Error; err != nil { t.Fatalf("create user: %v", err) }
+	return u
+}
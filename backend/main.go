@@ -1,31 +1,128 @@
 package main
 
 import (
+    "context"
+    "database/sql"
     "log"
+    "net/http"
     "os"
+    "os/signal"
+    "sync"
+    "syscall"
+    "time"
 
+    "gorm.io/gorm"
+
+    "achieving-backend/db/migrations"
     "achieving-backend/internal/config"
+    "achieving-backend/internal/health"
+    "achieving-backend/internal/jobs"
+    "achieving-backend/internal/migrate"
     "achieving-backend/internal/models"
+    "achieving-backend/internal/notifications"
+    "achieving-backend/internal/repository"
     "achieving-backend/internal/routes"
+    "achieving-backend/internal/services"
 )
 
 func main() {
-    // Load environment and connect DB
+    // Load environment; the DB is connected lazily so that `migrate schema` needs none
     config.LoadEnv()
-    db := config.ConnectDB()
+    migrator, err := migrate.New(migrations.FS)
+    if err != nil { log.Fatalf("failed to load migrations: %v", err) }
+    var db *gorm.DB
+    connect := func() (*sql.DB, error) { db = config.ConnectDB(); return db.DB() }
+    // A database created before versioned migrations is aligned once by the former startup migrations
+    migrator.Legacy = func(context.Context) error { return models.MigrateLegacy(db) }
+    // `achieving-backend migrate up|down [n]|status|schema [file]` runs migrations instead of serving
+    if len(os.Args) > 1 && os.Args[1] == "migrate" { os.Exit(migrator.Command(os.Args[2:], connect)) }
+
+    sqlDB, err := connect()
+    if err != nil { log.Fatalf("failed to connect database: %v", err) }
     // Log key envs for diagnostics
-    log.Printf("env GIN_MODE=%s DISABLE_LEGACY_MIGRATIONS=%s", os.Getenv("GIN_MODE"), os.Getenv("DISABLE_LEGACY_MIGRATIONS"))
-
-	// Migrations
-	models.MigrateGoals(db)
-	models.MigrateSpending(db)
-	models.MigrateAuth(db)
-
-    // Router
-    r := routes.SetupRouter(db)
-	port := config.MustGetEnv("PORT", "8081")
-	log.Printf("server listening on :%s", port)
-	if err := r.Run(":" + port); err != nil {
+    log.Printf("env GIN_MODE=%s", os.Getenv("GIN_MODE"))
+
+	// Migrations (the advisory lock makes concurrently starting instances apply them once)
+	cfg := config.LoadServerConfig()
+	if cfg.MigrateOnStart {
+		if _, err := migrator.Up(context.Background(), sqlDB); err != nil { log.Fatalf("migrations failed: %v", err) }
+	}
+	ready := health.NewReadiness()
+
+	// SIGINT/SIGTERM starts the shutdown below
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	// Background loops run until the HTTP server has drained
+	bgCtx, stopBackground := context.WithCancel(context.Background())
+	var background sync.WaitGroup
+	runLoop := func(loop func(context.Context)) {
+		background.Add(1)
+		go func() { defer background.Done(); loop(bgCtx) }()
+	}
+
+	// Background: materialize due recurring entries (catches up after downtime)
+	recurring := services.NewRecurringService(repository.NewRecurringRepository(db), services.NewSpendingService(repository.NewSpendingRepository(db)), services.NewFxService(repository.NewFxRepository(db)))
+	interval := services.RecurringInterval()
+	runLoop(func(ctx context.Context) { recurring.RunScheduler(ctx, interval) })
+	log.Printf("recurring scheduler running every %s", interval)
+
+	// Background: remind of debts coming due, and send queued notifications via Signal/Telegram
+	reminders := services.NewReminderService(repository.NewSpendingRepository(db))
+	runLoop(func(ctx context.Context) { reminders.RunScheduler(ctx, services.ReminderInterval()) })
+	channels := notifications.FromEnv()
+	dispatcher := notifications.NewDispatcher(repository.NewNotificationRepository(db), channels)
+	runLoop(func(ctx context.Context) { dispatcher.Run(ctx, notifications.Interval()) })
+	log.Printf("notification dispatcher running every %s (channels: %v)", notifications.Interval(), dispatcher.Channels())
+
+	// Background: job workers
+	runner := jobs.NewRunner(repository.NewJobRepository(db))
+	services.RegisterJobHandlers(runner, services.NewSpendingService(repository.NewSpendingRepository(db)), services.NewFxService(repository.NewFxRepository(db)))
+	runner.Start()
+	log.Printf("job runner started with %d worker(s)", runner.Workers)
+
+	// Readiness: no pending migrations, DB pool ping and job workers
+	checker := health.NewChecker(ready, cfg.HealthCheckTimeout)
+	checker.Add("migrations", func(ctx context.Context) error { return migrator.Ready(ctx, sqlDB) })
+	checker.Add("database", func(ctx context.Context) error { return sqlDB.PingContext(ctx) })
+	checker.Add("workers", func(context.Context) error { return runner.Health() })
+
+	// HTTP server
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           routes.SetupRouter(db, checker),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	log.Printf("server listening on :%s", cfg.Port)
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("failed to start server: %v", err)
+	case <-ctx.Done():
+	}
+	stop() // a second signal kills the process right away
+
+	// Shutdown: fail readiness, let load balancers notice, drain requests, then stop background
+	// work and close the DB pool last
+	log.Printf("shutting down: draining for %s", cfg.DrainDelay)
+	ready.SetDraining()
+	time.Sleep(cfg.DrainDelay)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil { log.Printf("shutdown: requests still in flight: %v", err) }
+	stopBackground()
+	if err := runner.Stop(shutdownCtx); err != nil { log.Printf("shutdown: jobs still running: %v", err) }
+	loopsDone := make(chan struct{})
+	go func() { background.Wait(); close(loopsDone) }()
+	select {
+	case <-loopsDone:
+	case <-shutdownCtx.Done():
+		log.Printf("shutdown: background loops still running")
 	}
-}
\ No newline at end of file
+	_ = sqlDB.Close()
+	log.Printf("shutdown complete")
+}
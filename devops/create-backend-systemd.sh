@@ -88,6 +88,9 @@ EnvironmentFile=${ENV_FILE}
 ExecStart=${EXEC_PATH}
 Restart=on-failure
 RestartSec=5s
+# The backend drains in-flight requests on SIGTERM (SHUTDOWN_DRAIN_DELAY + SHUTDOWN_TIMEOUT)
+KillSignal=SIGTERM
+TimeoutStopSec=45s
 NoNewPrivileges=yes
 PrivateTmp=true
 LimitNOFILE=65535